@@ -21,9 +21,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/BooleanCat/go-functional/v2/it"
 )
@@ -31,39 +35,279 @@ import (
 // ArtifactHubVersion represents a version entry in the API response.
 type ArtifactHubVersion struct {
 	Version string `json:"version"`
+	TS      int64  `json:"ts"`     // Release time in Unix seconds, 0 when absent
+	Signed  *bool  `json:"signed"` // Whether the version is signed, nil when the API does not say
 }
 
 // ArtifactHubResponse represents the API response structure.
 type ArtifactHubResponse struct {
 	AvailableVersions []ArtifactHubVersion `json:"available_versions"` //nolint:tagliatelle // ArtifactHub API uses snake_case
+	Deprecated        bool                 `json:"deprecated"`
+
+	// The package's remaining fields describe the version it was fetched at,
+	// the latest one when none is requested.
+	Version    string `json:"version"`
+	AppVersion string `json:"app_version"` //nolint:tagliatelle // ArtifactHub API uses snake_case
+	Digest     string `json:"digest"`
+}
+
+// VersionQuery describes the chart whose latest version should be resolved.
+type VersionQuery struct {
+	Repo    string        // ArtifactHub repository path (e.g., "cilium/cilium")
+	Current string        // Currently pinned version; a "major.minor" pin restricts results to that line
+	Timeout time.Duration // Request timeout overriding the client's, 0 to keep the client default
+	Limit   int           // Maximum number of versions to request, 0 for the full history
+
+	PrereleaseSameMajor bool          // Accept pre-releases that share Current's major version
+	Stability           StabilityRule // How pre-releases are told apart from stable versions
+	RequireSigned       bool          // Only consider versions the source marks as signed
+
+	Source          string   // Where Repo publishes its versions: "" for ArtifactHub, sourceGitHub, sourceHelmRepo or sourceOCI
+	AllowPrerelease bool     // Treat pre-releases as candidates, for charts that opted in
+	Constraint      string   // Only versions satisfying this constraint are candidates
+	Ignore          []string // Exact versions that are never candidates
+}
+
+// VersionInfo describes the version a VersionFetcher resolved for a query.
+type VersionInfo struct {
+	Version    string    // Selected version
+	Selection  Selection // Candidates considered and why others were rejected
+	ReleasedAt time.Time // When Version was released, zero if the source does not say
+	Deprecated bool      // The source marks the package as deprecated
+	AppVersion string    // Version of the application Version packages, empty if the source does not say
+	Digest     string    // Digest of Version's chart package, empty if the source does not say
+}
+
+// errDecodeResponse marks a 200 response whose body could not be decoded,
+// which ArtifactHub occasionally produces by truncating the body.
+var errDecodeResponse = errors.New("decode artifacthub response")
+
+// RateLimitError reports an HTTP 429 response. RetryAfter is how long the
+// server asked to wait before the next request, from its Retry-After header.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("artifacthub HTTP %d (retry after %s)", http.StatusTooManyRequests, e.RetryAfter)
 }
 
+// errNoVersions marks a repository without a single usable version, which
+// usually means it is dead or was never released, unlike one that only lacks
+// a stable release.
+var errNoVersions = errors.New("no versions published")
+
 // VersionFetcher is a function that retrieves the latest version for a repository.
-type VersionFetcher func(ctx context.Context, repo string) (string, error)
+type VersionFetcher func(ctx context.Context, q VersionQuery) (VersionInfo, error)
+
+// ArtifactHubKey is an ArtifactHub API key, which grants access to private
+// content and higher rate limits. The zero value makes anonymous requests.
+type ArtifactHubKey struct {
+	ID     string
+	Secret string
+}
+
+// String shows whether a key is set without revealing its secret, so that a
+// printed Config never leaks it.
+func (k ArtifactHubKey) String() string {
+	if k.ID == "" && k.Secret == "" {
+		return "none"
+	}
+
+	return k.ID + ":<redacted>"
+}
+
+// authenticate adds the key's headers to req, unless the key is unset.
+func (k ArtifactHubKey) authenticate(req *http.Request) {
+	if k.ID == "" {
+		return
+	}
+
+	req.Header.Set("X-API-KEY-ID", k.ID)
+	req.Header.Set("X-API-KEY-SECRET", k.Secret)
+}
 
-// MakeArtifactHubFetcher creates a VersionFetcher that uses the ArtifactHub API.
-func MakeArtifactHubFetcher(apiURL string, client *http.Client) VersionFetcher {
-	return func(ctx context.Context, repo string) (string, error) {
-		versions, err := fetchVersions(ctx, apiURL, client, repo)
+// MakeArtifactHubFetcher creates a VersionFetcher that uses the ArtifactHub
+// API, authenticated with key when it is set. Each request URL and the
+// versions it returned are logged to debug.
+func MakeArtifactHubFetcher(apiURL string, client *http.Client, key ArtifactHubKey, debug LogFunc) VersionFetcher {
+	return func(ctx context.Context, q VersionQuery) (VersionInfo, error) {
+		fetched, err := fetchVersions(ctx, apiURL, withTimeout(client, q.Timeout), key, q.Repo, q.Limit, debug)
 		if err != nil {
-			return "", err
+			return VersionInfo{}, err
+		}
+
+		switch {
+		case len(fetched.Versions) == 0 && len(fetched.Dropped) == 0:
+			return VersionInfo{}, errNoVersions
+		case len(fetched.Versions) == 0:
+			return VersionInfo{}, fmt.Errorf("%w (%s dropped as invalid)", errNoVersions, plural(len(fetched.Dropped), "version"))
 		}
 
-		latest, ok := findLatestStable(versions)
+		if q.RequireSigned {
+			if fetched, err = keepSigned(fetched); err != nil {
+				return VersionInfo{}, err
+			}
+		}
+
+		latest, sel, ok := selectVersion(fetched.Versions, q)
+		sel.Rejected = append(fetched.Dropped, sel.Rejected...)
 		if !ok {
-			return "", errors.New("no stable versions found")
+			if constraintBlocked(sel, q) {
+				return VersionInfo{}, constraintError(q.Constraint, sel)
+			}
+
+			if isPartialPin(q.Current) {
+				return VersionInfo{}, fmt.Errorf("no stable versions found in the %s.x line", q.Current)
+			}
+
+			return VersionInfo{}, fmt.Errorf("no stable versions found, only %s", plural(len(fetched.Versions), "pre-release"))
+		}
+
+		info := VersionInfo{
+			Version: latest, Selection: sel, ReleasedAt: fetched.Released[latest],
+			Deprecated: fetched.Deprecated, AppVersion: "", Digest: "",
+		}
+		if latest == fetched.Described {
+			info.AppVersion, info.Digest = fetched.AppVersion, fetched.Digest
+		}
+
+		return info, nil
+	}
+}
+
+// ResponseFetcher retrieves the raw API response body for a repository.
+type ResponseFetcher func(ctx context.Context, repo string) ([]byte, error)
+
+// MakeArtifactHubResponseFetcher creates a ResponseFetcher that returns the
+// ArtifactHub API body unparsed, for --dump-response.
+func MakeArtifactHubResponseFetcher(apiURL string, client *http.Client, key ArtifactHubKey) ResponseFetcher {
+	return func(ctx context.Context, repo string) ([]byte, error) {
+		return fetchResponse(ctx, apiURL, client, key, repo, 0)
+	}
+}
+
+// withTimeout returns a client sharing the given client's transport but using
+// timeout instead of its own, or the client itself when timeout is zero.
+func withTimeout(client *http.Client, timeout time.Duration) *http.Client {
+	if timeout <= 0 {
+		return client
+	}
+
+	c := *client
+	c.Timeout = timeout
+
+	return &c
+}
+
+// fetchedVersions is what fetchVersions learned about a repository.
+type fetchedVersions struct {
+	Versions []string             // Usable versions, newest first
+	Dropped  []Rejection          // Entries cleanVersions removed, and why
+	Released map[string]time.Time // Release time of each version the API dated
+	Signed   map[string]bool      // Signature status of each version the API reported one for
+
+	Deprecated bool   // The package is marked as deprecated
+	Described  string // The version AppVersion and Digest belong to
+	AppVersion string // App version of Described
+	Digest     string // Package digest of Described
+}
+
+// keepSigned drops the versions not marked as signed, recording them as
+// rejections, ahead of selection. It fails closed when the response carries no
+// signature data at all, since every version would otherwise look unsigned.
+func keepSigned(fetched fetchedVersions) (fetchedVersions, error) {
+	if len(fetched.Signed) == 0 && len(fetched.Versions) > 0 {
+		return fetched, errors.New("--require-signed: artifacthub reports no signature data for any version")
+	}
+
+	isSigned := func(v string) bool { return fetched.Signed[v] }
+	unsigned := slices.Collect(it.Filter(slices.Values(fetched.Versions), func(v string) bool { return !isSigned(v) }))
+
+	fetched.Dropped = slices.AppendSeq(fetched.Dropped, it.Map(slices.Values(unsigned), func(v string) Rejection {
+		return Rejection{Version: v, Reason: "unsigned"}
+	}))
+	fetched.Versions = slices.Collect(it.Filter(slices.Values(fetched.Versions), isSigned))
+
+	if len(fetched.Versions) == 0 && len(unsigned) > 0 {
+		return fetched, fmt.Errorf("--require-signed: none of the %s published is signed", plural(len(unsigned), "version"))
+	}
+
+	return fetched, nil
+}
+
+// fetchVersions requests the versions published for repo. A positive limit asks
+// the API for at most that many versions; endpoints that do not support it
+// ignore the parameter and return the full history. The versions are cleaned
+// by cleanVersions; entries it drops are returned as rejections. The request
+// URL and the resulting candidates are logged to debug.
+func fetchVersions(
+	ctx context.Context, apiURL string, client *http.Client, key ArtifactHubKey, repo string, limit int, debug LogFunc,
+) (fetchedVersions, error) {
+	debug("%s: GET %s", repo, artifactHubEndpoint(apiURL, repo, limit))
+
+	body, err := fetchResponse(ctx, apiURL, client, key, repo, limit)
+	if err != nil {
+		return fetchedVersions{}, err
+	}
+
+	var data ArtifactHubResponse
+	if decodeErr := json.Unmarshal(body, &data); decodeErr != nil {
+		return fetchedVersions{}, fmt.Errorf("%w: %w", errDecodeResponse, decodeErr)
+	}
+
+	versions, dropped := cleanVersions(slices.Collect(it.Map(slices.Values(data.AvailableVersions),
+		func(v ArtifactHubVersion) string { return v.Version })))
+
+	debug("%s: candidates %s (%d dropped as invalid)", repo, strings.Join(versions, ", "), len(dropped))
+
+	released := map[string]time.Time{}
+	signed := map[string]bool{}
+
+	for _, v := range data.AvailableVersions {
+		if _, seen := released[v.Version]; v.TS > 0 && !seen {
+			released[v.Version] = time.Unix(v.TS, 0).UTC()
 		}
 
-		return latest, nil
+		if _, seen := signed[v.Version]; v.Signed != nil && !seen {
+			signed[v.Version] = *v.Signed
+		}
 	}
+
+	return fetchedVersions{Versions: versions, Dropped: dropped, Released: released, Signed: signed,
+		Deprecated: data.Deprecated, Described: data.Version, AppVersion: data.AppVersion, Digest: data.Digest,
+	}, nil
 }
 
-func fetchVersions(ctx context.Context, apiURL string, client *http.Client, repo string) ([]string, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL+"/"+repo, nil)
+// artifactHubEndpoint is the URL of repo's package, asking for at most limit
+// versions when it is positive. repo may have as many segments as the package
+// path needs, each escaped on its own.
+func artifactHubEndpoint(apiURL, repo string, limit int) string {
+	segments := strings.Split(repo, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+
+	endpoint := apiURL + "/" + strings.Join(segments, "/")
+	if limit > 0 {
+		endpoint += "?" + url.Values{"limit": {strconv.Itoa(limit)}}.Encode()
+	}
+
+	return endpoint
+}
+
+// fetchResponse performs the GET behind fetchVersions and returns the raw body
+// of a 200 response.
+func fetchResponse(
+	ctx context.Context, apiURL string, client *http.Client, key ArtifactHubKey, repo string, limit int,
+) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, artifactHubEndpoint(apiURL, repo, limit), nil)
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
 	}
 
+	key.authenticate(req)
+
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("fetch versions from artifacthub: %w", err)
@@ -71,42 +315,74 @@ func fetchVersions(ctx context.Context, apiURL string, client *http.Client, repo
 
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, &RateLimitError{RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())}
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("artifacthub HTTP %d", resp.StatusCode)
 	}
 
-	var data ArtifactHubResponse
-	if decodeErr := json.NewDecoder(resp.Body).Decode(&data); decodeErr != nil {
-		return nil, fmt.Errorf("decode artifacthub response: %w", decodeErr)
+	// Reading to EOF also lets the connection be reused.
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errDecodeResponse, err)
 	}
 
-	return slices.Collect(it.Map(slices.Values(data.AvailableVersions), func(v ArtifactHubVersion) string {
-		return v.Version
-	})), nil
+	return body, nil
 }
 
-func findLatestStable(versions []string) (string, bool) {
-	stable := slices.Collect(it.Filter(slices.Values(versions), isStable))
+// cleanVersions guards selection against malformed API data: it drops empty and
+// unparseable entries, reporting each as a rejection, removes duplicates, and
+// returns the rest newest first.
+func cleanVersions(raw []string) ([]string, []Rejection) {
+	var (
+		versions []string
+		dropped  []Rejection
+	)
 
-	if len(stable) == 0 {
-		return "", false
+	for _, v := range raw {
+		switch {
+		case v == "":
+			dropped = append(dropped, Rejection{Version: `""`, Reason: "empty version"})
+		case !isParseableVersion(v):
+			dropped = append(dropped, Rejection{Version: v, Reason: "unparseable version"})
+		case !slices.Contains(versions, v):
+			versions = append(versions, v)
+		}
 	}
 
-	latest := slices.MaxFunc(stable, func(a, b string) int {
-		if versionLess(a, b) {
-			return -1
-		}
+	slices.SortStableFunc(versions, func(a, b string) int { return compareVersions(b, a) })
 
-		if versionLess(b, a) {
-			return 1
-		}
+	return versions, dropped
+}
+
+// StabilityRule decides which versions count as pre-releases.
+type StabilityRule string
 
-		return 0
-	})
+const (
+	StabilityDash             StabilityRule = "dash"              // Any "-" marks a pre-release
+	StabilitySemverPrerelease StabilityRule = "semver-prerelease" // Only a "-" right after a numeric core, as in 1.2.3-rc.1
+	StabilityNone             StabilityRule = "none"              // Every version is stable
+)
 
-	return latest, true
+// stabilityRules lists the rules --stable-rule accepts.
+func stabilityRules() []StabilityRule {
+	return []StabilityRule{StabilityDash, StabilitySemverPrerelease, StabilityNone}
 }
 
-func isStable(v string) bool {
-	return !strings.Contains(v, "-")
+// isStableUnder reports whether v is a stable version under rule. An empty
+// rule is StabilityDash.
+func isStableUnder(rule StabilityRule, v string) bool {
+	v = withoutBuild(v)
+
+	switch rule {
+	case StabilityNone:
+		return true
+	case StabilitySemverPrerelease:
+		core, _, found := strings.Cut(v, "-")
+		return !found || !numericSegments(core, 2)
+	default:
+		return !strings.Contains(v, "-")
+	}
 }
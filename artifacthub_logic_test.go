@@ -1,9 +1,11 @@
 package main
 
 import (
+	"slices"
 	"testing"
 )
 
+// stableQuery selects the highest stable version with no other filter.
 func TestFindLatestStable(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -23,6 +25,12 @@ func TestFindLatestStable(t *testing.T) {
 			want:     "2.0.0",
 			found:    true,
 		},
+		{
+			name:     "truncated window of newest versions",
+			versions: []string{"3.0.0-rc.2", "3.0.0-rc.1", "2.9.1"},
+			want:     "2.9.1",
+			found:    true,
+		},
 		{
 			name:     "only unstable versions",
 			versions: []string{"1.0.0-rc1", "2.0.0-beta"},
@@ -45,13 +53,96 @@ func TestFindLatestStable(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, found := findLatestStable(tt.versions)
+			got, _, found := selectVersion(tt.versions, testQuery("", ""))
 			if found != tt.found {
-				t.Errorf("findLatestStable() found = %v, want %v", found, tt.found)
+				t.Errorf("selectVersion() found = %v, want %v", found, tt.found)
 			}
 
 			if got != tt.want {
-				t.Errorf("findLatestStable() = %v, want %v", got, tt.want)
+				t.Errorf("selectVersion() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectVersionPartialPin(t *testing.T) {
+	versions := []string{"1.14.9", "1.15.0", "1.15.3", "1.15.4-rc1", "1.16.0", "1.150.0"}
+
+	tests := []struct {
+		name    string
+		current string
+		want    string
+		found   bool
+	}{
+		{name: "minor-line pin", current: "1.15", want: "1.15.3", found: true},
+		{name: "full version is unrestricted", current: "1.15.0", want: "1.150.0", found: true},
+		{name: "no current version", current: "", want: "1.150.0", found: true},
+		{name: "pin with no releases", current: "2.0", want: "", found: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, found := selectVersion(versions, VersionQuery{Repo: "org/repo", Current: tt.current})
+			if found != tt.found || got != tt.want {
+				t.Errorf("selectVersion() = %q, %v, want %q, %v", got, found, tt.want, tt.found)
+			}
+		})
+	}
+}
+
+func TestCleanVersions(t *testing.T) {
+	tests := []struct {
+		name        string
+		raw         []string
+		want        []string
+		wantDropped []Rejection
+	}{
+		{
+			name:        "sorted newest first",
+			raw:         []string{"1.0.0", "1.10.0", "1.2.0-rc.1", "1.2.0"},
+			want:        []string{"1.10.0", "1.2.0", "1.2.0-rc.1", "1.0.0"},
+			wantDropped: nil,
+		},
+		{
+			name:        "dated tags kept",
+			raw:         []string{"2023-01-01", "2024-06-30"},
+			want:        []string{"2024-06-30", "2023-01-01"},
+			wantDropped: nil,
+		},
+		{
+			name:        "duplicates removed",
+			raw:         []string{"2.0.0", "1.0.0", "2.0.0"},
+			want:        []string{"2.0.0", "1.0.0"},
+			wantDropped: nil,
+		},
+		{
+			name: "empty and unparseable dropped",
+			raw:  []string{"", "1.0.0", "v-next", "latest", "1.x.0"},
+			want: []string{"1.0.0"},
+			wantDropped: []Rejection{
+				{Version: `""`, Reason: "empty version"},
+				{Version: "v-next", Reason: "unparseable version"},
+				{Version: "latest", Reason: "unparseable version"},
+				{Version: "1.x.0", Reason: "unparseable version"},
+			},
+		},
+		{
+			name:        "nothing left",
+			raw:         []string{"", ""},
+			want:        nil,
+			wantDropped: []Rejection{{Version: `""`, Reason: "empty version"}, {Version: `""`, Reason: "empty version"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, dropped := cleanVersions(tt.raw)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("cleanVersions() versions = %v, want %v", got, tt.want)
+			}
+
+			if !slices.Equal(dropped, tt.wantDropped) {
+				t.Errorf("cleanVersions() dropped = %v, want %v", dropped, tt.wantDropped)
 			}
 		})
 	}
@@ -17,10 +17,16 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestArtifactHubLatestVersion(t *testing.T) {
@@ -111,8 +117,8 @@ func runArtifactHubTest(t *testing.T, response string, statusCode int, wantVer s
 	}))
 	defer server.Close()
 
-	fetcher := MakeArtifactHubFetcher(server.URL, http.DefaultClient)
-	ver, err := fetcher(context.Background(), "test/repo")
+	fetcher := MakeArtifactHubFetcher(server.URL, http.DefaultClient, ArtifactHubKey{}, discardLog)
+	ver, err := fetcher(context.Background(), VersionQuery{Repo: "test/repo", Current: "", Timeout: 0, Limit: 0, PrereleaseSameMajor: false, Stability: StabilityDash, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil})
 
 	if wantErr {
 		if err == nil {
@@ -127,7 +133,491 @@ func runArtifactHubTest(t *testing.T, response string, statusCode int, wantVer s
 		return
 	}
 
-	if ver != wantVer {
-		t.Errorf("artifactHubLatestVersion() = %q, want %q", ver, wantVer)
+	if ver.Version != wantVer {
+		t.Errorf("artifactHubLatestVersion() = %q, want %q", ver.Version, wantVer)
+	}
+}
+
+func TestArtifactHubPartialPin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"available_versions": [
+			{"version": "1.15.1"}, {"version": "1.15.3"}, {"version": "1.16.0"}
+		]}`))
+	}))
+	defer server.Close()
+
+	fetcher := MakeArtifactHubFetcher(server.URL, http.DefaultClient, ArtifactHubKey{}, discardLog)
+
+	ver, err := fetcher(context.Background(), VersionQuery{Repo: "test/repo", Current: "1.15", Timeout: 0, Limit: 0, PrereleaseSameMajor: false, Stability: StabilityDash, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil})
+	if err != nil || ver.Version != "1.15.3" {
+		t.Errorf("fetcher() = %q, %v, want %q", ver.Version, err, "1.15.3")
+	}
+
+	_, err = fetcher(context.Background(), VersionQuery{Repo: "test/repo", Current: "1.14", Timeout: 0, Limit: 0, PrereleaseSameMajor: false, Stability: StabilityDash, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil})
+	if err == nil || err.Error() != "no stable versions found in the 1.14.x line" {
+		t.Errorf("fetcher() error = %v, want missing line error", err)
+	}
+}
+
+func TestArtifactHubConstraint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"available_versions": [
+			{"version": "1.9.0"}, {"version": "2.0.0"}, {"version": "2.1.0"}
+		]}`))
+	}))
+	defer server.Close()
+
+	fetcher := MakeArtifactHubFetcher(server.URL, http.DefaultClient, ArtifactHubKey{}, discardLog)
+
+	ver, err := fetcher(context.Background(), VersionQuery{Repo: "test/repo", Current: "1.2.0", Timeout: 0, Limit: 0, PrereleaseSameMajor: false, Stability: StabilityDash, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: ">=1.2.0 <2.0.0", Ignore: nil})
+	if err != nil || ver.Version != "1.9.0" {
+		t.Errorf("fetcher() = %q, %v, want %q", ver.Version, err, "1.9.0")
+	}
+
+	_, err = fetcher(context.Background(), VersionQuery{Repo: "test/repo", Current: "1.2.0", Timeout: 0, Limit: 0, PrereleaseSameMajor: false, Stability: StabilityDash, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "^3.0.0", Ignore: nil})
+	if err == nil || err.Error() != `no versions satisfy constraint "^3.0.0", 3 candidates outside it` {
+		t.Errorf("fetcher() error = %v, want constraint error", err)
+	}
+}
+
+func TestArtifactHubDeprecated(t *testing.T) {
+	for _, deprecated := range []bool{false, true} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = fmt.Fprintf(w, `{"deprecated": %t, "available_versions": [{"version": "1.0.0"}]}`, deprecated)
+		}))
+
+		fetcher := MakeArtifactHubFetcher(server.URL, http.DefaultClient, ArtifactHubKey{}, discardLog)
+
+		ver, err := fetcher(context.Background(), VersionQuery{Repo: "test/repo", Current: "1.0.0", Timeout: 0, Limit: 0, PrereleaseSameMajor: false, Stability: StabilityDash, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil})
+		if err != nil || ver.Deprecated != deprecated {
+			t.Errorf("fetcher() = %+v, %v, want Deprecated %t", ver, err, deprecated)
+		}
+
+		server.Close()
+	}
+}
+
+func TestArtifactHubVerboseLog(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"available_versions": [{"version": "1.0.0"}, {"version": "1.1.0"}, {"version": "bogus"}]}`))
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+
+	fetcher := MakeArtifactHubFetcher(server.URL, http.DefaultClient, ArtifactHubKey{}, NewLogger(&logs, LogVerbose).Debug)
+
+	_, err := fetcher(context.Background(), VersionQuery{Repo: "test/repo", Current: "1.0.0", Timeout: 0, Limit: 0, PrereleaseSameMajor: false, Stability: StabilityDash, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil})
+	if err != nil {
+		t.Fatalf("fetcher() error = %v", err)
+	}
+
+	for _, want := range []string{"test/repo: GET " + server.URL + "/test/repo", "candidates 1.1.0, 1.0.0", "(1 dropped as invalid)"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("log = %q, want it to contain %q", logs.String(), want)
+		}
+	}
+}
+
+func TestArtifactHubPackageMetadata(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"version": "2.0.0", "app_version": "v5.1.0", "digest": "sha256:abc",
+			"available_versions": [{"version": "1.9.0"}, {"version": "2.0.0"}, {"version": "2.1.0-rc.1"}]}`))
+	}))
+	defer server.Close()
+
+	fetcher := MakeArtifactHubFetcher(server.URL, http.DefaultClient, ArtifactHubKey{}, discardLog)
+
+	ver, err := fetcher(context.Background(), VersionQuery{Repo: "test/repo", Current: "1.9.0", Timeout: 0, Limit: 0, PrereleaseSameMajor: false, Stability: StabilityDash, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil})
+	if err != nil || ver.AppVersion != "v5.1.0" || ver.Digest != "sha256:abc" {
+		t.Errorf("fetcher() = %+v, %v, want app version v5.1.0 and digest sha256:abc", ver, err)
+	}
+
+	// The package fields describe 2.0.0 only, not a version selected under a constraint.
+	ver, err = fetcher(context.Background(), VersionQuery{Repo: "test/repo", Current: "1.9.0", Timeout: 0, Limit: 0, PrereleaseSameMajor: false, Stability: StabilityDash, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "<2.0.0", Ignore: nil})
+	if err != nil || ver.Version != "1.9.0" || ver.AppVersion != "" || ver.Digest != "" {
+		t.Errorf("fetcher() with constraint = %+v, %v, want 1.9.0 without package metadata", ver, err)
+	}
+}
+
+func TestArtifactHubPerChartTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+
+		_, _ = w.Write([]byte(`{"available_versions": [{"version": "1.0.0"}]}`))
+	}))
+	defer server.Close()
+
+	client := server.Client()
+	client.Timeout = 50 * time.Millisecond
+
+	fetcher := MakeArtifactHubFetcher(server.URL, client, ArtifactHubKey{}, discardLog)
+
+	if _, err := fetcher(context.Background(), VersionQuery{Repo: "test/repo", Current: "", Timeout: 0, Limit: 0, PrereleaseSameMajor: false, Stability: StabilityDash, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil}); err == nil {
+		t.Error("fetcher() with global timeout error = nil, want timeout")
+	}
+
+	ver, err := fetcher(context.Background(), VersionQuery{Repo: "test/repo", Current: "", Timeout: 5 * time.Second, Limit: 0, PrereleaseSameMajor: false, Stability: StabilityDash, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil})
+	if err != nil || ver.Version != "1.0.0" {
+		t.Errorf("fetcher() with per-chart timeout = %q, %v, want %q", ver.Version, err, "1.0.0")
+	}
+
+	if client.Timeout != 50*time.Millisecond {
+		t.Errorf("client timeout changed to %v, want it untouched", client.Timeout)
+	}
+}
+
+func TestArtifactHubPackagePath(t *testing.T) {
+	tests := []struct {
+		repo string
+		want string
+	}{
+		{repo: "org/chart", want: "/org/chart"},
+		{repo: "publisher/repo/chart", want: "/publisher/repo/chart"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.repo, func(t *testing.T) {
+			var got string
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.URL.Path
+
+				_, _ = w.Write([]byte(`{"available_versions": [{"version": "1.0.0"}]}`))
+			}))
+			defer server.Close()
+
+			fetcher := MakeArtifactHubFetcher(server.URL, server.Client(), ArtifactHubKey{}, discardLog)
+			if _, err := fetcher(context.Background(), VersionQuery{Repo: tt.repo, Current: "", Timeout: 0, Limit: 0, PrereleaseSameMajor: false, Stability: StabilityDash, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil}); err != nil {
+				t.Fatalf("fetcher() error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("requested %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestArtifactHubAPIKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    ArtifactHubKey
+		wantID string
+		want   string
+	}{
+		{name: "configured", key: ArtifactHubKey{ID: "key-id", Secret: "key-secret"}, wantID: "key-id", want: "key-secret"},
+		{name: "anonymous", key: ArtifactHubKey{ID: "", Secret: ""}, wantID: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotID, got string
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotID, got = r.Header.Get("X-API-KEY-ID"), r.Header.Get("X-API-KEY-SECRET")
+
+				_, _ = w.Write([]byte(`{"available_versions": [{"version": "1.0.0"}]}`))
+			}))
+			defer server.Close()
+
+			fetcher := MakeArtifactHubFetcher(server.URL, server.Client(), tt.key, discardLog)
+			if _, err := fetcher(context.Background(), VersionQuery{Repo: "test/repo", Current: "", Timeout: 0, Limit: 0, PrereleaseSameMajor: false, Stability: StabilityDash, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil}); err != nil {
+				t.Fatalf("fetcher() error = %v", err)
+			}
+
+			if gotID != tt.wantID || got != tt.want {
+				t.Errorf("headers = %q, %q, want %q, %q", gotID, got, tt.wantID, tt.want)
+			}
+		})
+	}
+}
+
+func TestArtifactHubFetchLimit(t *testing.T) {
+	var gotQuery string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.RawQuery
+
+		// A limited window of the newest releases, including a pre-release.
+		_, _ = w.Write([]byte(`{"available_versions": [{"version": "2.1.0-rc.1"}, {"version": "2.0.1"}, {"version": "2.0.0"}]}`))
+	}))
+	defer server.Close()
+
+	fetcher := MakeArtifactHubFetcher(server.URL, server.Client(), ArtifactHubKey{}, discardLog)
+
+	tests := []struct {
+		name      string
+		limit     int
+		wantQuery string
+	}{
+		{name: "limited", limit: 3, wantQuery: "limit=3"},
+		{name: "unlimited", limit: 0, wantQuery: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ver, err := fetcher(context.Background(), VersionQuery{Repo: "test/repo", Current: "", Timeout: 0, Limit: tt.limit, PrereleaseSameMajor: false, Stability: StabilityDash, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil})
+			if err != nil {
+				t.Fatalf("fetcher() error = %v", err)
+			}
+
+			if gotQuery != tt.wantQuery {
+				t.Errorf("request query = %q, want %q", gotQuery, tt.wantQuery)
+			}
+
+			if ver.Version != "2.0.1" {
+				t.Errorf("fetcher() = %q, want latest stable %q from the truncated list", ver.Version, "2.0.1")
+			}
+		})
+	}
+}
+
+func TestArtifactHubMalformedVersions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"available_versions": [{"version": ""}, {"version": "1.1.0"}, {"version": "latest"},
+			{"version": "1.2.0"}, {"version": "1.2.0"}, {}]}`))
+	}))
+	defer server.Close()
+
+	fetcher := MakeArtifactHubFetcher(server.URL, server.Client(), ArtifactHubKey{}, discardLog)
+
+	ver, err := fetcher(context.Background(), VersionQuery{Repo: "test/repo", Current: "", Timeout: 0, Limit: 0, PrereleaseSameMajor: false, Stability: StabilityDash, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil})
+	if err != nil {
+		t.Fatalf("fetcher() error = %v", err)
+	}
+
+	if ver.Version != "1.2.0" {
+		t.Errorf("fetcher() = %q, want %q", ver.Version, "1.2.0")
+	}
+
+	if want := []string{"1.2.0", "1.1.0"}; !slices.Equal(ver.Selection.Candidates, want) {
+		t.Errorf("candidates = %v, want %v", ver.Selection.Candidates, want)
+	}
+
+	wantRejected := []Rejection{
+		{Version: `""`, Reason: "empty version"},
+		{Version: "latest", Reason: "unparseable version"},
+		{Version: `""`, Reason: "empty version"},
+	}
+	if !slices.Equal(ver.Selection.Rejected, wantRejected) {
+		t.Errorf("rejected = %v, want %v", ver.Selection.Rejected, wantRejected)
+	}
+}
+
+func TestArtifactHubOnlyMalformedVersions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"available_versions": [{"version": ""}, {"version": "nightly"}]}`))
+	}))
+	defer server.Close()
+
+	_, err := MakeArtifactHubFetcher(server.URL, server.Client(), ArtifactHubKey{}, discardLog)(context.Background(),
+		VersionQuery{Repo: "test/repo", Current: "", Timeout: 0, Limit: 0, PrereleaseSameMajor: false, Stability: StabilityDash, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil})
+	if want := "no versions published (2 versions dropped as invalid)"; err == nil || err.Error() != want {
+		t.Errorf("fetcher() error = %v, want %q", err, want)
+	}
+
+	if !errors.Is(err, errNoVersions) {
+		t.Errorf("fetcher() error = %v, want errNoVersions", err)
+	}
+}
+
+func TestArtifactHubNoVersionsAndNoStableVersions(t *testing.T) {
+	tests := []struct {
+		name           string
+		response       string
+		wantErr        string
+		wantNoVersions bool
+	}{
+		{
+			name:           "empty list",
+			response:       `{"available_versions": []}`,
+			wantErr:        "no versions published",
+			wantNoVersions: true,
+		},
+		{
+			name:           "missing list",
+			response:       `{}`,
+			wantErr:        "no versions published",
+			wantNoVersions: true,
+		},
+		{
+			name:           "only pre-releases",
+			response:       `{"available_versions": [{"version": "1.0.0-alpha"}, {"version": "2.0.0-rc.1"}]}`,
+			wantErr:        "no stable versions found, only 2 pre-releases",
+			wantNoVersions: false,
+		},
+		{
+			name:           "one pre-release",
+			response:       `{"available_versions": [{"version": "0.1.0-dev"}]}`,
+			wantErr:        "no stable versions found, only 1 pre-release",
+			wantNoVersions: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			_, err := MakeArtifactHubFetcher(server.URL, server.Client(), ArtifactHubKey{}, discardLog)(context.Background(),
+				VersionQuery{Repo: "test/repo", Current: "", Timeout: 0, Limit: 0, PrereleaseSameMajor: false, Stability: StabilityDash, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil})
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("fetcher() error = %v, want %q", err, tt.wantErr)
+			}
+
+			if got := errors.Is(err, errNoVersions); got != tt.wantNoVersions {
+				t.Errorf("errors.Is(err, errNoVersions) = %v, want %v", got, tt.wantNoVersions)
+			}
+		})
+	}
+}
+
+func TestArtifactHubRequireSigned(t *testing.T) {
+	tests := []struct {
+		name         string
+		response     string
+		require      bool
+		wantVer      string
+		wantErr      string
+		wantRejected []Rejection
+	}{
+		{
+			name: "newest signed version wins",
+			response: `{"available_versions": [
+				{"version": "1.3.0", "signed": false},
+				{"version": "1.2.0", "signed": true},
+				{"version": "1.1.0"},
+				{"version": "1.0.0", "signed": true}
+			]}`,
+			require:      true,
+			wantVer:      "1.2.0",
+			wantErr:      "",
+			wantRejected: []Rejection{{Version: "1.3.0", Reason: "unsigned"}, {Version: "1.1.0", Reason: "unsigned"}},
+		},
+		{
+			name:         "unsigned versions allowed without the flag",
+			response:     `{"available_versions": [{"version": "1.3.0", "signed": false}, {"version": "1.2.0", "signed": true}]}`,
+			require:      false,
+			wantVer:      "1.3.0",
+			wantErr:      "",
+			wantRejected: nil,
+		},
+		{
+			name:         "no signed versions",
+			response:     `{"available_versions": [{"version": "1.3.0", "signed": false}, {"version": "1.2.0", "signed": false}]}`,
+			require:      true,
+			wantVer:      "",
+			wantErr:      "--require-signed: none of the 2 versions published is signed",
+			wantRejected: nil,
+		},
+		{
+			name:         "no signature data fails closed",
+			response:     `{"available_versions": [{"version": "1.3.0"}, {"version": "1.2.0"}]}`,
+			require:      true,
+			wantVer:      "",
+			wantErr:      "--require-signed: artifacthub reports no signature data for any version",
+			wantRejected: nil,
+		},
+		{
+			name:         "only signed pre-releases",
+			response:     `{"available_versions": [{"version": "2.0.0-rc.1", "signed": true}, {"version": "1.0.0", "signed": false}]}`,
+			require:      true,
+			wantVer:      "",
+			wantErr:      "no stable versions found, only 1 pre-release",
+			wantRejected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			ver, err := MakeArtifactHubFetcher(server.URL, server.Client(), ArtifactHubKey{}, discardLog)(context.Background(), VersionQuery{
+				Repo: "test/repo", Current: "", Timeout: 0, Limit: 0, PrereleaseSameMajor: false, Stability: StabilityDash, RequireSigned: tt.require, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil,
+			})
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("fetcher() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("fetcher() error = %v", err)
+			}
+
+			if ver.Version != tt.wantVer {
+				t.Errorf("fetcher() = %q, want %q", ver.Version, tt.wantVer)
+			}
+
+			if !slices.Equal(ver.Selection.Rejected, tt.wantRejected) {
+				t.Errorf("rejected = %v, want %v", ver.Selection.Rejected, tt.wantRejected)
+			}
+		})
+	}
+}
+
+func TestArtifactHubDatedVersionsUnderSemverRule(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"available_versions": [{"version": "2024-06-30"}, {"version": "2023-01-01"}]}`))
+	}))
+	defer server.Close()
+
+	fetcher := MakeArtifactHubFetcher(server.URL, server.Client(), ArtifactHubKey{}, discardLog)
+
+	ver, err := fetcher(context.Background(), VersionQuery{
+		Repo: "test/repo", Current: "", Timeout: 0, Limit: 0, PrereleaseSameMajor: false, Stability: StabilitySemverPrerelease, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil,
+	})
+	if err != nil {
+		t.Fatalf("fetcher() error = %v", err)
+	}
+
+	if ver.Version != "2024-06-30" {
+		t.Errorf("fetcher() = %q, want %q", ver.Version, "2024-06-30")
+	}
+}
+
+func TestArtifactHubReleasedAt(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     time.Time
+	}{
+		{
+			name:     "timestamp of the selected version",
+			response: `{"available_versions": [{"version": "1.1.0", "ts": 1767225600}, {"version": "1.2.0-rc.1", "ts": 1767312000}, {"version": "1.0.0", "ts": 1764547200}]}`,
+			want:     time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "missing timestamp",
+			response: `{"available_versions": [{"version": "1.1.0"}]}`,
+			want:     time.Time{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			ver, err := MakeArtifactHubFetcher(server.URL, server.Client(), ArtifactHubKey{}, discardLog)(context.Background(),
+				VersionQuery{Repo: "test/repo", Current: "", Timeout: 0, Limit: 0, PrereleaseSameMajor: false, Stability: StabilityDash, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil})
+			if err != nil {
+				t.Fatalf("fetcher() error = %v", err)
+			}
+
+			if !ver.ReleasedAt.Equal(tt.want) {
+				t.Errorf("ReleasedAt = %v, want %v", ver.ReleasedAt, tt.want)
+			}
+		})
 	}
 }
@@ -0,0 +1,150 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+)
+
+// changelogHeading opens the section that collects entries until the next release.
+const changelogHeading = "## Unreleased"
+
+// updateChangelog adds a "- Bump repo from X to Y" entry for every applied
+// update to the Unreleased section of the Markdown changelog at path, creating
+// the file or section if missing. Entries already in the section are not
+// repeated, so re-running with the same results leaves the file untouched.
+func updateChangelog(path string, results *Results) error {
+	entries := changelogEntries(results)
+	if len(entries) == 0 {
+		return nil
+	}
+
+	//nolint:gosec // changelog path is supplied by the user on the command line
+	content, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("read changelog: %w", err)
+	}
+
+	updated := addChangelogEntries(string(content), entries)
+	if updated == string(content) {
+		return nil
+	}
+
+	//nolint:gosec // the changelog is committed alongside the manifests, so it stays world-readable
+	if err := os.WriteFile(path, []byte(updated), 0o644); err != nil {
+		return fmt.Errorf("write changelog: %w", err)
+	}
+
+	return nil
+}
+
+// changelogEntries returns one entry per distinct update in results, in order.
+func changelogEntries(results *Results) []string {
+	var entries []string
+
+	for _, r := range results.WithStatus(StatusUpdated) {
+		entry := fmt.Sprintf("- Bump %s from %s to %s", r.Repo, r.Current, r.Latest)
+		if !slices.Contains(entries, entry) {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+// addChangelogEntries appends the entries missing from the Unreleased section
+// of content to the end of that section. Without one, the section is inserted
+// above the first release heading, or at the end when there is none.
+func addChangelogEntries(content string, entries []string) string {
+	var lines []string
+	if trimmed := strings.TrimRight(content, "\n"); trimmed != "" {
+		lines = strings.Split(trimmed, "\n")
+	}
+
+	start := slices.IndexFunc(lines, isUnreleasedHeading)
+	if start < 0 {
+		start = slices.IndexFunc(lines, isReleaseHeading)
+		if start < 0 {
+			start = len(lines)
+		}
+
+		lines = slices.Insert(lines, start, changelogHeading)
+	}
+
+	end := len(lines)
+	if next := slices.IndexFunc(lines[start+1:], isReleaseHeading); next >= 0 {
+		end = start + 1 + next
+	}
+
+	body := trimBlankLines(lines[start+1 : end])
+
+	fresh := slices.DeleteFunc(slices.Clone(entries), func(e string) bool { return slices.Contains(body, e) })
+	if len(fresh) == 0 {
+		return content
+	}
+
+	before := trimBlankLines(lines[:start])
+	after := lines[end:]
+
+	var out []string
+	if len(before) > 0 {
+		out = append(slices.Clone(before), "")
+	}
+
+	out = append(out, lines[start], "")
+	out = append(out, body...)
+	out = append(out, fresh...)
+
+	if len(after) > 0 {
+		out = append(out, "")
+		out = append(out, after...)
+	}
+
+	return strings.Join(out, "\n") + "\n"
+}
+
+func isUnreleasedHeading(line string) bool {
+	heading := strings.TrimSpace(line)
+
+	return strings.EqualFold(heading, changelogHeading) || strings.EqualFold(heading, "## [Unreleased]")
+}
+
+func isReleaseHeading(line string) bool {
+	return strings.HasPrefix(line, "## ")
+}
+
+// trimBlankLines drops the blank lines at both ends of lines.
+func trimBlankLines(lines []string) []string {
+	isBlank := func(l string) bool { return strings.TrimSpace(l) == "" }
+
+	first := slices.IndexFunc(lines, func(l string) bool { return !isBlank(l) })
+	if first < 0 {
+		return nil
+	}
+
+	last := len(lines) - 1
+	for isBlank(lines[last]) {
+		last--
+	}
+
+	return lines[first : last+1]
+}
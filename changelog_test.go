@@ -0,0 +1,119 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAddChangelogEntries(t *testing.T) {
+	entries := []string{"- Bump org/a from 1.0.0 to 1.1.0", "- Bump org/b from 2.0.0 to 3.0.0"}
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "empty file",
+			content: "",
+			want:    "## Unreleased\n\n- Bump org/a from 1.0.0 to 1.1.0\n- Bump org/b from 2.0.0 to 3.0.0\n",
+		},
+		{
+			name:    "section created above the latest release",
+			content: "# Changelog\n\n## 1.0.0\n\n- Initial release\n",
+			want: "# Changelog\n\n## Unreleased\n\n- Bump org/a from 1.0.0 to 1.1.0\n- Bump org/b from 2.0.0 to 3.0.0\n\n" +
+				"## 1.0.0\n\n- Initial release\n",
+		},
+		{
+			name:    "section created at the end without releases",
+			content: "# Changelog\n\nNotable changes.\n",
+			want:    "# Changelog\n\nNotable changes.\n\n## Unreleased\n\n- Bump org/a from 1.0.0 to 1.1.0\n- Bump org/b from 2.0.0 to 3.0.0\n",
+		},
+		{
+			name:    "appended to the existing section",
+			content: "# Changelog\n\n## [Unreleased]\n\n- Fix typo\n\n## 1.0.0\n\n- Initial release\n",
+			want: "# Changelog\n\n## [Unreleased]\n\n- Fix typo\n- Bump org/a from 1.0.0 to 1.1.0\n- Bump org/b from 2.0.0 to 3.0.0\n\n" +
+				"## 1.0.0\n\n- Initial release\n",
+		},
+		{
+			name:    "only missing entries added",
+			content: "## Unreleased\n\n- Bump org/a from 1.0.0 to 1.1.0\n",
+			want:    "## Unreleased\n\n- Bump org/a from 1.0.0 to 1.1.0\n- Bump org/b from 2.0.0 to 3.0.0\n",
+		},
+		{
+			name:    "entries in older releases do not count",
+			content: "## Unreleased\n\n## 1.0.0\n\n- Bump org/a from 1.0.0 to 1.1.0\n",
+			want: "## Unreleased\n\n- Bump org/a from 1.0.0 to 1.1.0\n- Bump org/b from 2.0.0 to 3.0.0\n\n" +
+				"## 1.0.0\n\n- Bump org/a from 1.0.0 to 1.1.0\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := addChangelogEntries(tt.content, entries)
+			if got != tt.want {
+				t.Errorf("addChangelogEntries() =\n%s\nwant\n%s", got, tt.want)
+			}
+
+			if again := addChangelogEntries(got, entries); again != got {
+				t.Errorf("second addChangelogEntries() =\n%s\nwant it unchanged", again)
+			}
+		})
+	}
+}
+
+func TestUpdateChangelog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+	results := []UpdateResult{
+		{File: "a.yaml", Repo: "org/a", Current: "1.0.0", Latest: "1.1.0", Status: StatusUpdated},
+		{File: "a-staging.yaml", Repo: "org/a", Current: "1.0.0", Latest: "1.1.0", Status: StatusUpdated},
+		{File: "b.yaml", Repo: "org/b", Current: "2.0.0", Latest: "2.0.0", Status: StatusUpToDate},
+		{File: "c.yaml", Repo: "org/c", Current: "3.0.0", Latest: "", Status: StatusError},
+	}
+
+	for range 2 {
+		if err := updateChangelog(path, NewResults(results...)); err != nil {
+			t.Fatalf("updateChangelog() error = %v", err)
+		}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "## Unreleased\n\n- Bump org/a from 1.0.0 to 1.1.0\n"
+	if string(content) != want {
+		t.Errorf("changelog =\n%s\nwant\n%s", content, want)
+	}
+}
+
+func TestUpdateChangelogWithoutUpdatesLeavesFileAbsent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+
+	err := updateChangelog(path, NewResults(UpdateResult{File: "b.yaml", Repo: "org/b", Current: "2.0.0", Latest: "2.0.0", Status: StatusUpToDate}))
+	if err != nil {
+		t.Fatalf("updateChangelog() error = %v", err)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("changelog exists after a run without updates, stat error = %v", err)
+	}
+}
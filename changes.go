@@ -0,0 +1,65 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// changedFiles returns the manifest paths that were rewritten during the run.
+// The i-th result must be the outcome for charts[i]; nothing changes in a dry run.
+func changedFiles(cfg Config, charts []ChartInfo, results *Results) []string {
+	if cfg.DryRun {
+		return nil
+	}
+
+	var files []string
+
+	for i, r := range results.All() {
+		if r.Status == StatusUpdated {
+			files = append(files, chartPath(cfg, charts[i]))
+		}
+	}
+
+	return files
+}
+
+// writeChangedFiles writes files one per line to dest, or to stdout when dest
+// is "-". The output is empty, not absent, when nothing changed.
+func writeChangedFiles(dest string, files []string, stdout io.Writer) error {
+	var b strings.Builder
+	for _, f := range files {
+		b.WriteString(f + "\n")
+	}
+
+	if dest == "-" {
+		if _, err := io.WriteString(stdout, b.String()); err != nil {
+			return fmt.Errorf("write changed files: %w", err)
+		}
+
+		return nil
+	}
+
+	if err := os.WriteFile(dest, []byte(b.String()), 0o600); err != nil {
+		return fmt.Errorf("write changed files: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	createTestFiles(t, dir, map[string]string{
+		"stale.yaml":   "# artifacthub: org/stale\nkind: Application\nspec:\n  source:\n    targetRevision: 1.0.0\n",
+		"current.yaml": "# artifacthub: org/current\nkind: Application\nspec:\n  source:\n    targetRevision: 2.0.0\n",
+	})
+
+	charts := []ChartInfo{
+		{File: "stale.yaml", Repo: "org/stale", Timeout: 0, Labels: nil, Dir: ""},
+		{File: "current.yaml", Repo: "org/current", Timeout: 0, Labels: nil, Dir: ""},
+	}
+
+	fetch := func(_ context.Context, q VersionQuery) (VersionInfo, error) {
+		if q.Repo == "org/stale" {
+			return versionInfo("1.1.0"), nil
+		}
+
+		return versionInfo("2.0.0"), nil
+	}
+
+	tests := []struct {
+		name   string
+		dryRun bool
+		want   string
+	}{
+		{name: "updated charts are listed", dryRun: false, want: filepath.Join(dir, "stale.yaml") + "\n"},
+		{name: "dry run changes nothing", dryRun: true, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Dir: dir, DryRun: tt.dryRun}
+			write := writeYAMLDocuments
+
+			if tt.dryRun {
+				write = func(context.Context, string, []*yaml.Node) error { return nil }
+			}
+
+			updater := MakeChartUpdater(cfg, readYAMLDocuments, fetch, write, time.Now, discardLog)
+
+			results := NewResults()
+			for _, c := range charts {
+				results.Add(updater(context.Background(), c))
+			}
+
+			dest := filepath.Join(t.TempDir(), "changed.txt")
+			if err := writeChangedFiles(dest, changedFiles(cfg, charts, results), nil); err != nil {
+				t.Fatalf("writeChangedFiles() error = %v", err)
+			}
+
+			got, err := os.ReadFile(dest)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("changed files = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteChangedFilesStdout(t *testing.T) {
+	var buf bytes.Buffer
+
+	if err := writeChangedFiles("-", []string{"a.yaml", "b.yaml"}, &buf); err != nil {
+		t.Fatalf("writeChangedFiles() error = %v", err)
+	}
+
+	if got, want := buf.String(), "a.yaml\nb.yaml\n"; got != want {
+		t.Errorf("stdout = %q, want %q", got, want)
+	}
+}
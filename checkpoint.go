@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/BooleanCat/go-functional/v2/it"
+)
+
+// checkpointFile is where --resume records progress, in the working directory.
+const checkpointFile = ".chartupdater.progress"
+
+// Checkpoint records, one per line, the charts a run has processed, so that a
+// run interrupted part way can be resumed without redoing them.
+type Checkpoint struct {
+	path string
+	mu   sync.Mutex // Guards done and file, as charts may be recorded concurrently
+	done map[string]bool
+	file *os.File
+}
+
+// OpenCheckpoint loads the charts recorded at path by an earlier, interrupted
+// run and opens the file to record more. A missing file is an empty checkpoint.
+func OpenCheckpoint(path string) (*Checkpoint, error) {
+	done := make(map[string]bool)
+
+	//nolint:gosec // checkpoint path is fixed relative to the working directory
+	content, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("read checkpoint: %w", err)
+	}
+
+	for line := range strings.Lines(string(content)) {
+		if key := strings.TrimRight(line, "\n"); key != "" {
+			done[key] = true
+		}
+	}
+
+	//nolint:gosec // checkpoint path is fixed relative to the working directory
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open checkpoint: %w", err)
+	}
+
+	return &Checkpoint{path: path, mu: sync.Mutex{}, done: done, file: file}, nil
+}
+
+// Pending returns the charts not yet recorded as processed.
+func (c *Checkpoint) Pending(cfg Config, charts []ChartInfo) []ChartInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return slices.Collect(it.Filter(slices.Values(charts), func(chart ChartInfo) bool {
+		return !c.done[checkpointKey(cfg, chart)]
+	}))
+}
+
+// Record marks chart as processed. The line is written straight away so that
+// it survives the process being killed.
+func (c *Checkpoint) Record(cfg Config, chart ChartInfo) error {
+	key := checkpointKey(cfg, chart)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := fmt.Fprintln(c.file, key); err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+
+	c.done[key] = true
+
+	return nil
+}
+
+// Close closes the checkpoint, keeping its file for a later --resume.
+func (c *Checkpoint) Close() error {
+	if err := c.file.Close(); err != nil {
+		return fmt.Errorf("close checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+// Clear closes the checkpoint and removes its file once a run has completed.
+func (c *Checkpoint) Clear() error {
+	if err := c.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Remove(c.path); err != nil {
+		return fmt.Errorf("remove checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+// checkpointKey identifies chart in the checkpoint: its manifest path, plus
+// the key path for a version in a Helm values file, which may hold several.
+func checkpointKey(cfg Config, chart ChartInfo) string {
+	path := chartPath(cfg, chart)
+	if chart.ValuesKey == nil {
+		return path
+	}
+
+	return path + "#" + strings.Join(chart.ValuesKey, ".")
+}
@@ -0,0 +1,131 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCheckpointResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), checkpointFile)
+	cfg := Config{Dir: "apps"}
+
+	charts := []ChartInfo{
+		{File: "a.yaml", Repo: "org/a"},
+		{File: "b.yaml", Repo: "org/b"},
+		{File: "c.yaml", Repo: "org/c"},
+		{File: "values.yaml", Repo: "org/d", ValuesKey: []string{"d", "version"}},
+	}
+
+	var processed []string
+
+	process := func(c ChartInfo) UpdateResult {
+		processed = append(processed, c.File)
+		return UpdateResult{File: c.File, Repo: c.Repo, Status: StatusUpToDate}
+	}
+
+	errInterrupted := errors.New("interrupted")
+
+	// The first run is interrupted after its second chart.
+	checkpoint, err := OpenCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = processBatches(context.Background(), charts, 0, 1, checkpointed(cfg, checkpoint, process, NewLogger(io.Discard, LogNormal)), func(r UpdateResult) error {
+		if r.File == "b.yaml" {
+			return errInterrupted
+		}
+
+		return nil
+	}, func(int, int) {})
+	if !errors.Is(err, errInterrupted) {
+		t.Fatalf("processBatches() error = %v, want %v", err, errInterrupted)
+	}
+
+	if err := finishCheckpoint(checkpoint, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// The resumed run only processes what is left.
+	checkpoint, err = OpenCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pending := checkpoint.Pending(cfg, charts)
+	processed = nil
+
+	if err := processBatches(context.Background(), pending, 0, 1, checkpointed(cfg, checkpoint, process, NewLogger(io.Discard, LogNormal)), func(UpdateResult) error { return nil }, func(int, int) {}); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"c.yaml", "values.yaml"}; !slices.Equal(processed, want) {
+		t.Errorf("resumed run processed %v, want %v", processed, want)
+	}
+
+	if err := finishCheckpoint(checkpoint, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("checkpoint still present after a completed run: %v", err)
+	}
+}
+
+func TestCheckpointSkipsFailedCharts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), checkpointFile)
+	cfg := Config{Dir: "apps"}
+	charts := []ChartInfo{{File: "ok.yaml", Repo: "org/ok"}, {File: "bad.yaml", Repo: "org/bad"}}
+
+	checkpoint, err := OpenCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	process := checkpointed(cfg, checkpoint, func(c ChartInfo) UpdateResult {
+		if c.File == "bad.yaml" {
+			return newErrorResult(c.File, c.Repo, errors.New("unreachable"))
+		}
+
+		return UpdateResult{File: c.File, Repo: c.Repo, Status: StatusUpdated}
+	}, NewLogger(io.Discard, LogNormal))
+
+	for _, c := range charts {
+		process(c)
+	}
+
+	if err := checkpoint.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	checkpoint, err = OpenCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer checkpoint.Close()
+
+	if got := checkpoint.Pending(cfg, charts); len(got) != 1 || got[0].File != "bad.yaml" {
+		t.Errorf("Pending() = %v, want only the failed chart", got)
+	}
+}
@@ -0,0 +1,171 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Commit modes accepted by --commit-mode.
+const (
+	CommitPerChart = "per-chart" // One commit per updated manifest
+	CommitSingle   = "single"    // One commit for the whole run
+)
+
+// commitModes lists the modes --commit-mode accepts.
+func commitModes() []string {
+	return []string{CommitPerChart, CommitSingle}
+}
+
+// GitCommitter records the current content of files in a commit with message.
+type GitCommitter func(ctx context.Context, files []string, message string) error
+
+// fileUpdates are the updates applied to one manifest.
+type fileUpdates struct {
+	Path    string
+	Updates []UpdateResult
+}
+
+// commitUpdates commits the manifests rewritten during the run: one commit per
+// file, in processing order, or a single commit with --commit-mode single.
+// The i-th result must be the outcome for charts[i].
+func commitUpdates(ctx context.Context, cfg Config, charts []ChartInfo, results *Results, commit GitCommitter) error {
+	files := updatesByFile(cfg, charts, results)
+	if len(files) == 0 {
+		return nil
+	}
+
+	if cfg.CommitMode == CommitSingle {
+		var (
+			paths   []string
+			updates []UpdateResult
+		)
+
+		for _, f := range files {
+			paths = append(paths, f.Path)
+			updates = append(updates, f.Updates...)
+		}
+
+		return commit(ctx, paths, commitMessage(updates))
+	}
+
+	for _, f := range files {
+		if err := commit(ctx, []string{f.Path}, commitMessage(f.Updates)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// updatesByFile groups the applied updates by manifest path, in the order the
+// files were first updated. Charts sharing a file, such as keys of one values
+// file, end up in the same group.
+func updatesByFile(cfg Config, charts []ChartInfo, results *Results) []fileUpdates {
+	var files []fileUpdates
+
+	for i, r := range results.All() {
+		if r.Status != StatusUpdated {
+			continue
+		}
+
+		path := chartPath(cfg, charts[i])
+
+		j := slices.IndexFunc(files, func(f fileUpdates) bool { return f.Path == path })
+		if j < 0 {
+			files = append(files, fileUpdates{Path: path, Updates: nil})
+			j = len(files) - 1
+		}
+
+		files[j].Updates = append(files[j].Updates, r)
+	}
+
+	return files
+}
+
+// commitMessage describes updates as a conventional commit: the bump itself
+// for a single update, otherwise a count with one bump per line in the body.
+func commitMessage(updates []UpdateResult) string {
+	bump := func(r UpdateResult) string {
+		return fmt.Sprintf("bump %s %s → %s", r.Repo, r.Current, r.Latest)
+	}
+
+	if len(updates) == 1 {
+		return "chore(deps): " + bump(updates[0])
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "chore(deps): bump %d charts\n\n", len(updates))
+
+	for _, r := range updates {
+		b.WriteString("- " + bump(r) + "\n")
+	}
+
+	return b.String()
+}
+
+// gitCommit is the GitCommitter for --commit. It stages files and commits
+// only them, leaving anything else already staged out of the commit, in the
+// repository holding the first file.
+func gitCommit(ctx context.Context, files []string, message string) error {
+	if _, err := exec.LookPath("git"); err != nil {
+		return fmt.Errorf("--commit requires git: %w", err)
+	}
+
+	dir := filepath.Dir(files[0])
+	if err := runGit(ctx, dir, "rev-parse", "--is-inside-work-tree"); err != nil {
+		return fmt.Errorf("--commit: %s is not in a git repository: %w", dir, err)
+	}
+
+	paths := make([]string, 0, len(files))
+
+	for _, f := range files {
+		abs, err := filepath.Abs(f)
+		if err != nil {
+			return fmt.Errorf("resolve %s: %w", f, err)
+		}
+
+		paths = append(paths, abs)
+	}
+
+	if err := runGit(ctx, dir, append([]string{"add", "--"}, paths...)...); err != nil {
+		return fmt.Errorf("git add: %w", err)
+	}
+
+	if err := runGit(ctx, dir, append([]string{"commit", "--quiet", "--message", message, "--"}, paths...)...); err != nil {
+		return fmt.Errorf("git commit: %w", err)
+	}
+
+	return nil
+}
+
+// runGit runs git in dir, folding its output into the error when it fails.
+func runGit(ctx context.Context, dir string, args ...string) error {
+	//nolint:gosec // the arguments are fixed subcommands and manifest paths
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
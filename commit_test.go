@@ -0,0 +1,168 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type recordedCommit struct {
+	Files   []string
+	Message string
+}
+
+func TestCommitUpdates(t *testing.T) {
+	charts := []ChartInfo{
+		{File: "a.yaml", Repo: "org/a"},
+		{File: "b.yaml", Repo: "org/b"},
+		{File: "values.yaml", Repo: "org/c", ValuesKey: []string{"c", "version"}},
+		{File: "values.yaml", Repo: "org/d", ValuesKey: []string{"d", "version"}},
+	}
+	results := NewResults(
+		UpdateResult{File: "a.yaml", Repo: "org/a", Current: "1.0.0", Latest: "1.1.0", Status: StatusUpdated},
+		UpdateResult{File: "b.yaml", Repo: "org/b", Current: "2.0.0", Latest: "2.0.0", Status: StatusUpToDate},
+		UpdateResult{File: "values.yaml", Repo: "org/c", Current: "3.0.0", Latest: "3.1.0", Status: StatusUpdated},
+		UpdateResult{File: "values.yaml", Repo: "org/d", Current: "4.0.0", Latest: "5.0.0", Status: StatusUpdated},
+	)
+
+	tests := []struct {
+		name string
+		mode string
+		want []recordedCommit
+	}{
+		{
+			name: "per chart",
+			mode: "",
+			want: []recordedCommit{
+				{Files: []string{"a.yaml"}, Message: "chore(deps): bump org/a 1.0.0 → 1.1.0"},
+				{Files: []string{"values.yaml"}, Message: "chore(deps): bump 2 charts\n\n- bump org/c 3.0.0 → 3.1.0\n- bump org/d 4.0.0 → 5.0.0\n"},
+			},
+		},
+		{
+			name: "single",
+			mode: CommitSingle,
+			want: []recordedCommit{{
+				Files: []string{"a.yaml", "values.yaml"},
+				Message: "chore(deps): bump 3 charts\n\n" +
+					"- bump org/a 1.0.0 → 1.1.0\n- bump org/c 3.0.0 → 3.1.0\n- bump org/d 4.0.0 → 5.0.0\n",
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []recordedCommit
+
+			commit := func(_ context.Context, files []string, message string) error {
+				got = append(got, recordedCommit{Files: files, Message: message})
+				return nil
+			}
+
+			cfg := Config{Dir: ".", Commit: true, CommitMode: tt.mode}
+			if err := commitUpdates(context.Background(), cfg, charts, results, commit); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("commits = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommitUpdatesNothingApplied(t *testing.T) {
+	commit := func(context.Context, []string, string) error {
+		t.Error("commit called without any update")
+		return nil
+	}
+
+	results := NewResults(UpdateResult{File: "a.yaml", Repo: "org/a", Current: "1.0.0", Latest: "1.0.0", Status: StatusUpToDate})
+
+	if err := commitUpdates(context.Background(), Config{Dir: "."}, []ChartInfo{{File: "a.yaml", Repo: "org/a"}}, results, commit); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGitCommit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	repo := t.TempDir()
+	git(t, repo, "init", "-q")
+	git(t, repo, "config", "user.name", "test")
+	git(t, repo, "config", "user.email", "test@example.com")
+
+	path := filepath.Join(repo, testAppFile)
+	other := filepath.Join(repo, "other.yaml")
+
+	writeManifest(t, path, "1.0.0")
+	writeManifest(t, other, "1.0.0")
+	git(t, repo, "add", ".")
+	git(t, repo, "commit", "-q", "-m", "base")
+
+	writeManifest(t, path, "1.1.0")
+	writeManifest(t, other, "2.0.0")
+	git(t, repo, "add", other)
+
+	if err := gitCommit(context.Background(), []string{path}, "chore(deps): bump org/chart 1.0.0 → 1.1.0"); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := exec.CommandContext(context.Background(), "git", "-C", repo, "log", "-1", "--name-only", "--format=%s").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "chore(deps): bump org/chart 1.0.0 → 1.1.0\n\n" + testAppFile + "\n"; string(out) != want {
+		t.Errorf("last commit = %q, want %q", out, want)
+	}
+
+	staged, err := exec.CommandContext(context.Background(), "git", "-C", repo, "diff", "--cached", "--name-only").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(staged) != "other.yaml\n" {
+		t.Errorf("still staged = %q, want the unrelated file left alone", staged)
+	}
+}
+
+func TestGitCommitOutsideRepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir := t.TempDir()
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(dir))
+
+	path := filepath.Join(dir, testAppFile)
+	if err := os.WriteFile(path, []byte("kind: Application\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	err := gitCommit(context.Background(), []string{path}, "chore(deps): bump org/chart 1.0.0 → 1.1.0")
+	if err == nil || !strings.Contains(err.Error(), "is not in a git repository") {
+		t.Errorf("gitCommit() error = %v, want a not-in-repository error", err)
+	}
+}
@@ -19,45 +19,227 @@ package main
 import (
 	"errors"
 	"fmt"
+	"io/fs"
+	"net/url"
 	"os"
 	"path/filepath"
 	"slices"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/BooleanCat/go-functional/v2/it"
 	"gopkg.in/yaml.v3"
 )
 
 const (
-	defaultArgoAppsDir = "argoapps"
-	argoAppsDirEnvVar  = "UPDATE_VERSION_DIR"
+	defaultArgoAppsDir  = "argoapps"
+	argoAppsDirEnvVar   = "UPDATE_VERSION_DIR"
+	apiKeyIDEnvVar      = "ARTIFACTHUB_API_KEY_ID"
+	apiKeySecretEnvVar  = "ARTIFACTHUB_API_KEY_SECRET"
+	apiURLEnvVar        = "ARTIFACTHUB_API_URL"
+	defaultOptOutLabel  = "chart-updater"
+	chartSourcesFile    = "chart-sources.yaml"
+	maxExitCode         = 125
+	optOutDisabledValue = "disabled"
+
+	defaultMaxIdleConnsPerHost = 16
 )
 
 // Config holds the application configuration.
 type Config struct {
-	Dir       string
-	DryRun    bool
-	CheckOnly bool
+	Dir             string
+	DryRun          bool
+	CheckOnly       bool
+	Repo            string    // One-off ArtifactHub repository to query, bypassing discovery
+	Current         string    // Version to compare against in a one-off query
+	History         string    // CSV file that receives one row per chart per run
+	SkipUnreachable bool      // Report charts whose versions cannot be fetched as skipped
+	MaxPerHost      int       // Maximum concurrent requests to a single API host, 0 for unlimited
+	ExplainVersion  bool      // Print the candidates and filters behind each selected version
+	SelfTest        bool      // Verify ArtifactHub connectivity and exit without touching files
+	Suggest         bool      // In dry-run, print GitHub suggestion blocks instead of a diff
+	OptOutLabel     string    // Label or annotation key that, set to "disabled", excludes an Application
+	PrintEffective  bool      // Print a table of every chart and its resulting version after the run
+	ChartName       string    // Only process charts whose repo ends in "/<ChartName>", across all orgs
+	DryRunExitCode  int       // Exit code for a dry run that would change at least one chart, 0 to succeed
+	ExitCode        bool      // Set by --exit-code, which stands for a DryRunExitCode of exitCodePending
+	ConfigFile      string    // YAML config file applied below env vars and flags
+	DiffBase        string    // In dry-run, diff against files at this git revision instead of the working tree
+	FreezeUntil     time.Time // Run in check-only mode while the current time is before this instant
+	ChangedFiles    string    // File to list changed manifests in, "-" for stdout
+	Probe           bool      // Only check that Dir is readable, without parsing files or network calls
+	FetchLimit      int       // Maximum number of versions to request per chart, 0 for unlimited
+
+	RepoMap      map[string]string // Renames from --map-repo old=new, keyed by org or org/chart
+	RewriteMoved bool              // Also rewrite the artifacthub comment of renamed repos when updating
+	StampChecked bool              // Stamp a "# last-checked:" comment on every successfully checked file
+
+	PrereleaseSameMajor bool // Accept pre-releases sharing the current major; other majors must be stable
+	DiscoverJSON        bool // Print discovered charts as JSON and exit without any network calls
+
+	FailOn []string // Outcomes that make the run exit non-zero; nil means just "error"
+
+	MaxIdleConnsPerHost int            // Idle HTTP connections kept per API host, 0 for defaultMaxIdleConnsPerHost
+	OnlyKind            string         // Only update resources of this kind, "" for every supported kind
+	NeverDowngrade      bool           // Report charts whose latest version is below the current pin as blocked
+	DumpResponse        string         // Print the raw ArtifactHub response for this org/chart and exit
+	CheckConsistency    bool           // Warn when manifests pin the same chart to different versions
+	StableRule          StabilityRule  // How pre-releases are recognized, "" for StabilityDash
+	VerifyWrites        bool           // Re-read each written file and keep the original if it does not verify
+	ValuesFiles         []string       // Helm values files whose annotated keys are updated too
+	SortDocs            bool           // Order the documents of rewritten files by kind
+	Verbose             bool           // Print extra detail per chart, such as how long ago Latest was released
+	BatchSize           int            // Process charts this many at a time with progress in between, 0 for all at once
+	PatchOut            string         // In dry-run, write one patch for all files here instead of printing diffs
+	CheckChartName      bool           // Fail when spec.source.chart differs from the chart in the artifacthub comment
+	Profile             string         // Named profile of ConfigFile merged over its base settings
+	Changelog           string         // Markdown changelog whose Unreleased section lists every applied update
+	SummaryFormat       string         // Go template for the final summary line, "" for the built-in one
+	RequireSigned       bool           // Only accept versions ArtifactHub marks as signed
+	MaxRequests         int            // Stop fetching after this many ArtifactHub requests, 0 for no limit
+	AllowOutsideBase    bool           // Read manifests that resolve outside Dir instead of dropping them
+	ConfigPrint         bool           // Print the resolved configuration and exit
+	WithSource          bool           // With ConfigPrint, also print the layer each setting came from
+	IdleTimeout         time.Duration  // Abort a request whose connection sends nothing for this long, 0 to wait for the overall timeout
+	Resume              bool           // Skip charts recorded in the checkpoint file and record progress there
+	Compact             bool           // In dry-run, print one version delta per chart instead of diffs
+	MaxBump             BumpLevel      // Largest version segment an update may change, "" for any
+	Concurrency         int            // Charts processed at once, 0 for defaultConcurrency
+	Output              string         // Result format, OutputText or OutputJSON; "" for OutputText
+	ArtifactHubKey      ArtifactHubKey // From $ARTIFACTHUB_API_KEY_ID and $ARTIFACTHUB_API_KEY_SECRET; zero for anonymous requests
+	Include             []string       // Glob patterns a manifest's base name must match one of, nil for every manifest
+	Exclude             []string       // Glob patterns excluding manifests by base name; they win over Include
+	Commit              bool           // Commit every rewritten manifest with git
+	CommitMode          string         // With Commit, CommitPerChart or CommitSingle; "" for CommitPerChart
+	Report              string         // Extra summary written after the run, ReportMarkdown or "" for none
+	ReportFile          string         // Where Report is written, "" for stdout
+	APIURL              string         // ArtifactHub packages API base URL, "" for artifactHubAPIURL
+	HTTPTimeout         time.Duration  // Overall timeout of each request, 0 for httpClientTimeout
+	PreserveFormat      bool           // Edit targetRevision in place instead of re-encoding whole files
+	Quiet               bool           // Only log updated charts, warnings and errors, plus the summary
+	DiffMode            string         // How dry-run diffs are made, DiffModeGit or DiffModeBuiltin; "" for DiffModeGit
 }
 
 // ParseConfig parses command line arguments and environment variables to create a Config.
 func ParseConfig(args []string, getEnv func(string) string) (Config, error) {
+	cfg, _, err := ParseConfigWithSources(args, getEnv)
+	return cfg, err
+}
+
+// ParseConfigWithSources is like ParseConfig but also reports which layer,
+// from defaults through the config file and environment to flags, set each
+// field of the returned Config.
+func ParseConfigWithSources(args []string, getEnv func(string) string) (Config, Provenance, error) {
 	cfg := defaultConfig()
-	cfg = applyEnv(cfg, getEnv)
+	sources := Provenance{}
 
-	cfg, err := parseArgs(cfg, args)
+	args, err := expandResponseFiles(args, os.ReadFile)
 	if err != nil {
-		return cfg, err
+		return cfg, sources, err
 	}
 
-	return validateConfig(cfg)
+	path, explicit := configFilePath(args)
+
+	layered, fileErr := applyConfigFile(cfg, path, profileArg(args), os.ReadFile)
+
+	switch {
+	case fileErr == nil:
+		// A default file that was found is reported as the file in use; an
+		// explicit one is set, and credited, by the --config flag.
+		if !explicit {
+			layered.ConfigFile = path
+		}
+
+		cfg = sources.record(cfg, layered, SettingConfig)
+	case explicit || !errors.Is(fileErr, fs.ErrNotExist):
+		return layered, sources, fileErr
+	}
+
+	cfg = sources.record(cfg, applyEnv(cfg, getEnv), SettingEnv)
+
+	layered, err = parseArgs(cfg, args)
+	if err != nil {
+		return layered, sources, err
+	}
+
+	cfg, err = validateConfig(sources.record(cfg, layered, SettingFlag))
+
+	return cfg, sources, err
 }
 
 func defaultConfig() Config {
 	return Config{
-		Dir:       defaultArgoAppsDir,
-		DryRun:    false,
-		CheckOnly: false,
+		Dir:             defaultArgoAppsDir,
+		DryRun:          false,
+		CheckOnly:       false,
+		Repo:            "",
+		Current:         "",
+		History:         "",
+		SkipUnreachable: false,
+		MaxPerHost:      0,
+		ExplainVersion:  false,
+		SelfTest:        false,
+		Suggest:         false,
+		OptOutLabel:     defaultOptOutLabel,
+		PrintEffective:  false,
+		ChartName:       "",
+		DryRunExitCode:  0,
+		ExitCode:        false,
+		ConfigFile:      "",
+		DiffBase:        "",
+		FreezeUntil:     time.Time{},
+		ChangedFiles:    "",
+		Probe:           false,
+		FetchLimit:      0,
+		RepoMap:         nil,
+		RewriteMoved:    false,
+		StampChecked:    false,
+
+		PrereleaseSameMajor: false,
+		DiscoverJSON:        false,
+
+		FailOn: nil,
+
+		MaxIdleConnsPerHost: 0,
+		OnlyKind:            "",
+		NeverDowngrade:      false,
+		DumpResponse:        "",
+		CheckConsistency:    false,
+		StableRule:          "",
+		VerifyWrites:        false,
+		ValuesFiles:         nil,
+		SortDocs:            false,
+		Verbose:             false,
+		BatchSize:           0,
+		PatchOut:            "",
+		CheckChartName:      false,
+		Profile:             "",
+		Changelog:           "",
+		SummaryFormat:       "",
+		RequireSigned:       false,
+		MaxRequests:         0,
+		AllowOutsideBase:    false,
+		ConfigPrint:         false,
+		WithSource:          false,
+		IdleTimeout:         0,
+		Resume:              false,
+		Compact:             false,
+		MaxBump:             "",
+		Concurrency:         0,
+		Output:              "",
+		ArtifactHubKey:      ArtifactHubKey{ID: "", Secret: ""},
+		Include:             nil,
+		Exclude:             nil,
+		Commit:              false,
+		CommitMode:          "",
+		Report:              "",
+		ReportFile:          "",
+		APIURL:              "",
+		HTTPTimeout:         0,
+		PreserveFormat:      false,
+		Quiet:               false,
+		DiffMode:            "",
 	}
 }
 
@@ -66,38 +248,65 @@ func applyEnv(cfg Config, getEnv func(string) string) Config {
 		cfg.Dir = v
 	}
 
+	if v := getEnv(apiURLEnvVar); v != "" {
+		cfg.APIURL = v
+	}
+
+	if id, secret := getEnv(apiKeyIDEnvVar), getEnv(apiKeySecretEnvVar); id != "" || secret != "" {
+		cfg.ArtifactHubKey = ArtifactHubKey{ID: id, Secret: secret}
+	}
+
 	return cfg
 }
 
-func parseArgs(cfg Config, args []string) (Config, error) {
-	if len(args) == 0 {
-		return cfg, nil
-	}
+// expandResponseFiles replaces each "@file" argument with the whitespace-separated
+// tokens read from that file. Response files may not reference other response files.
+func expandResponseFiles(args []string, readFile func(string) ([]byte, error)) ([]string, error) {
+	expanded := make([]string, 0, len(args))
 
-	head, tail := args[0], args[1:]
+	for _, arg := range args {
+		name, ok := strings.CutPrefix(arg, "@")
+		if !ok {
+			expanded = append(expanded, arg)
+			continue
+		}
 
-	switch head {
-	case "--dry-run", "-n":
-		cfg.DryRun = true
-		return parseArgs(cfg, tail)
+		if name == "" {
+			return nil, errors.New("@ requires a response file path")
+		}
 
-	case "--check", "-C":
-		cfg.CheckOnly = true
-		return parseArgs(cfg, tail)
+		data, err := readFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("read response file: %w", err)
+		}
 
-	case "--dir", "-d":
-		if len(tail) == 0 {
-			return cfg, errors.New("--dir requires a directory path")
+		tokens := strings.Fields(string(data))
+		if nested, found := it.Find(slices.Values(tokens), func(t string) bool {
+			return strings.HasPrefix(t, "@")
+		}); found {
+			return nil, fmt.Errorf("response file %s: nested response file %s is not supported", name, nested)
 		}
 
-		cfg.Dir = tail[0]
+		expanded = append(expanded, tokens...)
+	}
+
+	return expanded, nil
+}
+
+func parseArgs(cfg Config, args []string) (Config, error) {
+	if len(args) == 0 {
+		return cfg, nil
+	}
 
-		return parseArgs(cfg, tail[1:])
+	head, tail := args[0], args[1:]
 
-	case "--help", "-h":
-		return cfg, errors.New("help requested")
+	// "--flag=value" is shorthand for "--flag value".
+	if name, value, ok := strings.Cut(head, "="); ok && strings.HasPrefix(name, "--") {
+		head, tail = name, append([]string{value}, tail...)
+	}
 
-	default:
+	name, spec, ok := lookupFlag(head)
+	if !ok {
 		if strings.HasPrefix(head, "-test.") {
 			return parseArgs(cfg, tail)
 		}
@@ -108,20 +317,276 @@ func parseArgs(cfg Config, args []string) (Config, error) {
 		// Ignore positional arguments for now, matching previous behavior
 		return parseArgs(cfg, tail)
 	}
+
+	if spec.arg == "" {
+		next, err := spec.apply(cfg, "")
+		if err != nil {
+			return cfg, err
+		}
+
+		return parseArgs(next, tail)
+	}
+
+	if len(tail) == 0 {
+		return cfg, fmt.Errorf("%s requires %s", name, spec.arg)
+	}
+
+	next, err := spec.apply(cfg, tail[0])
+	if err != nil {
+		return cfg, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return parseArgs(next, tail[1:])
 }
 
 func validateConfig(cfg Config) (Config, error) {
-	if cfg.DryRun && cfg.CheckOnly {
-		return cfg, errors.New("--dry-run and --check cannot be used together")
+	for _, validate := range []func(Config) error{
+		validateRunModes, validateCommitOptions, validateQueryModes, validateDiffOptions,
+		validateOutputOptions, validateNamedValues, validateBounds,
+	} {
+		if err := validate(cfg); err != nil {
+			return cfg, err
+		}
 	}
 
 	return cfg, nil
 }
 
+// validateRunModes rejects flags that need, or cannot be used with, the mode
+// a run is in.
+func validateRunModes(cfg Config) error {
+	if cfg.DryRun && cfg.CheckOnly {
+		return errors.New("--dry-run and --check cannot be used together")
+	}
+
+	if cfg.Profile != "" && cfg.ConfigFile == "" {
+		return errors.New("--profile requires --config or " + defaultConfigFile)
+	}
+
+	if cfg.Changelog != "" && cfg.DryRun {
+		return errors.New("--changelog cannot be combined with --dry-run")
+	}
+
+	if cfg.Resume && (cfg.DryRun || cfg.CheckOnly) {
+		return errors.New("--resume cannot be combined with --dry-run or --check")
+	}
+
+	if cfg.DryRunExitCode != 0 && !cfg.DryRun {
+		return errors.New("--dry-run-exit-code requires --dry-run")
+	}
+
+	if cfg.ExitCode && cfg.DryRunExitCode != 0 {
+		return errors.New("--exit-code cannot be combined with --dry-run-exit-code")
+	}
+
+	if cfg.WithSource && !cfg.ConfigPrint {
+		return errors.New("--with-source requires --config-print")
+	}
+
+	if cfg.RewriteMoved && len(cfg.RepoMap) == 0 {
+		return errors.New("--rewrite-moved requires --map-repo")
+	}
+
+	return nil
+}
+
+// validateCommitOptions checks --commit and --commit-mode.
+func validateCommitOptions(cfg Config) error {
+	if cfg.Commit && (cfg.DryRun || cfg.CheckOnly) {
+		return errors.New("--commit cannot be combined with --dry-run or --check")
+	}
+
+	if cfg.CommitMode != "" && !cfg.Commit {
+		return errors.New("--commit-mode requires --commit")
+	}
+
+	if cfg.CommitMode != "" && !slices.Contains(commitModes(), cfg.CommitMode) {
+		return fmt.Errorf("--commit-mode: unknown mode %q (want %s)", cfg.CommitMode, strings.Join(commitModes(), " or "))
+	}
+
+	return nil
+}
+
+// validateQueryModes keeps the modes that replace an update run, such as
+// --repo and --selftest, apart from each other and from --dry-run and --check.
+func validateQueryModes(cfg Config) error {
+	updating := cfg.DryRun || cfg.CheckOnly
+
+	if cfg.Current != "" && cfg.Repo == "" {
+		return errors.New("--version requires --repo")
+	}
+
+	if cfg.Repo != "" && updating {
+		return errors.New("--repo cannot be combined with --dry-run or --check")
+	}
+
+	if cfg.SelfTest && (cfg.Repo != "" || updating) {
+		return errors.New("--selftest cannot be combined with --repo, --dry-run or --check")
+	}
+
+	if cfg.DiscoverJSON && (cfg.Repo != "" || cfg.SelfTest || cfg.Probe || updating) {
+		return errors.New("--discover-json cannot be combined with --repo, --selftest, --probe, --dry-run or --check")
+	}
+
+	if cfg.Probe && (cfg.Repo != "" || cfg.SelfTest || updating) {
+		return errors.New("--probe cannot be combined with --repo, --selftest, --dry-run or --check")
+	}
+
+	if cfg.DumpResponse != "" && (cfg.Repo != "" || cfg.SelfTest || cfg.Probe || cfg.DiscoverJSON || updating) {
+		return errors.New("--dump-response cannot be combined with --repo, --selftest, --probe, --discover-json, --dry-run or --check")
+	}
+
+	if cfg.ChartName != "" && (cfg.Repo != "" || cfg.SelfTest) {
+		return errors.New("--chart cannot be combined with --repo or --selftest")
+	}
+
+	return nil
+}
+
+// validateDiffOptions checks the flags that shape the diff of a dry run.
+func validateDiffOptions(cfg Config) error {
+	if cfg.Suggest && !cfg.DryRun {
+		return errors.New("--suggest requires --dry-run")
+	}
+
+	if cfg.DiffBase != "" && (!cfg.DryRun || cfg.Suggest) {
+		return errors.New("--diff-base requires --dry-run and cannot be combined with --suggest")
+	}
+
+	if cfg.PatchOut != "" && (!cfg.DryRun || cfg.Suggest || cfg.DiffBase != "") {
+		return errors.New("--patch-out requires --dry-run and cannot be combined with --suggest or --diff-base")
+	}
+
+	if cfg.Compact && (!cfg.DryRun || cfg.Suggest || cfg.DiffBase != "" || cfg.PatchOut != "") {
+		return errors.New("--compact requires --dry-run and cannot be combined with --suggest, --diff-base or --patch-out")
+	}
+
+	if cfg.DiffMode != "" && !slices.Contains(diffModes(), cfg.DiffMode) {
+		return fmt.Errorf("--diff-mode: unknown mode %q (want %s)", cfg.DiffMode, strings.Join(diffModes(), " or "))
+	}
+
+	if cfg.DiffMode == DiffModeBuiltin && (!cfg.DryRun || cfg.DiffBase != "" || cfg.PatchOut != "") {
+		return errors.New("--diff-mode builtin requires --dry-run and cannot be combined with --diff-base or --patch-out")
+	}
+
+	return nil
+}
+
+// validateOutputOptions checks the output format, the report and how much
+// is logged.
+func validateOutputOptions(cfg Config) error {
+	if _, err := parseSummaryFormat(cfg.SummaryFormat); err != nil {
+		return err
+	}
+
+	if cfg.Output != "" && !slices.Contains(outputFormats(), cfg.Output) {
+		return fmt.Errorf("--output: unknown format %q (want text or json)", cfg.Output)
+	}
+
+	if cfg.Output == OutputJSON && (cfg.Suggest || cfg.DiffBase != "" || cfg.Compact || cfg.ChangedFiles == "-") {
+		return errors.New("--output json cannot be combined with --suggest, --diff-base, --compact or --changed-files -")
+	}
+
+	if cfg.Report != "" && cfg.Report != ReportMarkdown {
+		return fmt.Errorf("--report: unknown format %q (want %s)", cfg.Report, ReportMarkdown)
+	}
+
+	if cfg.ReportFile != "" && cfg.Report == "" {
+		return errors.New("--report-file requires --report")
+	}
+
+	if cfg.Report != "" && cfg.ReportFile == "" && cfg.Output == OutputJSON {
+		return errors.New("--report without --report-file cannot be combined with --output json, which also writes to stdout")
+	}
+
+	if cfg.Quiet && cfg.Verbose {
+		return errors.New("--quiet cannot be combined with --verbose")
+	}
+
+	return nil
+}
+
+// validateNamedValues rejects values outside the fixed set a flag accepts,
+// and malformed URLs, keys and patterns.
+func validateNamedValues(cfg Config) error {
+	if (cfg.ArtifactHubKey.ID == "") != (cfg.ArtifactHubKey.Secret == "") {
+		return errors.New(apiKeyIDEnvVar + " and " + apiKeySecretEnvVar + " must be set together")
+	}
+
+	if cfg.APIURL != "" {
+		if u, err := url.Parse(cfg.APIURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("--api-url: %q is not an http(s) URL", cfg.APIURL)
+		}
+	}
+
+	if err := validateGlobs(cfg.Include, cfg.Exclude); err != nil {
+		return err
+	}
+
+	if err := validateFailOn(cfg.FailOn); err != nil {
+		return err
+	}
+
+	if cfg.OnlyKind != "" && !slices.Contains(supportedKinds(), cfg.OnlyKind) {
+		return fmt.Errorf("--only-kind: unsupported kind %q (supported: %s)",
+			cfg.OnlyKind, strings.Join(supportedKinds(), ", "))
+	}
+
+	if cfg.StableRule != "" && !slices.Contains(stabilityRules(), cfg.StableRule) {
+		return fmt.Errorf("--stable-rule: unknown rule %q (want dash, semver-prerelease or none)", cfg.StableRule)
+	}
+
+	if cfg.MaxBump != "" && !slices.Contains(bumpLevels(), cfg.MaxBump) {
+		return fmt.Errorf("--max-bump: unknown level %q (want major, minor or patch)", cfg.MaxBump)
+	}
+
+	return nil
+}
+
+// validateBounds rejects numeric flags outside their range.
+func validateBounds(cfg Config) error {
+	if cfg.DryRunExitCode < 0 || cfg.DryRunExitCode > maxExitCode {
+		return fmt.Errorf("--dry-run-exit-code must be between 0 and %d", maxExitCode)
+	}
+
+	for _, bound := range []struct {
+		flag     string
+		negative bool
+	}{
+		{flag: "--fetch-limit", negative: cfg.FetchLimit < 0},
+		{flag: "--max-per-host", negative: cfg.MaxPerHost < 0},
+		{flag: "--idle-timeout", negative: cfg.IdleTimeout < 0},
+		{flag: "--timeout", negative: cfg.HTTPTimeout < 0},
+		{flag: "--max-requests", negative: cfg.MaxRequests < 0},
+		{flag: "--batch-size", negative: cfg.BatchSize < 0},
+		{flag: "--max-idle-conns-per-host", negative: cfg.MaxIdleConnsPerHost < 0},
+		{flag: "--concurrency", negative: cfg.Concurrency < 0},
+	} {
+		if bound.negative {
+			return fmt.Errorf("%s must not be negative", bound.flag)
+		}
+	}
+
+	return nil
+}
+
 // ChartInfo holds the discovered chart information from an ArgoCD Application manifest.
 type ChartInfo struct {
-	File string // File path relative to the argoapps directory
-	Repo string // ArtifactHub repository path (e.g., "cilium/cilium")
+	File    string        // File path relative to the argoapps directory
+	Repo    string        // ArtifactHub repository path (e.g., "cilium/cilium")
+	Timeout time.Duration // Per-chart request timeout from "# artifacthub-timeout:", 0 for the global default
+
+	Labels map[string]string // Application metadata.labels merged with metadata.annotations
+	Dir    string            // Directory File is relative to, overriding Config.Dir when set
+
+	ValuesKey []string // Key path of the version in a Helm values file, nil for an Application
+	Source    string   // Where Repo publishes its versions: "" for ArtifactHub, sourceGitHub, sourceHelmRepo or sourceOCI
+
+	Transforms      []VersionTransform // Rewrites from "# artifacthub-transform:" applied to each fetched version
+	AllowPrerelease bool               // The source comment carries the prerelease marker
+	Constraint      string             // Version constraint from the source comment, e.g. ">=1.2.0 <2.0.0"
+	Pinned          bool               // The source comment carries the pinned marker
+	Ignore          []string           // Versions the source comment excludes with "!version"
 }
 
 type (
@@ -129,20 +594,56 @@ type (
 	FileStater func(name string) (os.FileInfo, error)
 )
 
+// MakeDirProber creates a function that checks that a directory exists and
+// can be listed, without reading any of the files in it.
+func MakeDirProber(stat FileStater, readDir DirReader) func(dir string) error {
+	return func(dir string) error {
+		if err := checkDir(stat, dir); err != nil {
+			return err
+		}
+
+		if _, err := readDir(dir); err != nil {
+			return fmt.Errorf("cannot read directory: %w", err)
+		}
+
+		return nil
+	}
+}
+
+func checkDir(stat FileStater, dir string) error {
+	info, err := stat(dir)
+	if err != nil {
+		return fmt.Errorf("cannot access directory: %w", err)
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("path is not a directory: %s", dir)
+	}
+
+	return nil
+}
+
+// PathChecker reports whether path may be read as part of the directory absDir.
+type PathChecker func(absDir, path string) bool
+
+// FileFilter reports whether the manifest with base name name is scanned.
+type FileFilter func(name string) bool
+
 // MakeChartDiscoverer creates a function that scans a directory for ArgoCD Application manifests.
+// Files for which keep or inBase reports false are dropped; pass anyFile and
+// isValidPath to scan every manifest with the containment check. Manifests
+// whose source comment is malformed are skipped with a warning on warn.
 func MakeChartDiscoverer(
 	stat FileStater,
 	readDir DirReader,
 	readYaml YAMLReader,
+	keep FileFilter,
+	inBase PathChecker,
+	warn LogFunc,
 ) func(dir string) ([]ChartInfo, error) {
 	return func(dir string) ([]ChartInfo, error) {
-		info, err := stat(dir)
-		if err != nil {
-			return nil, fmt.Errorf("cannot access directory: %w", err)
-		}
-
-		if !info.IsDir() {
-			return nil, fmt.Errorf("path is not a directory: %s", dir)
+		if err := checkDir(stat, dir); err != nil {
+			return nil, err
 		}
 
 		absDir, err := filepath.Abs(dir)
@@ -155,9 +656,17 @@ func MakeChartDiscoverer(
 			return nil, fmt.Errorf("cannot read directory: %w", err)
 		}
 
+		sources, err := readChartSources(readYaml, dir, entries)
+		if err != nil {
+			return nil, err
+		}
+
 		// Functional pipeline to discover charts
-		// 1. Filter YAML files
-		yamlFiles := it.Filter(slices.Values(entries), isYamlFile)
+		// 1. Filter YAML files, leaving out the chart-sources sidecar and
+		// files --include/--exclude rule out
+		yamlFiles := it.Filter(slices.Values(entries), func(e os.DirEntry) bool {
+			return isManifestFile(e) && !isChartSourcesFile(e) && keep(e.Name())
+		})
 
 		// 2. Map to full path
 		paths := it.Map(yamlFiles, func(e os.DirEntry) string {
@@ -166,12 +675,12 @@ func MakeChartDiscoverer(
 
 		// 3. Filter valid paths (security check)
 		validPaths := it.Filter(paths, func(p string) bool {
-			return isValidPath(absDir, p)
+			return inBase(absDir, p)
 		})
 
 		// 4. Map to ChartInfo
 		chartInfos := it.Map(validPaths, func(p string) ChartInfo {
-			return toChartInfo(readYaml, p, dir)
+			return toChartInfo(readYaml, p, dir, sources, warn)
 		})
 
 		// 5. Filter valid charts (where Repo is found)
@@ -183,15 +692,186 @@ func MakeChartDiscoverer(
 	}
 }
 
-// isYamlFile checks if the directory entry is a YAML file.
-func isYamlFile(entry os.DirEntry) bool {
+// discoverDirs runs discover on dir or, when dir is a glob pattern such as
+// "clusters/*/apps", on every directory it matches. Charts from a glob keep
+// their matched directory in File so identically named manifests stay distinct.
+// Matches that are the same directory under another name are scanned once,
+// with a warning on log.
+func discoverDirs(discover func(string) ([]ChartInfo, error), dir string, log *Logger) ([]ChartInfo, error) {
+	if !isGlobPattern(dir) {
+		return discover(dir)
+	}
+
+	matches, err := expandDirPattern(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	matches, duplicates := uniqueDirs(matches)
+	ForEach(slices.Values(duplicates), func(d DuplicateDir) {
+		log.Warn("%s is the same directory as %s, scanning it once", d.Dir, d.SameAs)
+	})
+
+	var charts []ChartInfo
+
+	for _, match := range matches {
+		found, discoverErr := discover(match)
+		if discoverErr != nil {
+			return nil, fmt.Errorf("%s: %w", match, discoverErr)
+		}
+
+		for _, c := range found {
+			c.File = filepath.Join(match, c.File)
+			c.Dir = "."
+			charts = append(charts, c)
+		}
+	}
+
+	return charts, nil
+}
+
+// expandDirPattern returns the paths a --dir glob matches, or dir itself when
+// it is not a pattern.
+func expandDirPattern(dir string) ([]string, error) {
+	if !isGlobPattern(dir) {
+		return []string{dir}, nil
+	}
+
+	matches, err := filepath.Glob(dir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid directory pattern %q: %w", dir, err)
+	}
+
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no directories match %s", dir)
+	}
+
+	return matches, nil
+}
+
+// DuplicateDir is a directory left out of a scan because it resolves to one
+// already being scanned.
+type DuplicateDir struct {
+	Dir    string
+	SameAs string
+}
+
+// uniqueDirs drops every directory that resolves, through symlinks and relative
+// paths, to one earlier in dirs, so that no manifest is processed twice.
+func uniqueDirs(dirs []string) ([]string, []DuplicateDir) {
+	var (
+		unique     []string
+		duplicates []DuplicateDir
+	)
+
+	seen := map[string]string{}
+
+	for _, d := range dirs {
+		canonical := canonicalDir(d)
+
+		if first, ok := seen[canonical]; ok {
+			duplicates = append(duplicates, DuplicateDir{Dir: d, SameAs: first})
+			continue
+		}
+
+		seen[canonical] = d
+		unique = append(unique, d)
+	}
+
+	return unique, duplicates
+}
+
+// canonicalDir returns the absolute, symlink-free form of dir, or as much of it
+// as can be resolved.
+func canonicalDir(dir string) string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+
+	return dir
+}
+
+func isGlobPattern(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// ScanResult summarizes what a directory scan saw, to explain why no charts
+// were discovered.
+type ScanResult struct {
+	YAMLFiles    int // YAML and JSON files scanned, excluding the chart-sources sidecar
+	Applications int // Application documents found in those files
+	Uncommented  int // Applications without an artifacthub comment
+}
+
+// scanDirs counts the YAML files and Applications under dir, which may be a
+// glob like --dir. Unreadable or malformed files are counted but not inspected.
+func scanDirs(readDir DirReader, readYaml YAMLReader, dir string) (ScanResult, error) {
+	var result ScanResult
+
+	dirs, err := expandDirPattern(dir)
+	if err != nil {
+		return result, err
+	}
+
+	dirs, _ = uniqueDirs(dirs)
+
+	for _, d := range dirs {
+		entries, readErr := readDir(d)
+		if readErr != nil {
+			return result, fmt.Errorf("cannot read directory: %w", readErr)
+		}
+
+		ForEach(it.Filter(slices.Values(entries), func(e os.DirEntry) bool {
+			return isManifestFile(e) && !isChartSourcesFile(e)
+		}), func(e os.DirEntry) {
+			result.YAMLFiles++
+
+			docs, _ := readYaml(filepath.Join(d, e.Name()))
+			ForEach(slices.Values(docs), func(n *yaml.Node) {
+				if !isSupportedKind(n) {
+					return
+				}
+
+				result.Applications++
+
+				if comment, _, err := parseChartSource(n); err != nil || comment.Repo == "" {
+					result.Uncommented++
+				}
+			})
+		})
+	}
+
+	return result, nil
+}
+
+// noChartsError explains an empty discovery using the scan counts.
+func noChartsError(dir string, scan ScanResult) error {
+	hint := "add \"# artifacthub: org/chart\" comments to your Applications"
+
+	switch {
+	case scan.YAMLFiles == 0:
+		hint = "check that --dir points at your Application manifests"
+	case scan.Applications == 0:
+		hint = "none of the YAML files contain an Argo CD Application; check --dir"
+	}
+
+	return fmt.Errorf("no charts with artifacthub comments found in %s: scanned %d YAML file(s), "+
+		"found %d Application(s) without an artifacthub comment; %s", dir, scan.YAMLFiles, scan.Uncommented, hint)
+}
+
+// isManifestFile checks if the directory entry is a YAML or JSON file.
+func isManifestFile(entry os.DirEntry) bool {
 	if entry.IsDir() {
 		return false
 	}
 
 	name := entry.Name()
 
-	return strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml")
+	return strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml") || isJSONFile(name)
 }
 
 // isValidPath checks if the path is safe and within the base directory.
@@ -204,17 +884,92 @@ func isValidPath(absDir, path string) bool {
 	return strings.HasPrefix(absPath, absDir+string(os.PathSeparator)) || absPath == absDir
 }
 
-// toChartInfo extracts chart info from the file.
-func toChartInfo(readYaml YAMLReader, path, baseDir string) ChartInfo {
-	repo, err := extractArtifactHubRepo(readYaml, path)
+// toChartInfo extracts chart info from the file, falling back to the repo the
+// chart-sources sidecar lists for it when the file has no inline comment.
+func toChartInfo(readYaml YAMLReader, path, baseDir string, sources map[string]string, warn LogFunc) ChartInfo {
+	file := relativePath(baseDir, path)
+
+	info, err := extractChartInfoWithSource(readYaml, path, sources[file])
 	if err != nil {
+		if errors.Is(err, errMalformedComment) {
+			warn("%v, skipping", err)
+		}
+
 		return ChartInfo{}
 	}
 
-	return ChartInfo{
-		File: relativePath(baseDir, path),
-		Repo: repo,
+	info.File = file
+
+	return info
+}
+
+// chartName returns the chart portion of an "org/chart" repository path.
+func chartName(repo string) string {
+	if i := strings.LastIndex(repo, "/"); i >= 0 {
+		return repo[i+1:]
 	}
+
+	return repo
+}
+
+// filterByChartName keeps the charts whose repository's chart portion is name,
+// whatever org publishes it. An empty name keeps every chart.
+func filterByChartName(charts []ChartInfo, name string) []ChartInfo {
+	if name == "" {
+		return charts
+	}
+
+	return slices.Collect(it.Filter(slices.Values(charts), func(c ChartInfo) bool {
+		return chartName(c.Repo) == name
+	}))
+}
+
+// optedOut reports whether the chart carries the opt-out label set to "disabled".
+func optedOut(chart ChartInfo, label string) bool {
+	return label != "" && chart.Labels[label] == optOutDisabledValue
+}
+
+// anyFile is the FileFilter that keeps every manifest.
+func anyFile(string) bool {
+	return true
+}
+
+// MakeGlobFilter creates a FileFilter keeping the names that match no exclude
+// pattern and, unless include is empty, at least one include pattern.
+// Patterns use filepath.Match syntax; see validateGlobs.
+func MakeGlobFilter(include, exclude []string) FileFilter {
+	return func(name string) bool {
+		if matchesAny(exclude, name) {
+			return false
+		}
+
+		return len(include) == 0 || matchesAny(include, name)
+	}
+}
+
+func matchesAny(patterns []string, name string) bool {
+	return slices.ContainsFunc(patterns, func(p string) bool {
+		matched, _ := filepath.Match(p, name)
+		return matched
+	})
+}
+
+// validateGlobs rejects malformed --include and --exclude patterns, which
+// filepath.Match would otherwise silently never match.
+func validateGlobs(include, exclude []string) error {
+	for _, p := range slices.Concat(include, exclude) {
+		if _, err := filepath.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid file pattern %q: %w", p, err)
+		}
+	}
+
+	return nil
+}
+
+// anyPath is the PathChecker for --allow-outside-base: it disables the
+// containment check.
+func anyPath(string, string) bool {
+	return true
 }
 
 func relativePath(base, target string) string {
@@ -225,30 +980,128 @@ func relativePath(base, target string) string {
 	return target
 }
 
-// extractArtifactHubRepo reads a YAML file and extracts the ArtifactHub repo
-// from the first Application document that has the comment.
-func extractArtifactHubRepo(readYaml YAMLReader, path string) (string, error) {
+// extractChartInfoWithSource reads a YAML file and extracts the repo and any
+// per-chart annotations from the first Application document that has a source
+// comment. When no Application has one it attributes the first Application to
+// fallbackRepo; an empty fallbackRepo disables the fallback. The returned File
+// is left empty for the caller to fill in.
+func extractChartInfoWithSource(readYaml YAMLReader, path, fallbackRepo string) (ChartInfo, error) {
 	docs, err := readYaml(path)
 	if err != nil {
-		return "", err
+		return ChartInfo{}, err
 	}
 
 	// Filter for Application nodes
 	apps := it.Filter(slices.Values(docs), func(n *yaml.Node) bool {
-		return kind(n) == KindApplication
+		return isSupportedKind(n)
 	})
 
-	// Map to repo strings
-	repos := it.Map(apps, getArtifactHubRepo)
+	var first *yaml.Node
 
-	// Find first non-empty
-	repo, found := it.Find(repos, func(s string) bool {
-		return s != ""
-	})
+	// Return the first repo found, surfacing malformed comments
+	for app := range apps {
+		comment, source, parseErr := parseChartSource(app)
+		if parseErr != nil {
+			return ChartInfo{}, fmt.Errorf("%s: %w: %w", path, errMalformedComment, parseErr)
+		}
+
+		if comment.Repo != "" {
+			return newChartInfo(path, comment, source, app)
+		}
+
+		if first == nil {
+			first = app
+		}
+	}
+
+	if fallbackRepo != "" && first != nil {
+		return newChartInfo(path, RepoComment{Repo: fallbackRepo, AllowPrerelease: false, Pinned: false, Constraint: "", Ignore: nil}, "", first)
+	}
+
+	return ChartInfo{}, nil
+}
+
+func newChartInfo(path string, comment RepoComment, source string, app *yaml.Node) (ChartInfo, error) {
+	chart := ChartInfo{
+		File: "", Repo: comment.Repo, Timeout: 0, Labels: metadataLabels(app), Source: source,
+		AllowPrerelease: comment.AllowPrerelease, Constraint: comment.Constraint, Pinned: comment.Pinned,
+		Ignore: comment.Ignore,
+	}
+
+	info, err := applyAnnotations(chart, app)
+	if err != nil {
+		return ChartInfo{}, fmt.Errorf("%s: %w: %w", path, errMalformedComment, err)
+	}
+
+	return info, nil
+}
+
+// readChartSources loads the optional chart-sources.yaml sidecar, a mapping of
+// manifest paths relative to dir onto ArtifactHub repositories, for manifests
+// that carry no inline comment. A missing sidecar yields an empty mapping.
+func readChartSources(readYaml YAMLReader, dir string, entries []os.DirEntry) (map[string]string, error) {
+	sources := make(map[string]string)
+
+	if !slices.ContainsFunc(entries, isChartSourcesFile) {
+		return sources, nil
+	}
+
+	path := filepath.Join(dir, chartSourcesFile)
+
+	docs, err := readYaml(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", chartSourcesFile, err)
+	}
+
+	if len(docs) == 0 {
+		return sources, nil
+	}
+
+	root := docRoot(docs[0])
+	if root == nil || root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s: expected a mapping of file to repo", chartSourcesFile)
+	}
+
+	for i := 0; i+1 < len(root.Content); i += mappingNodeStep {
+		file, repo := root.Content[i].Value, strings.TrimSpace(root.Content[i+1].Value)
+		if repo == "" || strings.ContainsFunc(repo, unicode.IsSpace) {
+			return nil, fmt.Errorf("%s: invalid repo %q for %s", chartSourcesFile, repo, file)
+		}
+
+		if err := validateRepoPath("artifacthub", repo, artifactHubSegments); err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", chartSourcesFile, file, err)
+		}
+
+		sources[filepath.Clean(file)] = repo
+	}
+
+	return sources, nil
+}
+
+func isChartSourcesFile(entry os.DirEntry) bool {
+	return !entry.IsDir() && entry.Name() == chartSourcesFile
+}
+
+// applyAnnotations reads the optional "# artifacthub-*:" comments that accompany
+// the artifacthub comment and records them on the chart.
+func applyAnnotations(info ChartInfo, n *yaml.Node) (ChartInfo, error) {
+	if v, ok := headComment(n, timeoutPrefix); ok {
+		timeout, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil || timeout <= 0 {
+			return info, fmt.Errorf("invalid artifacthub-timeout %q", strings.TrimSpace(v))
+		}
+
+		info.Timeout = timeout
+	}
+
+	if v, ok := headComment(n, transformPrefix); ok {
+		transforms, err := parseTransforms(v)
+		if err != nil {
+			return info, fmt.Errorf("invalid artifacthub-transform: %w", err)
+		}
 
-	if found {
-		return repo, nil
+		info.Transforms = transforms
 	}
 
-	return "", nil
+	return info, nil
 }
@@ -17,15 +17,47 @@
 package main
 
 import (
+	"bytes"
+	"io"
+	"maps"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"testing"
+	"time"
 )
 
 const (
 	testAppFile    = "app.yaml"
 	testChartRepo  = "org/chart"
 	testAppContent = "# artifacthub: " + testChartRepo + "\nkind: Application"
+
+	testAppSetContent = `# artifacthub: ` + testChartRepo + `
+apiVersion: argoproj.io/v1alpha1
+kind: ApplicationSet
+metadata:
+  name: chart
+  namespace: argocd
+spec:
+  generators:
+    - list:
+        elements:
+          - cluster: staging
+          - cluster: production
+  template:
+    metadata:
+      name: 'chart-{{cluster}}'
+    spec:
+      project: default
+      source:
+        repoURL: https://charts.example.com
+        chart: chart
+        targetRevision: 1.0.0
+      destination:
+        name: '{{cluster}}'
+        namespace: chart
+`
 )
 
 func TestDiscoverCharts(t *testing.T) {
@@ -64,6 +96,16 @@ func TestDiscoverCharts(t *testing.T) {
 			wantCount:  0,
 			wantCharts: nil,
 		},
+		{
+			name: "first of ApplicationSet and Application in a mixed file",
+			files: map[string]string{
+				"mixed.yaml": "# artifacthub: org/set-chart\nkind: ApplicationSet\n---\n# artifacthub: org/app-chart\nkind: Application",
+			},
+			wantCount: 1,
+			wantCharts: []ChartInfo{
+				{File: "mixed.yaml", Repo: "org/set-chart"},
+			},
+		},
 		{
 			name: "non-Application kind is skipped",
 			files: map[string]string{
@@ -98,6 +140,39 @@ func TestDiscoverCharts(t *testing.T) {
 			wantCount:  1,
 			wantCharts: nil,
 		},
+		{
+			name: "repo from chart-sources sidecar",
+			files: map[string]string{
+				chartSourcesFile: "app1.yaml: org1/chart1\napp2.yaml: org2/chart2\n",
+				"app1.yaml":      "kind: Application",
+				"app2.yaml":      "kind: Application",
+			},
+			wantCount: 2,
+			wantCharts: []ChartInfo{
+				{File: "app1.yaml", Repo: "org1/chart1"},
+				{File: "app2.yaml", Repo: "org2/chart2"},
+			},
+		},
+		{
+			name: "inline comment takes precedence over sidecar",
+			files: map[string]string{
+				chartSourcesFile: testAppFile + ": sidecar/chart\n",
+				testAppFile:      testAppContent,
+			},
+			wantCount: 1,
+			wantCharts: []ChartInfo{
+				{File: testAppFile, Repo: testChartRepo},
+			},
+		},
+		{
+			name: "sidecar entry without Application is skipped",
+			files: map[string]string{
+				chartSourcesFile: "deploy.yaml: org/chart\n",
+				"deploy.yaml":    "kind: Deployment",
+			},
+			wantCount:  0,
+			wantCharts: nil,
+		},
 	}
 
 	for _, tt := range tests {
@@ -109,7 +184,7 @@ func TestDiscoverCharts(t *testing.T) {
 
 			createTestFiles(t, testDir, tt.files)
 
-			discover := MakeChartDiscoverer(os.Stat, os.ReadDir, readYAMLDocuments)
+			discover := MakeChartDiscoverer(os.Stat, os.ReadDir, readYAMLDocuments, anyFile, isValidPath, discardLog)
 
 			charts, err := discover(testDir)
 			if err != nil {
@@ -122,6 +197,62 @@ func TestDiscoverCharts(t *testing.T) {
 	}
 }
 
+func TestDiscoverChartsGlobFilter(t *testing.T) {
+	files := map[string]string{
+		"cilium.app.yaml":       "# artifacthub: cilium/cilium\nkind: Application",
+		"legacy-nginx.app.yaml": "# artifacthub: org/nginx\nkind: Application",
+		"vendored.yaml":         "# artifacthub: org/vendored\nkind: Application",
+	}
+
+	tests := []struct {
+		name       string
+		include    []string
+		exclude    []string
+		wantCharts []ChartInfo
+	}{
+		{
+			name:    "include",
+			include: []string{"*.app.yaml"},
+			exclude: nil,
+			wantCharts: []ChartInfo{
+				{File: "cilium.app.yaml", Repo: "cilium/cilium"},
+				{File: "legacy-nginx.app.yaml", Repo: "org/nginx"},
+			},
+		},
+		{
+			name:       "exclude wins over include",
+			include:    []string{"*.app.yaml"},
+			exclude:    []string{"legacy-*"},
+			wantCharts: []ChartInfo{{File: "cilium.app.yaml", Repo: "cilium/cilium"}},
+		},
+		{
+			name:    "several patterns",
+			include: []string{"cilium.*", "vendored.yaml"},
+			exclude: nil,
+			wantCharts: []ChartInfo{
+				{File: "cilium.app.yaml", Repo: "cilium/cilium"},
+				{File: "vendored.yaml", Repo: "org/vendored"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			createTestFiles(t, dir, files)
+
+			keep := MakeGlobFilter(tt.include, tt.exclude)
+
+			charts, err := MakeChartDiscoverer(os.Stat, os.ReadDir, readYAMLDocuments, keep, isValidPath, discardLog)(dir)
+			if err != nil {
+				t.Fatalf("discoverCharts() error = %v", err)
+			}
+
+			checkDiscoveredCharts(t, charts, len(tt.wantCharts), tt.wantCharts)
+		})
+	}
+}
+
 func createTestFiles(t *testing.T, dir string, files map[string]string) {
 	t.Helper()
 
@@ -157,7 +288,7 @@ func checkDiscoveredCharts(t *testing.T, got []ChartInfo, wantCount int, wantCha
 }
 
 func TestDiscoverChartsErrors(t *testing.T) {
-	discover := MakeChartDiscoverer(os.Stat, os.ReadDir, readYAMLDocuments)
+	discover := MakeChartDiscoverer(os.Stat, os.ReadDir, readYAMLDocuments, anyFile, isValidPath, discardLog)
 
 	t.Run("nonexistent directory", func(t *testing.T) {
 		_, err := discover("/nonexistent/path")
@@ -183,6 +314,377 @@ func TestDiscoverChartsErrors(t *testing.T) {
 	})
 }
 
+func TestDiscoverChartsInvalidChartSources(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "not a mapping", content: "- app.yaml\n"},
+		{name: "empty repo", content: "app.yaml: \"\"\n"},
+		{name: "repo with whitespace", content: "app.yaml: org /chart\n"},
+		{name: "bare chart name", content: "app.yaml: cilium\n"},
+		{name: "path traversal", content: "app.yaml: ../../x\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			createTestFiles(t, dir, map[string]string{chartSourcesFile: tt.content, testAppFile: "kind: Application"})
+
+			_, err := MakeChartDiscoverer(os.Stat, os.ReadDir, readYAMLDocuments, anyFile, isValidPath, discardLog)(dir)
+			if err == nil || !contains(err.Error(), chartSourcesFile) {
+				t.Errorf("discoverCharts() error = %v, want error mentioning %s", err, chartSourcesFile)
+			}
+		})
+	}
+}
+
+func TestChartDiscovererWarnsMalformedComments(t *testing.T) {
+	dir := t.TempDir()
+	createTestFiles(t, dir, map[string]string{
+		"good.yaml":  "# artifacthub: org/chart\nkind: Application\n",
+		"typo.yaml":  "# artifacthub: orgchart\nkind: Application\n",
+		"plain.yaml": "kind: Application\n",
+	})
+
+	var warnings bytes.Buffer
+
+	charts, err := MakeChartDiscoverer(os.Stat, os.ReadDir, readYAMLDocuments, anyFile, isValidPath, NewLogger(&warnings, LogNormal).Warn)(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(charts) != 1 || charts[0].File != "good.yaml" {
+		t.Errorf("discoverCharts() = %+v, want only good.yaml", charts)
+	}
+
+	want := "▶ warning: " + filepath.Join(dir, "typo.yaml") + `: malformed source comment: invalid artifacthub repo "orgchart": want org/repo or publisher/repo/package, skipping` + "\n"
+	if got := warnings.String(); got != want {
+		t.Errorf("warnings = %q, want %q", got, want)
+	}
+}
+
+func TestChartDiscovererWarnsMalformedAnnotations(t *testing.T) {
+	dir := t.TempDir()
+	createTestFiles(t, dir, map[string]string{
+		"good.yaml":      "# artifacthub: org/chart\nkind: Application\n",
+		"timeout.yaml":   "# artifacthub: org/chart\n# artifacthub-timeout: 30\nkind: Application\n",
+		"transform.yaml": "# artifacthub: org/chart\n# artifacthub-transform: nope\nkind: Application\n",
+	})
+
+	var warnings bytes.Buffer
+
+	charts, err := MakeChartDiscoverer(os.Stat, os.ReadDir, readYAMLDocuments, anyFile, isValidPath, NewLogger(&warnings, LogNormal).Warn)(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(charts) != 1 || charts[0].File != "good.yaml" {
+		t.Errorf("discoverCharts() = %+v, want only good.yaml", charts)
+	}
+
+	for _, want := range []string{
+		filepath.Join(dir, "timeout.yaml") + `: malformed source comment: invalid artifacthub-timeout "30", skipping`,
+		filepath.Join(dir, "transform.yaml") + ": malformed source comment: invalid artifacthub-transform: ",
+	} {
+		if got := warnings.String(); !contains(got, want) {
+			t.Errorf("warnings = %q, want it to contain %q", got, want)
+		}
+	}
+}
+
+func TestDiscoverDirsGlob(t *testing.T) {
+	root := t.TempDir()
+
+	for _, cluster := range []string{"prod", "staging"} {
+		dir := filepath.Join(root, "clusters", cluster, "apps")
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			t.Fatal(err)
+		}
+
+		createTestFiles(t, dir, map[string]string{testAppFile: testAppContent})
+	}
+
+	discover := MakeChartDiscoverer(os.Stat, os.ReadDir, readYAMLDocuments, anyFile, isValidPath, discardLog)
+
+	charts, err := discoverDirs(discover, filepath.Join(root, "clusters", "*", "apps"), NewLogger(io.Discard, LogNormal))
+	if err != nil {
+		t.Fatalf("discoverDirs() error = %v", err)
+	}
+
+	want := []string{
+		filepath.Join(root, "clusters", "prod", "apps", testAppFile),
+		filepath.Join(root, "clusters", "staging", "apps", testAppFile),
+	}
+
+	got := make([]string, 0, len(charts))
+	for _, c := range charts {
+		got = append(got, chartPath(Config{Dir: "unused"}, c))
+	}
+
+	if !slices.Equal(got, want) {
+		t.Errorf("discoverDirs() files = %v, want %v", got, want)
+	}
+}
+
+// renamedEntry is a directory entry reported under a different name.
+type renamedEntry struct {
+	os.DirEntry
+
+	name string
+}
+
+func (e renamedEntry) Name() string { return e.name }
+
+func TestChartDiscovererPathCheck(t *testing.T) {
+	root := t.TempDir()
+	base := filepath.Join(root, "base")
+
+	if err := os.Mkdir(base, 0o750); err != nil {
+		t.Fatal(err)
+	}
+
+	createTestFiles(t, root, map[string]string{testAppFile: testAppContent})
+
+	// The directory lists a manifest that resolves outside of it.
+	readDir := func(string) ([]os.DirEntry, error) {
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			return nil, err
+		}
+
+		i := slices.IndexFunc(entries, func(e os.DirEntry) bool { return e.Name() == testAppFile })
+
+		return []os.DirEntry{renamedEntry{DirEntry: entries[i], name: "../" + testAppFile}}, nil
+	}
+
+	tests := []struct {
+		name   string
+		inBase PathChecker
+		want   int
+	}{
+		{name: "containment check", inBase: isValidPath, want: 0},
+		{name: "allow outside base", inBase: anyPath, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			charts, err := MakeChartDiscoverer(os.Stat, readDir, readYAMLDocuments, anyFile, tt.inBase, discardLog)(base)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(charts) != tt.want {
+				t.Errorf("discovered %d chart(s), want %d", len(charts), tt.want)
+			}
+		})
+	}
+}
+
+func TestDiscoverDirsGlobDuplicates(t *testing.T) {
+	root := t.TempDir()
+	clusters := filepath.Join(root, "clusters")
+
+	prod := filepath.Join(clusters, "prod", "apps")
+	if err := os.MkdirAll(prod, 0o750); err != nil {
+		t.Fatal(err)
+	}
+
+	createTestFiles(t, prod, map[string]string{testAppFile: testAppContent})
+
+	// "staging" and "zz-prod" are other names for the prod cluster.
+	if err := os.Symlink("prod", filepath.Join(clusters, "staging")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	if err := os.Symlink(filepath.Join(clusters, "prod"), filepath.Join(clusters, "zz-prod")); err != nil {
+		t.Fatal(err)
+	}
+
+	discover := MakeChartDiscoverer(os.Stat, os.ReadDir, readYAMLDocuments, anyFile, isValidPath, discardLog)
+
+	var warnings bytes.Buffer
+
+	charts, err := discoverDirs(discover, filepath.Join(clusters, "*", "apps"), NewLogger(&warnings, LogNormal))
+	if err != nil {
+		t.Fatalf("discoverDirs() error = %v", err)
+	}
+
+	if len(charts) != 1 || chartPath(Config{Dir: "unused"}, charts[0]) != filepath.Join(prod, testAppFile) {
+		t.Errorf("discoverDirs() = %+v, want only %s", charts, filepath.Join(prod, testAppFile))
+	}
+
+	want := "▶ warning: " + filepath.Join(clusters, "staging", "apps") + " is the same directory as " + prod + ", scanning it once\n" +
+		"▶ warning: " + filepath.Join(clusters, "zz-prod", "apps") + " is the same directory as " + prod + ", scanning it once\n"
+	if got := warnings.String(); got != want {
+		t.Errorf("warnings =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestUniqueDirs(t *testing.T) {
+	root := t.TempDir()
+	t.Chdir(root)
+
+	for _, d := range []string{"apps", filepath.Join("apps", "nested"), "other"} {
+		if err := os.MkdirAll(d, 0o750); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	abs := filepath.Join(root, "apps")
+
+	got, duplicates := uniqueDirs([]string{"apps", filepath.Join("apps", "nested"), "./apps", abs, "other", "apps/"})
+
+	if want := []string{"apps", filepath.Join("apps", "nested"), "other"}; !slices.Equal(got, want) {
+		t.Errorf("uniqueDirs() = %v, want %v", got, want)
+	}
+
+	wantDuplicates := []DuplicateDir{{Dir: "./apps", SameAs: "apps"}, {Dir: abs, SameAs: "apps"}, {Dir: "apps/", SameAs: "apps"}}
+	if !slices.Equal(duplicates, wantDuplicates) {
+		t.Errorf("uniqueDirs() duplicates = %v, want %v", duplicates, wantDuplicates)
+	}
+}
+
+func TestDiscoverDirsGlobErrors(t *testing.T) {
+	root := t.TempDir()
+	createTestFiles(t, root, map[string]string{"notes.txt": "not a directory"})
+
+	discover := MakeChartDiscoverer(os.Stat, os.ReadDir, readYAMLDocuments, anyFile, isValidPath, discardLog)
+
+	tests := []struct {
+		name    string
+		pattern string
+		wantErr string
+	}{
+		{name: "no matches", pattern: filepath.Join(root, "clusters", "*"), wantErr: "no directories match"},
+		{name: "match is a file", pattern: filepath.Join(root, "*.txt"), wantErr: "not a directory"},
+		{name: "malformed pattern", pattern: filepath.Join(root, "["), wantErr: "invalid directory pattern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := discoverDirs(discover, tt.pattern, NewLogger(io.Discard, LogNormal))
+			if err == nil || !contains(err.Error(), tt.wantErr) {
+				t.Errorf("discoverDirs() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDirProber(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, testAppFile)
+	createTestFiles(t, dir, map[string]string{testAppFile: "kind: [not parsed"})
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr string
+	}{
+		{name: "readable directory", path: dir, wantErr: ""},
+		{name: "missing directory", path: filepath.Join(dir, "missing"), wantErr: "cannot access directory"},
+		{name: "file path", path: file, wantErr: "not a directory"},
+	}
+
+	probe := MakeDirProber(os.Stat, os.ReadDir)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := probe(tt.path)
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("probe() error = %v, want nil", err)
+				}
+
+				return
+			}
+
+			if err == nil || !contains(err.Error(), tt.wantErr) {
+				t.Errorf("probe() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestScanDirs(t *testing.T) {
+	dir := t.TempDir()
+	createTestFiles(t, dir, map[string]string{
+		"app1.yaml":      "kind: Application\n---\nkind: Application\n",
+		"app2.yml":       testAppContent,
+		"secret.yaml":    "kind: Secret\n",
+		"notes.txt":      "kind: Application\n",
+		chartSourcesFile: "app1.yaml: org/chart\n",
+	})
+
+	got, err := scanDirs(os.ReadDir, readYAMLDocuments, dir)
+	if err != nil {
+		t.Fatalf("scanDirs() error = %v", err)
+	}
+
+	want := ScanResult{YAMLFiles: 3, Applications: 3, Uncommented: 2}
+	if got != want {
+		t.Errorf("scanDirs() = %+v, want %+v", got, want)
+	}
+}
+
+func TestNoChartsError(t *testing.T) {
+	tests := []struct {
+		name string
+		scan ScanResult
+		want string
+	}{
+		{
+			name: "empty directory",
+			scan: ScanResult{YAMLFiles: 0, Applications: 0, Uncommented: 0},
+			want: "scanned 0 YAML file(s), found 0 Application(s) without an artifacthub comment; check that --dir points",
+		},
+		{
+			name: "no applications",
+			scan: ScanResult{YAMLFiles: 4, Applications: 0, Uncommented: 0},
+			want: "scanned 4 YAML file(s), found 0 Application(s) without an artifacthub comment; none of the YAML files",
+		},
+		{
+			name: "applications missing comments",
+			scan: ScanResult{YAMLFiles: 4, Applications: 3, Uncommented: 3},
+			want: "scanned 4 YAML file(s), found 3 Application(s) without an artifacthub comment; add",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := noChartsError("argoapps", tt.scan)
+			if !contains(err.Error(), "no charts with artifacthub comments found in argoapps") || !contains(err.Error(), tt.want) {
+				t.Errorf("noChartsError() = %q, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// chartInfoAt reads the manifest at path with toChartInfo, as discovery does,
+// and returns what it logged as warnings alongside the chart.
+func chartInfoAt(read YAMLReader, path string) (ChartInfo, string) {
+	var warnings bytes.Buffer
+
+	info := toChartInfo(read, path, filepath.Dir(path), nil, NewLogger(&warnings, LogNormal).Warn)
+
+	return info, warnings.String()
+}
+
+func TestExtractArtifactHubRepoErrors(t *testing.T) {
+	t.Run("repo with internal whitespace", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), testAppFile)
+		if err := os.WriteFile(path, []byte("# artifacthub: org /chart\nkind: Application"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		_, warnings := chartInfoAt(readYAMLDocuments, path)
+		if !contains(warnings, "must not contain whitespace") {
+			t.Errorf("toChartInfo() warnings = %q, want whitespace error", warnings)
+		}
+	})
+}
+
 func TestExtractArtifactHubRepo(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -206,11 +708,21 @@ func TestExtractArtifactHubRepo(t *testing.T) {
 			content: "# artifacthub:   org/chart  \nkind: Application",
 			want:    testChartRepo,
 		},
+		{
+			name:    "comment with tabs",
+			content: "# artifacthub:\torg/chart\t\nkind: Application",
+			want:    testChartRepo,
+		},
 		{
 			name:    "wrong comment prefix",
 			content: "# other: org/chart\nkind: Application",
 			want:    "",
 		},
+		{
+			name:    "application set",
+			content: testAppSetContent,
+			want:    testChartRepo,
+		},
 	}
 
 	for _, tt := range tests {
@@ -220,14 +732,14 @@ func TestExtractArtifactHubRepo(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			got, err := extractArtifactHubRepo(readYAMLDocuments, path)
-			if err != nil {
-				t.Errorf("extractArtifactHubRepo() error = %v", err)
+			got, warnings := chartInfoAt(readYAMLDocuments, path)
+			if warnings != "" {
+				t.Errorf("toChartInfo() warnings = %q", warnings)
 				return
 			}
 
-			if got != tt.want {
-				t.Errorf("extractArtifactHubRepo() = %q, want %q", got, tt.want)
+			if got.Repo != tt.want {
+				t.Errorf("toChartInfo() = %q, want %q", got.Repo, tt.want)
 			}
 		})
 	}
@@ -246,9 +758,10 @@ func TestParseConfig(t *testing.T) {
 			args: []string{},
 			env:  nil,
 			want: Config{
-				Dir:       defaultArgoAppsDir,
-				DryRun:    false,
-				CheckOnly: false,
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
 			},
 			wantErr: false,
 		},
@@ -259,9 +772,10 @@ func TestParseConfig(t *testing.T) {
 			},
 			args: []string{},
 			want: Config{
-				Dir:       "custom/dir",
-				DryRun:    false,
-				CheckOnly: false,
+				Dir:         "custom/dir",
+				DryRun:      false,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
 			},
 			wantErr: false,
 		},
@@ -270,9 +784,10 @@ func TestParseConfig(t *testing.T) {
 			args: []string{"--dir", "flag/dir"},
 			env:  nil,
 			want: Config{
-				Dir:       "flag/dir",
-				DryRun:    false,
-				CheckOnly: false,
+				Dir:         "flag/dir",
+				DryRun:      false,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
 			},
 			wantErr: false,
 		},
@@ -283,9 +798,10 @@ func TestParseConfig(t *testing.T) {
 			},
 			args: []string{"--dir", "flag/dir"},
 			want: Config{
-				Dir:       "flag/dir",
-				DryRun:    false,
-				CheckOnly: false,
+				Dir:         "flag/dir",
+				DryRun:      false,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
 			},
 			wantErr: false,
 		},
@@ -294,9 +810,10 @@ func TestParseConfig(t *testing.T) {
 			args: []string{"-n"},
 			env:  nil,
 			want: Config{
-				Dir:       defaultArgoAppsDir,
-				DryRun:    true,
-				CheckOnly: false,
+				Dir:         defaultArgoAppsDir,
+				DryRun:      true,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
 			},
 			wantErr: false,
 		},
@@ -305,9 +822,10 @@ func TestParseConfig(t *testing.T) {
 			args: []string{"--dry-run"},
 			env:  nil,
 			want: Config{
-				Dir:       defaultArgoAppsDir,
-				DryRun:    true,
-				CheckOnly: false,
+				Dir:         defaultArgoAppsDir,
+				DryRun:      true,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
 			},
 			wantErr: false,
 		},
@@ -316,9 +834,10 @@ func TestParseConfig(t *testing.T) {
 			args: []string{"-C"},
 			env:  nil,
 			want: Config{
-				Dir:       defaultArgoAppsDir,
-				DryRun:    false,
-				CheckOnly: true,
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   true,
+				OptOutLabel: defaultOptOutLabel,
 			},
 			wantErr: false,
 		},
@@ -327,9 +846,10 @@ func TestParseConfig(t *testing.T) {
 			args: []string{"--check"},
 			env:  nil,
 			want: Config{
-				Dir:       defaultArgoAppsDir,
-				DryRun:    false,
-				CheckOnly: true,
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   true,
+				OptOutLabel: defaultOptOutLabel,
 			},
 			wantErr: false,
 		},
@@ -338,9 +858,10 @@ func TestParseConfig(t *testing.T) {
 			args: []string{"--dry-run", "--check"},
 			env:  nil,
 			want: Config{
-				Dir:       defaultArgoAppsDir,
-				DryRun:    true,
-				CheckOnly: true,
+				Dir:         defaultArgoAppsDir,
+				DryRun:      true,
+				CheckOnly:   true,
+				OptOutLabel: defaultOptOutLabel,
 			},
 			wantErr: true,
 		},
@@ -349,9 +870,10 @@ func TestParseConfig(t *testing.T) {
 			args: []string{"--dir"},
 			env:  nil,
 			want: Config{
-				Dir:       defaultArgoAppsDir,
-				DryRun:    false,
-				CheckOnly: false,
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
 			},
 			wantErr: true,
 		},
@@ -360,42 +882,1128 @@ func TestParseConfig(t *testing.T) {
 			args: []string{"--unknown"},
 			env:  nil,
 			want: Config{
-				Dir:       defaultArgoAppsDir,
-				DryRun:    false,
-				CheckOnly: false,
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
 			},
 			wantErr: true,
 		},
 		{
-			name: "ignore test flags",
-			args: []string{"-test.v"},
+			name: "repo with version",
+			args: []string{"--repo", testChartRepo, "--version", "1.0.0"},
 			env:  nil,
 			want: Config{
-				Dir:       defaultArgoAppsDir,
-				DryRun:    false,
-				CheckOnly: false,
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				Repo:        testChartRepo,
+				Current:     "1.0.0",
+				OptOutLabel: defaultOptOutLabel,
 			},
 			wantErr: false,
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			getEnv := func(key string) string {
-				if tt.env == nil {
-					return ""
-				}
-
-				return tt.env[key]
-			}
-
-			got, err := ParseConfig(tt.args, getEnv)
+		{
+			name: "repo short",
+			args: []string{"-r", testChartRepo},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				Repo:        testChartRepo,
+				Current:     "",
+				OptOutLabel: defaultOptOutLabel,
+			},
+			wantErr: false,
+		},
+		{
+			name: "version without repo",
+			args: []string{"--version", "1.0.0"},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				Repo:        "",
+				Current:     "1.0.0",
+				OptOutLabel: defaultOptOutLabel,
+			},
+			wantErr: true,
+		},
+		{
+			name: "repo and check incompatible",
+			args: []string{"--repo", testChartRepo, "--check"},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   true,
+				Repo:        testChartRepo,
+				Current:     "",
+				OptOutLabel: defaultOptOutLabel,
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing repo argument",
+			args: []string{"--repo"},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				Repo:        "",
+				Current:     "",
+				OptOutLabel: defaultOptOutLabel,
+			},
+			wantErr: true,
+		},
+		{
+			name: "history file",
+			args: []string{"--history", "history.csv"},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				Repo:        "",
+				Current:     "",
+				History:     "history.csv",
+				OptOutLabel: defaultOptOutLabel,
+			},
+			wantErr: false,
+		},
+		{
+			name: "skip unreachable",
+			args: []string{"--skip-unreachable"},
+			env:  nil,
+			want: Config{
+				Dir:             defaultArgoAppsDir,
+				DryRun:          false,
+				CheckOnly:       false,
+				Repo:            "",
+				Current:         "",
+				History:         "",
+				SkipUnreachable: true,
+				OptOutLabel:     defaultOptOutLabel,
+			},
+			wantErr: false,
+		},
+		{
+			name: "max per host",
+			args: []string{"--max-per-host", "3"},
+			env:  nil,
+			want: Config{
+				Dir:             defaultArgoAppsDir,
+				DryRun:          false,
+				CheckOnly:       false,
+				Repo:            "",
+				Current:         "",
+				History:         "",
+				SkipUnreachable: false,
+				MaxPerHost:      3,
+				OptOutLabel:     defaultOptOutLabel,
+			},
+			wantErr: false,
+		},
+		{
+			name: "opt-out label",
+			args: []string{"--opt-out-label", "renovate"},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				OptOutLabel: "renovate",
+			},
+			wantErr: false,
+		},
+		{
+			name: "print effective versions",
+			args: []string{"--print-effective-versions"},
+			env:  nil,
+			want: Config{
+				Dir:            defaultArgoAppsDir,
+				DryRun:         false,
+				CheckOnly:      false,
+				OptOutLabel:    defaultOptOutLabel,
+				PrintEffective: true,
+			},
+			wantErr: false,
+		},
+		{
+			name: "chart name",
+			args: []string{"--chart", "redis"},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
+				ChartName:   "redis",
+			},
+			wantErr: false,
+		},
+		{
+			name:    "chart name with repo",
+			args:    []string{"--chart", "redis", "--repo", "bitnami/redis"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name: "dry run exit code",
+			args: []string{"--dry-run", "--dry-run-exit-code", "2"},
+			env:  nil,
+			want: Config{
+				Dir:            defaultArgoAppsDir,
+				DryRun:         true,
+				CheckOnly:      false,
+				OptOutLabel:    defaultOptOutLabel,
+				DryRunExitCode: 2,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "dry run exit code without dry run",
+			args:    []string{"--dry-run-exit-code", "2"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name: "fail on list",
+			args: []string{"--fail-on", "error,outdated"},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
+				FailOn:      []string{FailOnError, FailOnOutdated},
+			},
+			wantErr: false,
+		},
+		{
+			name: "fail on with equals",
+			args: []string{"--fail-on=skipped"},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
+				FailOn:      []string{FailOnSkipped},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "fail on unknown outcome",
+			args:    []string{"--fail-on", "error,unmaintained"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name: "max idle conns per host",
+			args: []string{"--max-idle-conns-per-host", "32"},
+			env:  nil,
+			want: Config{
+				Dir:                 defaultArgoAppsDir,
+				DryRun:              false,
+				CheckOnly:           false,
+				OptOutLabel:         defaultOptOutLabel,
+				MaxIdleConnsPerHost: 32,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "negative max idle conns per host",
+			args:    []string{"--max-idle-conns-per-host", "-1"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name: "only kind",
+			args: []string{"--only-kind", "Application"},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
+				OnlyKind:    KindApplication,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "only kind not supported",
+			args:    []string{"--only-kind", "Deployment"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name: "never downgrade",
+			args: []string{"--never-downgrade"},
+			env:  nil,
+			want: Config{
+				Dir:            defaultArgoAppsDir,
+				DryRun:         false,
+				CheckOnly:      false,
+				OptOutLabel:    defaultOptOutLabel,
+				NeverDowngrade: true,
+			},
+			wantErr: false,
+		},
+		{
+			name: "dump response",
+			args: []string{"--dump-response", "cilium/cilium"},
+			env:  nil,
+			want: Config{
+				Dir:          defaultArgoAppsDir,
+				DryRun:       false,
+				CheckOnly:    false,
+				OptOutLabel:  defaultOptOutLabel,
+				DumpResponse: "cilium/cilium",
+			},
+			wantErr: false,
+		},
+		{
+			name:    "dump response with dry run",
+			args:    []string{"--dump-response", "cilium/cilium", "--dry-run"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name: "check consistency",
+			args: []string{"--check-consistency"},
+			env:  nil,
+			want: Config{
+				Dir:              defaultArgoAppsDir,
+				DryRun:           false,
+				CheckOnly:        false,
+				OptOutLabel:      defaultOptOutLabel,
+				CheckConsistency: true,
+			},
+			wantErr: false,
+		},
+		{
+			name: "stable rule",
+			args: []string{"--stable-rule", "semver-prerelease"},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
+				StableRule:  StabilitySemverPrerelease,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "unknown stable rule",
+			args:    []string{"--stable-rule", "calver"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name: "verify writes",
+			args: []string{"--verify-writes"},
+			env:  nil,
+			want: Config{
+				Dir:          defaultArgoAppsDir,
+				DryRun:       false,
+				CheckOnly:    false,
+				OptOutLabel:  defaultOptOutLabel,
+				VerifyWrites: true,
+			},
+			wantErr: false,
+		},
+		{
+			name: "preserve format",
+			args: []string{"--preserve-format"},
+			env:  nil,
+			want: Config{
+				Dir:            defaultArgoAppsDir,
+				DryRun:         false,
+				CheckOnly:      false,
+				OptOutLabel:    defaultOptOutLabel,
+				PreserveFormat: true,
+			},
+			wantErr: false,
+		},
+		{
+			name: "quiet short flag",
+			args: []string{"-q"},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
+				Quiet:       true,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "quiet with verbose",
+			args:    []string{"--quiet", "--verbose"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name: "builtin diff mode",
+			args: []string{"--dry-run", "--diff-mode", "builtin"},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      true,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
+				DiffMode:    DiffModeBuiltin,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "unknown diff mode",
+			args:    []string{"--dry-run", "--diff-mode", "patience"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name:    "builtin diff mode with diff base",
+			args:    []string{"--dry-run", "--diff-mode", "builtin", "--diff-base", "main"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name: "values files repeated",
+			args: []string{"--values-file", "a.yaml,b.yaml", "--values-file=c.yaml"},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
+				ValuesFiles: []string{"a.yaml", "b.yaml", "c.yaml"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "sort docs",
+			args: []string{"--sort-docs"},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
+				SortDocs:    true,
+			},
+			wantErr: false,
+		},
+		{
+			name: "verbose short flag",
+			args: []string{"-v"},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
+				Verbose:     true,
+			},
+			wantErr: false,
+		},
+		{
+			name: "batch size",
+			args: []string{"--batch-size", "50"},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
+				BatchSize:   50,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "negative batch size",
+			args:    []string{"--batch-size", "-5"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name: "patch out",
+			args: []string{"--dry-run", "--patch-out", "changes.patch"},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      true,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
+				PatchOut:    "changes.patch",
+			},
+			wantErr: false,
+		},
+		{
+			name:    "patch out without dry run",
+			args:    []string{"--patch-out", "changes.patch"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name:    "patch out with diff base",
+			args:    []string{"--dry-run", "--diff-base", "main", "--patch-out", "changes.patch"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name: "check chart name",
+			args: []string{"--check-chart-name"},
+			env:  nil,
+			want: Config{
+				Dir:            defaultArgoAppsDir,
+				DryRun:         false,
+				CheckOnly:      false,
+				OptOutLabel:    defaultOptOutLabel,
+				CheckChartName: true,
+			},
+			wantErr: false,
+		},
+		{
+			name: "changelog",
+			args: []string{"--changelog", "CHANGELOG.md"},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
+				Changelog:   "CHANGELOG.md",
+			},
+			wantErr: false,
+		},
+		{
+			name:    "changelog with dry run",
+			args:    []string{"--dry-run", "--changelog", "CHANGELOG.md"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name: "summary format",
+			args: []string{"--summary-format", "{{.Updated}}/{{.Errors}}"},
+			env:  nil,
+			want: Config{
+				Dir:           defaultArgoAppsDir,
+				DryRun:        false,
+				CheckOnly:     false,
+				OptOutLabel:   defaultOptOutLabel,
+				SummaryFormat: "{{.Updated}}/{{.Errors}}",
+			},
+			wantErr: false,
+		},
+		{
+			name:    "invalid summary format",
+			args:    []string{"--summary-format", "{{.Updated"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name: "require signed",
+			args: []string{"--require-signed"},
+			env:  nil,
+			want: Config{
+				Dir:           defaultArgoAppsDir,
+				DryRun:        false,
+				CheckOnly:     false,
+				OptOutLabel:   defaultOptOutLabel,
+				RequireSigned: true,
+			},
+			wantErr: false,
+		},
+		{
+			name: "max requests",
+			args: []string{"--max-requests", "100"},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
+				MaxRequests: 100,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "negative max requests",
+			args:    []string{"--max-requests", "-1"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name: "allow outside base",
+			args: []string{"--allow-outside-base"},
+			env:  nil,
+			want: Config{
+				Dir:              defaultArgoAppsDir,
+				DryRun:           false,
+				CheckOnly:        false,
+				OptOutLabel:      defaultOptOutLabel,
+				AllowOutsideBase: true,
+			},
+			wantErr: false,
+		},
+		{
+			name: "config print with source",
+			args: []string{"--config-print", "--with-source"},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
+				ConfigPrint: true,
+				WithSource:  true,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "with source requires config print",
+			args:    []string{"--with-source"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name: "idle timeout",
+			args: []string{"--idle-timeout", "10s"},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
+				IdleTimeout: 10 * time.Second,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "invalid idle timeout",
+			args:    []string{"--idle-timeout", "soon"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name:    "negative idle timeout",
+			args:    []string{"--idle-timeout", "-1s"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name: "resume",
+			args: []string{"--resume"},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
+				Resume:      true,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "resume with dry run",
+			args:    []string{"--resume", "--dry-run"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name: "compact dry run",
+			args: []string{"--dry-run", "--compact"},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      true,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
+				Compact:     true,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "compact without dry run",
+			args:    []string{"--compact"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name:    "compact with suggest",
+			args:    []string{"--dry-run", "--compact", "--suggest"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name: "max bump",
+			args: []string{"--max-bump", "minor"},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
+				MaxBump:     BumpMinor,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "unknown max bump",
+			args:    []string{"--max-bump", "huge"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name: "concurrency",
+			args: []string{"--concurrency", "8"},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
+				Concurrency: 8,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "negative concurrency",
+			args:    []string{"--concurrency", "-1"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name: "json output",
+			args: []string{"--output", "json"},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
+				Output:      OutputJSON,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "unknown output format",
+			args:    []string{"--output", "yaml"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name:    "json output with suggest",
+			args:    []string{"--dry-run", "--suggest", "--output", "json"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name: "artifacthub api key from env",
+			env: map[string]string{
+				apiKeyIDEnvVar:     "key-id",
+				apiKeySecretEnvVar: "key-secret",
+			},
+			args: []string{},
+			want: Config{
+				Dir:            defaultArgoAppsDir,
+				DryRun:         false,
+				CheckOnly:      false,
+				OptOutLabel:    defaultOptOutLabel,
+				ArtifactHubKey: ArtifactHubKey{ID: "key-id", Secret: "key-secret"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "artifacthub api key id without secret",
+			env: map[string]string{
+				apiKeyIDEnvVar: "key-id",
+			},
+			args:    []string{},
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name: "exit code",
+			args: []string{"--exit-code"},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      true,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
+				ExitCode:    true,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "exit code with dry run exit code",
+			args:    []string{"--dry-run-exit-code", "3", "--exit-code"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name:    "exit code with check",
+			args:    []string{"--exit-code", "--check"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name: "include and exclude repeated",
+			args: []string{"--include", "*.app.yaml", "--exclude", "legacy-*", "--include", "apps-*.yaml"},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
+				Include:     []string{"*.app.yaml", "apps-*.yaml"},
+				Exclude:     []string{"legacy-*"},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "malformed exclude pattern",
+			args:    []string{"--exclude", "legacy-["},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name: "commit single",
+			args: []string{"--commit", "--commit-mode", "single"},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
+				Commit:      true,
+				CommitMode:  CommitSingle,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "commit with dry run",
+			args:    []string{"--commit", "--dry-run"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name:    "commit mode without commit",
+			args:    []string{"--commit-mode", "single"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name:    "unknown commit mode",
+			args:    []string{"--commit", "--commit-mode", "per-file"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name: "markdown report to file",
+			args: []string{"--report", "markdown", "--report-file", "pr-body.md"},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
+				Report:      ReportMarkdown,
+				ReportFile:  "pr-body.md",
+			},
+			wantErr: false,
+		},
+		{
+			name:    "unknown report format",
+			args:    []string{"--report", "html"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name:    "report file without report",
+			args:    []string{"--report-file", "pr-body.md"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name: "api url from env",
+			env: map[string]string{
+				apiURLEnvVar: "https://hub.internal/api/v1/packages/helm",
+			},
+			args: []string{},
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
+				APIURL:      "https://hub.internal/api/v1/packages/helm",
+			},
+			wantErr: false,
+		},
+		{
+			name: "api url flag overrides env var",
+			env: map[string]string{
+				apiURLEnvVar: "https://hub.internal/api/v1/packages/helm",
+			},
+			args: []string{"--api-url", "http://localhost:8080/api/v1/packages/helm"},
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
+				APIURL:      "http://localhost:8080/api/v1/packages/helm",
+			},
+			wantErr: false,
+		},
+		{
+			name:    "api url without scheme",
+			args:    []string{"--api-url", "hub.internal/api"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name: "http timeout",
+			args: []string{"--timeout", "30s"},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
+				HTTPTimeout: 30 * time.Second,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "invalid http timeout",
+			args:    []string{"--timeout", "30"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name:    "negative http timeout",
+			args:    []string{"--timeout", "-5s"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name:    "dry run exit code out of range",
+			args:    []string{"--dry-run", "--dry-run-exit-code", "300"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name: "diff base",
+			args: []string{"--dry-run", "--diff-base", "origin/main"},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      true,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
+				DiffBase:    "origin/main",
+			},
+			wantErr: false,
+		},
+		{
+			name:    "diff base without dry run",
+			args:    []string{"--diff-base", "origin/main"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name: "freeze until",
+			args: []string{"--freeze-until", "2026-12-31T23:59:59Z"},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
+				FreezeUntil: time.Date(2026, 12, 31, 23, 59, 59, 0, time.UTC),
+			},
+			wantErr: false,
+		},
+		{
+			name:    "freeze until not a timestamp",
+			args:    []string{"--freeze-until", "tomorrow"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name:    "probe with check",
+			args:    []string{"--probe", "--check"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name: "fetch limit",
+			args: []string{"--fetch-limit", "20"},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
+				FetchLimit:  20,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "negative fetch limit",
+			args:    []string{"--fetch-limit", "-1"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name: "repeated repo mappings",
+			args: []string{"--map-repo", "oldorg=neworg", "--map-repo", "a/chart=b/chart", "--rewrite-moved"},
+			env:  nil,
+			want: Config{
+				Dir:          defaultArgoAppsDir,
+				DryRun:       false,
+				CheckOnly:    false,
+				OptOutLabel:  defaultOptOutLabel,
+				RepoMap:      map[string]string{"oldorg": "neworg", "a/chart": "b/chart"},
+				RewriteMoved: true,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "malformed repo mapping",
+			args:    []string{"--map-repo", "oldorg"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name:    "rewrite moved without mapping",
+			args:    []string{"--rewrite-moved"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name: "stamp checked",
+			args: []string{"--stamp-checked"},
+			env:  nil,
+			want: Config{
+				Dir:          defaultArgoAppsDir,
+				DryRun:       false,
+				CheckOnly:    false,
+				OptOutLabel:  defaultOptOutLabel,
+				StampChecked: true,
+			},
+			wantErr: false,
+		},
+		{
+			name: "prerelease within current major",
+			args: []string{"--prerelease-within-current-major"},
+			env:  nil,
+			want: Config{
+				Dir:                 defaultArgoAppsDir,
+				DryRun:              false,
+				CheckOnly:           false,
+				OptOutLabel:         defaultOptOutLabel,
+				PrereleaseSameMajor: true,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "max per host not a number",
+			args:    []string{"--max-per-host", "many"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name:    "max per host negative",
+			args:    []string{"--max-per-host", "-1"},
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+		{
+			name: "ignore test flags",
+			args: []string{"-test.v"},
+			env:  nil,
+			want: Config{
+				Dir:         defaultArgoAppsDir,
+				DryRun:      false,
+				CheckOnly:   false,
+				OptOutLabel: defaultOptOutLabel,
+			},
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getEnv := func(key string) string {
+				if tt.env == nil {
+					return ""
+				}
+
+				return tt.env[key]
+			}
+
+			got, err := ParseConfig(tt.args, getEnv)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ParseConfig() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 
-			if !tt.wantErr && got != tt.want {
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("ParseConfig() = %+v, want %+v", got, tt.want)
 			}
 		})
@@ -416,3 +2024,254 @@ func searchSubstring(s, substr string) bool {
 
 	return false
 }
+
+func TestExpandResponseFiles(t *testing.T) {
+	files := map[string]string{
+		"flags.txt":  "--dir clusters/prod\n\t--dry-run  --history\thistory.csv\n",
+		"nested.txt": "--check @flags.txt",
+		"empty.txt":  "  \n",
+	}
+
+	readFile := func(name string) ([]byte, error) {
+		content, ok := files[name]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+
+		return []byte(content), nil
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string
+		wantErr string
+	}{
+		{
+			name:    "no response files",
+			args:    []string{"--check"},
+			want:    []string{"--check"},
+			wantErr: "",
+		},
+		{
+			name:    "tokens spliced in place",
+			args:    []string{"-n", "@flags.txt", "--explain-version"},
+			want:    []string{"-n", "--dir", "clusters/prod", "--dry-run", "--history", "history.csv", "--explain-version"},
+			wantErr: "",
+		},
+		{
+			name:    "empty response file",
+			args:    []string{"@empty.txt"},
+			want:    []string{},
+			wantErr: "",
+		},
+		{
+			name:    "nested response file rejected",
+			args:    []string{"@nested.txt"},
+			want:    nil,
+			wantErr: "response file nested.txt: nested response file @flags.txt is not supported",
+		},
+		{
+			name:    "missing response file",
+			args:    []string{"@missing.txt"},
+			want:    nil,
+			wantErr: "read response file: file does not exist",
+		},
+		{
+			name:    "bare at sign",
+			args:    []string{"@"},
+			want:    nil,
+			wantErr: "@ requires a response file path",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandResponseFiles(tt.args, readFile)
+			assertError(t, tt.wantErr, err)
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("expandResponseFiles() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseConfigResponseFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.txt")
+	if err := os.WriteFile(path, []byte("--dir from/file\n--skip-unreachable\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ParseConfig([]string{"@" + path}, func(string) string { return "" })
+	if err != nil {
+		t.Fatalf("ParseConfig() error = %v", err)
+	}
+
+	if got.Dir != "from/file" || !got.SkipUnreachable {
+		t.Errorf("ParseConfig() = %+v, want Dir from/file and SkipUnreachable", got)
+	}
+}
+
+func TestExtractChartInfoLabels(t *testing.T) {
+	content := `# artifacthub: org/chart
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  labels:
+    chart-updater: disabled
+    team: platform
+  annotations:
+    argocd.argoproj.io/sync-wave: "5"
+spec:
+  source:
+    targetRevision: 1.0.0
+`
+
+	path := filepath.Join(t.TempDir(), testAppFile)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	info, warnings := chartInfoAt(readYAMLDocuments, path)
+	if warnings != "" {
+		t.Fatal(warnings)
+	}
+
+	want := map[string]string{
+		"chart-updater":                "disabled",
+		"team":                         "platform",
+		"argocd.argoproj.io/sync-wave": "5",
+	}
+	if !maps.Equal(info.Labels, want) {
+		t.Errorf("Labels = %v, want %v", info.Labels, want)
+	}
+
+	if !optedOut(info, defaultOptOutLabel) {
+		t.Error("expected chart to be opted out")
+	}
+
+	if optedOut(info, "team") {
+		t.Error("expected chart with team label not to be opted out")
+	}
+
+	if optedOut(info, "") {
+		t.Error("expected empty opt-out label to disable the check")
+	}
+}
+
+func TestFilterByChartName(t *testing.T) {
+	charts := []ChartInfo{
+		{File: "a.yaml", Repo: "bitnami/redis", Timeout: 0, Labels: nil},
+		{File: "b.yaml", Repo: "dandydev/redis", Timeout: 0, Labels: nil},
+		{File: "c.yaml", Repo: "bitnami/redis-cluster", Timeout: 0, Labels: nil},
+		{File: "d.yaml", Repo: "cilium/cilium", Timeout: 0, Labels: nil},
+	}
+
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{name: "redis", want: []string{"a.yaml", "b.yaml"}},
+		{name: "cilium", want: []string{"d.yaml"}},
+		{name: "bitnami", want: nil},
+		{name: "", want: []string{"a.yaml", "b.yaml", "c.yaml", "d.yaml"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, c := range filterByChartName(charts, tt.name) {
+				got = append(got, c.File)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("filterByChartName(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractChartInfoGitHubSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), testAppFile)
+
+	// Discovery reads only up to the first commented Application, so a
+	// "# github:" comment must end the scan just like an artifacthub one.
+	content := "kind: Application\n---\n# github: owner/chart\nkind: Application\n---\n# artifacthub: org/other\nkind: Application\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, read := range []YAMLReader{readYAMLDocuments, readFirstArtifactHubApplication} {
+		got, warnings := chartInfoAt(read, path)
+		if warnings != "" {
+			t.Fatal(warnings)
+		}
+
+		if got.Repo != "owner/chart" || got.Source != sourceGitHub {
+			t.Errorf("toChartInfo() = %q from %q, want %q from %q", got.Repo, got.Source, "owner/chart", sourceGitHub)
+		}
+	}
+}
+
+func TestExtractChartInfoTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    time.Duration
+		wantErr bool
+	}{
+		{
+			name:    "no timeout annotation",
+			content: testAppContent,
+			want:    0,
+			wantErr: false,
+		},
+		{
+			name:    "timeout annotation",
+			content: "# artifacthub: org/chart\n# artifacthub-timeout: 30s\nkind: Application",
+			want:    30 * time.Second,
+			wantErr: false,
+		},
+		{
+			name:    "timeout annotation before repo",
+			content: "# artifacthub-timeout:\t2m \n# artifacthub: org/chart\nkind: Application",
+			want:    2 * time.Minute,
+			wantErr: false,
+		},
+		{
+			name:    "invalid timeout",
+			content: "# artifacthub: org/chart\n# artifacthub-timeout: soon\nkind: Application",
+			want:    0,
+			wantErr: true,
+		},
+		{
+			name:    "non-positive timeout",
+			content: "# artifacthub: org/chart\n# artifacthub-timeout: 0s\nkind: Application",
+			want:    0,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), testAppFile)
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			got, warnings := chartInfoAt(readYAMLDocuments, path)
+			if (warnings != "") != tt.wantErr {
+				t.Fatalf("toChartInfo() warnings = %q, wantErr %v", warnings, tt.wantErr)
+			}
+
+			if got.Timeout != tt.want {
+				t.Errorf("toChartInfo() Timeout = %v, want %v", got.Timeout, tt.want)
+			}
+
+			if !tt.wantErr && got.Repo != testChartRepo {
+				t.Errorf("toChartInfo() Repo = %q, want %q", got.Repo, testChartRepo)
+			}
+		})
+	}
+}
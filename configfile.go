@@ -0,0 +1,180 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileSettings is the subset of Config that can be persisted in a config file.
+// Unset fields are nil so they leave the corresponding Config value untouched.
+type fileSettings struct {
+	Dir             *string `yaml:"dir"`
+	History         *string `yaml:"history"`
+	SkipUnreachable *bool   `yaml:"skipUnreachable"`
+	MaxPerHost      *int    `yaml:"maxPerHost"`
+	OptOutLabel     *string `yaml:"optOutLabel"`
+	FreezeUntil     *string `yaml:"freezeUntil"`
+	DryRun          *bool   `yaml:"dryRun"`
+	CheckOnly       *bool   `yaml:"checkOnly"`
+	Concurrency     *int    `yaml:"concurrency"`
+}
+
+// fileConfig is a config file: base settings plus named profiles that can be
+// merged over them with --profile.
+type fileConfig struct {
+	fileSettings `yaml:",inline"`
+
+	Profiles map[string]fileSettings `yaml:"profiles"`
+}
+
+// defaultConfigFile is read from the working directory when --config is not
+// given. Unlike a file named with --config, it may be missing.
+const defaultConfigFile = ".chart-version-updater.yaml"
+
+// configFilePath returns the config file to apply, the --config argument or
+// else defaultConfigFile, and whether it was given explicitly and so must exist.
+func configFilePath(args []string) (string, bool) {
+	if path := configFileArg(args); path != "" {
+		return path, true
+	}
+
+	return defaultConfigFile, false
+}
+
+// configFileArg returns the value of the last "--config <path>" in args, if any.
+// It runs before flag parsing so that flags can override values from the file.
+func configFileArg(args []string) string {
+	return lastFlagValue(args, "--config")
+}
+
+// profileArg returns the value of the last "--profile <name>" in args, if any,
+// for the same reason as configFileArg.
+func profileArg(args []string) string {
+	return lastFlagValue(args, "--profile")
+}
+
+// lastFlagValue returns the value of the last "name value" or "name=value" in args.
+func lastFlagValue(args []string, name string) string {
+	value := ""
+
+	for i := 0; i < len(args); i++ {
+		if v, ok := strings.CutPrefix(args[i], name+"="); ok {
+			value = v
+		} else if args[i] == name && i+1 < len(args) {
+			value = args[i+1]
+		}
+	}
+
+	return value
+}
+
+// applyConfigFile reads the YAML config file at path and applies the values it
+// sets to cfg, then those of the named profile, if any, over them. A relative
+// dir is resolved against the config file's directory, so the file works the
+// same from any working directory.
+func applyConfigFile(cfg Config, path, profile string, readFile func(string) ([]byte, error)) (Config, error) {
+	data, err := readFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("read config file: %w", err)
+	}
+
+	var fc fileConfig
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	if err := dec.Decode(&fc); err != nil && !errors.Is(err, io.EOF) {
+		return cfg, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	cfg, err = applyFileSettings(cfg, path, fc.fileSettings)
+	if err != nil || profile == "" {
+		return cfg, err
+	}
+
+	settings, ok := fc.Profiles[profile]
+	if !ok {
+		known := slices.Sorted(maps.Keys(fc.Profiles))
+		if len(known) == 0 {
+			return cfg, fmt.Errorf("config file %s: unknown profile %q, it defines no profiles", path, profile)
+		}
+
+		return cfg, fmt.Errorf("config file %s: unknown profile %q (known: %s)", path, profile, strings.Join(known, ", "))
+	}
+
+	return applyFileSettings(cfg, path, settings)
+}
+
+// applyFileSettings applies the values set in fc, read from the config file at path, to cfg.
+func applyFileSettings(cfg Config, path string, fc fileSettings) (Config, error) {
+	if fc.Dir != nil {
+		cfg.Dir = *fc.Dir
+		if !filepath.IsAbs(cfg.Dir) {
+			cfg.Dir = filepath.Join(filepath.Dir(path), cfg.Dir)
+		}
+	}
+
+	if fc.History != nil {
+		cfg.History = *fc.History
+	}
+
+	if fc.SkipUnreachable != nil {
+		cfg.SkipUnreachable = *fc.SkipUnreachable
+	}
+
+	if fc.MaxPerHost != nil {
+		cfg.MaxPerHost = *fc.MaxPerHost
+	}
+
+	if fc.OptOutLabel != nil {
+		cfg.OptOutLabel = *fc.OptOutLabel
+	}
+
+	if fc.DryRun != nil {
+		cfg.DryRun = *fc.DryRun
+	}
+
+	if fc.CheckOnly != nil {
+		cfg.CheckOnly = *fc.CheckOnly
+	}
+
+	if fc.Concurrency != nil {
+		cfg.Concurrency = *fc.Concurrency
+	}
+
+	if fc.FreezeUntil != nil {
+		t, err := time.Parse(time.RFC3339, *fc.FreezeUntil)
+		if err != nil {
+			return cfg, fmt.Errorf("config file %s: invalid freezeUntil %q", path, *fc.FreezeUntil)
+		}
+
+		cfg.FreezeUntil = t
+	}
+
+	return cfg, nil
+}
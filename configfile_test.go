@@ -0,0 +1,351 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+
+	dir := filepath.Join(t.TempDir(), "ci")
+	if err := os.Mkdir(dir, 0o750); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "updater.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestParseConfigConfigFile(t *testing.T) {
+	path := writeConfigFile(t, "dir: ../argoapps\nmaxPerHost: 4\noptOutLabel: renovate\n")
+	configDir := filepath.Dir(path)
+
+	tests := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		wantDir string
+	}{
+		{
+			name:    "relative dir resolves against the config file",
+			args:    []string{"--config", path},
+			env:     nil,
+			wantDir: filepath.Join(configDir, "..", "argoapps"),
+		},
+		{
+			name:    "env var overrides config file",
+			args:    []string{"--config", path},
+			env:     map[string]string{argoAppsDirEnvVar: "env/dir"},
+			wantDir: "env/dir",
+		},
+		{
+			name:    "dir flag overrides config file and stays relative to the working directory",
+			args:    []string{"--config", path, "--dir", "flag/dir"},
+			env:     nil,
+			wantDir: "flag/dir",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := ParseConfig(tt.args, func(key string) string { return tt.env[key] })
+			if err != nil {
+				t.Fatalf("ParseConfig() error = %v", err)
+			}
+
+			if cfg.Dir != tt.wantDir {
+				t.Errorf("Dir = %q, want %q", cfg.Dir, tt.wantDir)
+			}
+
+			if cfg.MaxPerHost != 4 || cfg.OptOutLabel != "renovate" {
+				t.Errorf("config file values not applied: MaxPerHost = %d, OptOutLabel = %q", cfg.MaxPerHost, cfg.OptOutLabel)
+			}
+		})
+	}
+}
+
+func TestParseConfigConfigFileAbsoluteDir(t *testing.T) {
+	abs := filepath.Join(t.TempDir(), "apps")
+	path := writeConfigFile(t, "dir: "+abs+"\n")
+
+	cfg, err := ParseConfig([]string{"--config", path}, func(string) string { return "" })
+	if err != nil {
+		t.Fatalf("ParseConfig() error = %v", err)
+	}
+
+	if cfg.Dir != abs {
+		t.Errorf("Dir = %q, want %q", cfg.Dir, abs)
+	}
+}
+
+func TestParseConfigConfigFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "malformed yaml", content: "dir: [unterminated\n"},
+		{name: "unknown field", content: "directory: apps\n"},
+		{name: "wrong type", content: "maxPerHost: many\n"},
+		{name: "invalid freeze timestamp", content: "freezeUntil: soon\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfigFile(t, tt.content)
+
+			if _, err := ParseConfig([]string{"--config", path}, func(string) string { return "" }); err == nil {
+				t.Error("ParseConfig() error = nil, want error")
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		missing := filepath.Join(t.TempDir(), "missing.yaml")
+
+		if _, err := ParseConfig([]string{"--config", missing}, func(string) string { return "" }); err == nil {
+			t.Error("ParseConfig() error = nil, want error for explicitly requested missing file")
+		}
+	})
+}
+
+func TestParseConfigConfigFileProfiles(t *testing.T) {
+	path := writeConfigFile(t, `dir: ../apps
+maxPerHost: 4
+optOutLabel: base
+profiles:
+  dev:
+    dir: ../apps/dev
+  prod:
+    dir: /srv/apps/prod
+    maxPerHost: 1
+`)
+	configDir := filepath.Dir(path)
+
+	tests := []struct {
+		name           string
+		args           []string
+		env            map[string]string
+		wantDir        string
+		wantMaxPerHost int
+	}{
+		{
+			name:           "no profile uses the base",
+			args:           []string{"--config", path},
+			env:            nil,
+			wantDir:        filepath.Join(configDir, "..", "apps"),
+			wantMaxPerHost: 4,
+		},
+		{
+			name:           "profile overrides only the keys it sets",
+			args:           []string{"--config", path, "--profile", "dev"},
+			env:            nil,
+			wantDir:        filepath.Join(configDir, "..", "apps", "dev"),
+			wantMaxPerHost: 4,
+		},
+		{
+			name:           "profile before config with equals form",
+			args:           []string{"--profile=prod", "--config", path},
+			env:            nil,
+			wantDir:        "/srv/apps/prod",
+			wantMaxPerHost: 1,
+		},
+		{
+			name:           "flags override the profile",
+			args:           []string{"--config", path, "--profile", "prod", "--max-per-host", "8"},
+			env:            nil,
+			wantDir:        "/srv/apps/prod",
+			wantMaxPerHost: 8,
+		},
+		{
+			name:           "env overrides the profile",
+			args:           []string{"--config", path, "--profile", "prod"},
+			env:            map[string]string{argoAppsDirEnvVar: "env/dir"},
+			wantDir:        "env/dir",
+			wantMaxPerHost: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := ParseConfig(tt.args, func(key string) string { return tt.env[key] })
+			if err != nil {
+				t.Fatalf("ParseConfig() error = %v", err)
+			}
+
+			if cfg.Dir != tt.wantDir || cfg.MaxPerHost != tt.wantMaxPerHost {
+				t.Errorf("Dir = %q, MaxPerHost = %d, want %q, %d", cfg.Dir, cfg.MaxPerHost, tt.wantDir, tt.wantMaxPerHost)
+			}
+
+			if cfg.OptOutLabel != "base" {
+				t.Errorf("OptOutLabel = %q, want the base value %q", cfg.OptOutLabel, "base")
+			}
+		})
+	}
+}
+
+func TestParseConfigConfigFileProfileErrors(t *testing.T) {
+	withProfiles := writeConfigFile(t, "profiles:\n  dev:\n    dir: dev\n  prod:\n    dir: prod\n")
+	withoutProfiles := writeConfigFile(t, "dir: apps\n")
+	nested := writeConfigFile(t, "profiles:\n  dev:\n    profiles:\n      inner:\n        dir: x\n")
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{
+			name:    "unknown profile",
+			args:    []string{"--config", withProfiles, "--profile", "stage"},
+			wantErr: `unknown profile "stage" (known: dev, prod)`,
+		},
+		{
+			name:    "file without profiles",
+			args:    []string{"--config", withoutProfiles, "--profile", "dev"},
+			wantErr: `unknown profile "dev", it defines no profiles`,
+		},
+		{
+			name:    "nested profiles",
+			args:    []string{"--config", nested, "--profile", "dev"},
+			wantErr: "field profiles not found",
+		},
+		{
+			name:    "profile without config",
+			args:    []string{"--profile", "dev"},
+			wantErr: "--profile requires --config",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseConfig(tt.args, func(string) string { return "" })
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseConfig() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseConfigDefaultConfigFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string // Written to defaultConfigFile unless empty
+		args    []string
+		env     map[string]string
+		want    Config
+		wantErr bool
+	}{
+		{
+			name:    "missing file is not an error",
+			content: "",
+			args:    nil,
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: false,
+		},
+		{
+			name:    "file sets dir",
+			content: "dir: apps\nconcurrency: 8\ndryRun: true\n",
+			args:    nil,
+			env:     nil,
+			want: Config{
+				Dir: "apps", DryRun: true, CheckOnly: false, OptOutLabel: defaultOptOutLabel,
+				ConfigFile: defaultConfigFile, Concurrency: 8,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "dir flag wins over the file",
+			content: "dir: apps\ncheckOnly: true\n",
+			args:    []string{"--dir", "flag/dir"},
+			env:     nil,
+			want: Config{
+				Dir: "flag/dir", DryRun: false, CheckOnly: true, OptOutLabel: defaultOptOutLabel,
+				ConfigFile: defaultConfigFile,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "env wins over the file",
+			content: "dir: apps\n",
+			args:    nil,
+			env:     map[string]string{argoAppsDirEnvVar: "env/dir"},
+			want: Config{
+				Dir: "env/dir", DryRun: false, CheckOnly: false, OptOutLabel: defaultOptOutLabel,
+				ConfigFile: defaultConfigFile,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "malformed file is an error",
+			content: "dir: [unterminated\n",
+			args:    nil,
+			env:     nil,
+			want:    defaultConfig(),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+
+			if tt.content != "" {
+				if err := os.WriteFile(defaultConfigFile, []byte(tt.content), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := ParseConfig(tt.args, func(key string) string { return tt.env[key] })
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseConfig() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseConfigExplicitConfigFileSkipsDefault(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	if err := os.WriteFile(defaultConfigFile, []byte("dir: default\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	path := writeConfigFile(t, "maxPerHost: 2\n")
+
+	cfg, err := ParseConfig([]string{"--config", path}, func(string) string { return "" })
+	if err != nil {
+		t.Fatalf("ParseConfig() error = %v", err)
+	}
+
+	if cfg.Dir != defaultArgoAppsDir || cfg.MaxPerHost != 2 {
+		t.Errorf("Dir = %q, MaxPerHost = %d, want only %s applied", cfg.Dir, cfg.MaxPerHost, path)
+	}
+}
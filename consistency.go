@@ -0,0 +1,126 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/BooleanCat/go-functional/v2/it"
+	"gopkg.in/yaml.v3"
+)
+
+// Pin is the version a single manifest pins a chart to.
+type Pin struct {
+	File    string
+	Version string
+}
+
+// Divergence lists the manifests of a repo that do not all agree on a version.
+type Divergence struct {
+	Repo string
+	Pins []Pin // Every pin of Repo, in discovery order
+}
+
+// findDivergentPins reads the current version of every chart and returns the
+// repos pinned to more than one version, sorted by repo. Files that cannot be
+// read are left out; the update run reports them.
+func findDivergentPins(read YAMLReader, cfg Config, charts []ChartInfo) []Divergence {
+	pins := map[string][]Pin{}
+
+	for _, c := range charts {
+		docs, err := read(chartPath(cfg, c))
+		if err != nil {
+			continue
+		}
+
+		if version, ok := findChartVersion(docs, c); ok {
+			pins[c.Repo] = append(pins[c.Repo], Pin{File: c.File, Version: version})
+		}
+	}
+
+	divergent := it.Filter(maps.Keys(pins), func(repo string) bool {
+		return slices.ContainsFunc(pins[repo], func(p Pin) bool { return p.Version != pins[repo][0].Version })
+	})
+
+	return slices.SortedFunc(it.Map(divergent, func(repo string) Divergence {
+		return Divergence{Repo: repo, Pins: pins[repo]}
+	}), func(a, b Divergence) int { return cmp.Compare(a.Repo, b.Repo) })
+}
+
+// warnDivergentPins logs one warning per repo whose manifests disagree.
+func warnDivergentPins(log *Logger, divergences []Divergence) {
+	ForEach(slices.Values(divergences), func(d Divergence) {
+		pins := slices.Collect(it.Map(slices.Values(d.Pins), func(p Pin) string { return p.File + " " + p.Version }))
+		log.Warn("%s is pinned to different versions: %s", d.Repo, strings.Join(pins, ", "))
+	})
+}
+
+// ChartNameMismatch is a manifest whose artifacthub comment names a different
+// chart than its spec.source.chart, which usually means a copy-paste error.
+type ChartNameMismatch struct {
+	File  string
+	Repo  string // As written in the artifacthub comment
+	Chart string // As written in spec.source.chart
+}
+
+// findChartNameMismatches returns the Applications whose spec.source.chart is
+// set and differs from the chart portion of their artifacthub comment, in
+// discovery order. Values files have no spec.source.chart and are skipped, as
+// are files that cannot be read.
+func findChartNameMismatches(read YAMLReader, cfg Config, charts []ChartInfo) []ChartNameMismatch {
+	var mismatches []ChartNameMismatch
+
+	for _, c := range charts {
+		// Release repositories need not be named after the chart they ship.
+		if c.ValuesKey != nil || c.Source == sourceGitHub {
+			continue
+		}
+
+		docs, err := read(chartPath(cfg, c))
+		if err != nil {
+			continue
+		}
+
+		app, found := it.Find(slices.Values(docs), func(n *yaml.Node) bool { return isSupportedKind(n) })
+		if !found {
+			continue
+		}
+
+		if chart := lookup(docRoot(app), sourcePath(app, "chart")...); chart != "" && chart != chartName(c.Repo) {
+			mismatches = append(mismatches, ChartNameMismatch{File: c.File, Repo: c.Repo, Chart: chart})
+		}
+	}
+
+	return mismatches
+}
+
+// checkChartNames logs every mismatch and fails if there is at least one.
+func checkChartNames(log *Logger, mismatches []ChartNameMismatch) error {
+	ForEach(slices.Values(mismatches), func(m ChartNameMismatch) {
+		log.Error("%s: artifacthub comment names %s but spec.source.chart is %q", m.File, m.Repo, m.Chart)
+	})
+
+	if len(mismatches) > 0 {
+		return fmt.Errorf("spec.source.chart disagrees with the artifacthub comment in %s", plural(len(mismatches), "manifest"))
+	}
+
+	return nil
+}
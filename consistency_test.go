@@ -0,0 +1,136 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestFindDivergentPins(t *testing.T) {
+	dir := t.TempDir()
+	createTestFiles(t, dir, map[string]string{
+		"prod-redis.yaml":    "# artifacthub: bitnami/redis\nkind: Application\nspec:\n  source:\n    targetRevision: 18.1.0\n",
+		"staging-redis.yaml": "# artifacthub: bitnami/redis\nkind: Application\nspec:\n  source:\n    targetRevision: 18.2.0\n",
+		"prod-cilium.yaml":   "# artifacthub: cilium/cilium\nkind: Application\nspec:\n  source:\n    targetRevision: 1.16.0\n",
+		"dev-cilium.yaml":    "# artifacthub: cilium/cilium\nkind: Application\nspec:\n  source:\n    targetRevision: 1.16.0\n",
+	})
+
+	charts := []ChartInfo{
+		{File: "prod-redis.yaml", Repo: "bitnami/redis"},
+		{File: "prod-cilium.yaml", Repo: "cilium/cilium"},
+		{File: "staging-redis.yaml", Repo: "bitnami/redis"},
+		{File: "dev-cilium.yaml", Repo: "cilium/cilium"},
+		{File: "missing.yaml", Repo: "bitnami/redis"},
+	}
+
+	got := findDivergentPins(readYAMLDocuments, Config{Dir: dir}, charts)
+
+	want := []Divergence{{
+		Repo: "bitnami/redis",
+		Pins: []Pin{{File: "prod-redis.yaml", Version: "18.1.0"}, {File: "staging-redis.yaml", Version: "18.2.0"}},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findDivergentPins() = %+v, want %+v", got, want)
+	}
+}
+
+func TestFindDivergentPinsConsistent(t *testing.T) {
+	dir := t.TempDir()
+	createTestFiles(t, dir, map[string]string{
+		"a.yaml": "# artifacthub: org/chart\nkind: Application\nspec:\n  source:\n    targetRevision: 1.0.0\n",
+		"b.yaml": "# artifacthub: org/chart\nkind: Application\nspec:\n  source:\n    targetRevision: 1.0.0\n",
+	})
+
+	charts := []ChartInfo{{File: "a.yaml", Repo: "org/chart"}, {File: "b.yaml", Repo: "org/chart"}}
+
+	if got := findDivergentPins(readYAMLDocuments, Config{Dir: dir}, charts); len(got) != 0 {
+		t.Errorf("findDivergentPins() = %+v, want none", got)
+	}
+}
+
+func TestWarnDivergentPins(t *testing.T) {
+	var buf bytes.Buffer
+
+	warnDivergentPins(NewLogger(&buf, LogNormal), []Divergence{{
+		Repo: "bitnami/redis",
+		Pins: []Pin{{File: "prod.yaml", Version: "18.1.0"}, {File: "staging.yaml", Version: "18.2.0"}},
+	}})
+
+	want := "▶ warning: bitnami/redis is pinned to different versions: prod.yaml 18.1.0, staging.yaml 18.2.0\n"
+	if got := buf.String(); got != want {
+		t.Errorf("warnDivergentPins() = %q, want %q", got, want)
+	}
+}
+
+func TestFindChartNameMismatches(t *testing.T) {
+	dir := t.TempDir()
+	createTestFiles(t, dir, map[string]string{
+		"match.yaml":     "# artifacthub: bitnami/redis\nkind: Application\nspec:\n  source:\n    chart: redis\n    targetRevision: 18.1.0\n",
+		"mismatch.yaml":  "# artifacthub: bitnami/redis\nkind: Application\nspec:\n  source:\n    chart: postgresql\n    targetRevision: 18.1.0\n",
+		"no-chart.yaml":  "# artifacthub: cilium/cilium\nkind: Application\nspec:\n  source:\n    targetRevision: 1.16.0\n",
+		"multi-doc.yaml": "kind: ConfigMap\n---\n# artifacthub: cilium/cilium\nkind: Application\nspec:\n  source:\n    chart: tetragon\n",
+	})
+
+	charts := []ChartInfo{
+		{File: "match.yaml", Repo: "bitnami/redis"},
+		{File: "mismatch.yaml", Repo: "bitnami/redis"},
+		{File: "no-chart.yaml", Repo: "cilium/cilium"},
+		{File: "multi-doc.yaml", Repo: "cilium/cilium"},
+		{File: "values.yaml", Repo: "org/values", ValuesKey: []string{"image", "tag"}},
+		{File: "missing.yaml", Repo: "org/missing"},
+	}
+
+	got := findChartNameMismatches(readYAMLDocuments, Config{Dir: dir}, charts)
+
+	want := []ChartNameMismatch{
+		{File: "mismatch.yaml", Repo: "bitnami/redis", Chart: "postgresql"},
+		{File: "multi-doc.yaml", Repo: "cilium/cilium", Chart: "tetragon"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findChartNameMismatches() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCheckChartNames(t *testing.T) {
+	t.Run("matching", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		if err := checkChartNames(NewLogger(&buf, LogNormal), nil); err != nil {
+			t.Errorf("checkChartNames() error = %v, want nil", err)
+		}
+
+		if buf.Len() != 0 {
+			t.Errorf("checkChartNames() logged %q, want nothing", buf.String())
+		}
+	})
+
+	t.Run("mismatching", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		err := checkChartNames(NewLogger(&buf, LogNormal), []ChartNameMismatch{{File: "a.yaml", Repo: "bitnami/redis", Chart: "postgresql"}})
+		if err == nil || err.Error() != "spec.source.chart disagrees with the artifacthub comment in 1 manifest" {
+			t.Errorf("checkChartNames() error = %v", err)
+		}
+
+		want := "▶ error: a.yaml: artifacthub comment names bitnami/redis but spec.source.chart is \"postgresql\"\n"
+		if got := buf.String(); got != want {
+			t.Errorf("checkChartNames() logged %q, want %q", got, want)
+		}
+	})
+}
@@ -0,0 +1,152 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// constraintOps lists the operators a constraint term may start with. Longer
+// operators come first so that ">=" is not read as ">".
+func constraintOps() []string {
+	return []string{">=", "<=", ">", "<", "=", "^", "~"}
+}
+
+// constraintTerm is one comparison of a version constraint, such as ">=1.2.0".
+// Caret and tilde ranges are expanded into a pair of terms when parsed.
+type constraintTerm struct {
+	op      string
+	version string
+}
+
+// isConstraintTerm reports whether field starts with a constraint operator.
+func isConstraintTerm(field string) bool {
+	return slices.ContainsFunc(constraintOps(), func(op string) bool { return strings.HasPrefix(field, op) })
+}
+
+// parseConstraint parses a whitespace-separated list of terms, all of which a
+// version must satisfy, as in ">=1.2.0 <2.0.0". Supported operators are >=,
+// <=, >, <, =, ^ (same leftmost non-zero segment) and ~ (same minor line, or
+// same major when only the major is given).
+func parseConstraint(expr string) ([]constraintTerm, error) {
+	fields := strings.Fields(expr)
+	if len(fields) == 0 {
+		return nil, errors.New("empty constraint")
+	}
+
+	ops := constraintOps()
+
+	var terms []constraintTerm
+
+	for _, field := range fields {
+		i := slices.IndexFunc(ops, func(op string) bool { return strings.HasPrefix(field, op) })
+		if i < 0 {
+			return nil, fmt.Errorf("constraint term %q: want one of %s followed by a version",
+				field, strings.Join(ops, " "))
+		}
+
+		op, version := ops[i], strings.TrimPrefix(field, ops[i])
+		if !isParseableVersion(version) {
+			return nil, fmt.Errorf("constraint term %q: invalid version %q", field, version)
+		}
+
+		switch op {
+		case "^":
+			terms = append(terms, constraintTerm{op: ">=", version: version}, constraintTerm{op: "<", version: caretBound(version)})
+		case "~":
+			terms = append(terms, constraintTerm{op: ">=", version: version}, constraintTerm{op: "<", version: tildeBound(version)})
+		default:
+			terms = append(terms, constraintTerm{op: op, version: version})
+		}
+	}
+
+	return terms, nil
+}
+
+// caretBound returns the exclusive upper bound of ^version: the leftmost
+// non-zero segment bumped, so ^1.2.3 is below 2.0.0 and ^0.2.3 below 0.3.0.
+func caretBound(version string) string {
+	core, _, _ := strings.Cut(version, "-")
+	parts := strings.Split(core, ".")
+
+	i := slices.IndexFunc(parts, func(p string) bool { return toInt(p) != 0 })
+	if i < 0 {
+		i = len(parts) - 1
+	}
+
+	return bumpSegment(parts, i)
+}
+
+// tildeBound returns the exclusive upper bound of ~version: the minor bumped
+// when given, so ~1.2.3 is below 1.3.0, otherwise the major, so ~1 is below 2.
+func tildeBound(version string) string {
+	core, _, _ := strings.Cut(version, "-")
+	parts := strings.Split(core, ".")
+
+	return bumpSegment(parts, min(1, len(parts)-1))
+}
+
+// bumpSegment increments parts[i] and zeroes the segments after it.
+func bumpSegment(parts []string, i int) string {
+	bumped := make([]string, len(parts))
+	for j := range parts {
+		switch {
+		case j < i:
+			bumped[j] = parts[j]
+		case j == i:
+			bumped[j] = strconv.Itoa(toInt(parts[j]) + 1)
+		default:
+			bumped[j] = "0"
+		}
+	}
+
+	return strings.Join(bumped, ".")
+}
+
+// satisfies reports whether version meets every term of constraint. An empty
+// constraint admits every version; one that does not parse admits none.
+func satisfies(version, constraint string) bool {
+	if constraint == "" {
+		return true
+	}
+
+	terms, err := parseConstraint(constraint)
+	if err != nil {
+		return false
+	}
+
+	return !slices.ContainsFunc(terms, func(t constraintTerm) bool {
+		cmp := compareVersions(version, t.version)
+
+		switch t.op {
+		case ">=":
+			return cmp < 0
+		case "<=":
+			return cmp > 0
+		case ">":
+			return cmp <= 0
+		case "<":
+			return cmp >= 0
+		default:
+			return cmp != 0
+		}
+	})
+}
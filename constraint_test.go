@@ -0,0 +1,108 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseConstraint(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		want    []constraintTerm
+		wantErr string
+	}{
+		{
+			name:    "range",
+			expr:    ">=1.2.0 <2.0.0",
+			want:    []constraintTerm{{op: ">=", version: "1.2.0"}, {op: "<", version: "2.0.0"}},
+			wantErr: "",
+		},
+		{
+			name:    "caret",
+			expr:    "^1.2.3",
+			want:    []constraintTerm{{op: ">=", version: "1.2.3"}, {op: "<", version: "2.0.0"}},
+			wantErr: "",
+		},
+		{
+			name:    "caret below 1.0.0 bumps the minor",
+			expr:    "^0.2.3",
+			want:    []constraintTerm{{op: ">=", version: "0.2.3"}, {op: "<", version: "0.3.0"}},
+			wantErr: "",
+		},
+		{
+			name:    "tilde",
+			expr:    "~1.2.3",
+			want:    []constraintTerm{{op: ">=", version: "1.2.3"}, {op: "<", version: "1.3.0"}},
+			wantErr: "",
+		},
+		{
+			name:    "tilde with major only",
+			expr:    "~1",
+			want:    []constraintTerm{{op: ">=", version: "1"}, {op: "<", version: "2"}},
+			wantErr: "",
+		},
+		{name: "empty", expr: " ", want: nil, wantErr: "empty constraint"},
+		{name: "missing operator", expr: "1.2.0", want: nil, wantErr: `constraint term "1.2.0": want one of >= <= > < = ^ ~ followed by a version`},
+		{name: "invalid version", expr: ">=1.x", want: nil, wantErr: `constraint term ">=1.x": invalid version "1.x"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseConstraint(tt.expr)
+			assertError(t, tt.wantErr, err)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseConstraint() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSatisfies(t *testing.T) {
+	tests := []struct {
+		version    string
+		constraint string
+		want       bool
+	}{
+		{version: "1.5.0", constraint: "", want: true},
+		{version: "1.2.0", constraint: ">=1.2.0 <2.0.0", want: true},
+		{version: "1.1.9", constraint: ">=1.2.0 <2.0.0", want: false},
+		{version: "2.0.0", constraint: ">=1.2.0 <2.0.0", want: false},
+		{version: "1.9.10", constraint: ">=1.2.0 <2.0.0", want: true},
+		{version: "1.4.0", constraint: "<=1.4.0", want: true},
+		{version: "1.4.1", constraint: "<=1.4.0", want: false},
+		{version: "1.4.0", constraint: ">1.4.0", want: false},
+		{version: "1.4.0", constraint: "=1.4.0", want: true},
+		{version: "1.4.1", constraint: "=1.4.0", want: false},
+		{version: "1.9.0", constraint: "^1.2.3", want: true},
+		{version: "2.0.0", constraint: "^1.2.3", want: false},
+		{version: "0.2.9", constraint: "^0.2.3", want: true},
+		{version: "0.3.0", constraint: "^0.2.3", want: false},
+		{version: "1.2.9", constraint: "~1.2.3", want: true},
+		{version: "1.3.0", constraint: "~1.2.3", want: false},
+		{version: "1.2.0", constraint: ">=nonsense", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := satisfies(tt.version, tt.constraint); got != tt.want {
+			t.Errorf("satisfies(%q, %q) = %v, want %v", tt.version, tt.constraint, got, tt.want)
+		}
+	}
+}
@@ -17,16 +17,240 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
 
 	"gopkg.in/yaml.v3"
 )
 
-func showDiffInternal(ctx context.Context, path string, docs []*yaml.Node) (err error) {
+// Diff modes accepted by --diff-mode.
+const (
+	DiffModeGit     = "git"     // git diff --no-index
+	DiffModeBuiltin = "builtin" // A unified diff computed in process, for hosts without git
+)
+
+// diffModes lists the modes --diff-mode accepts.
+func diffModes() []string {
+	return []string{DiffModeGit, DiffModeBuiltin}
+}
+
+// MakeDiffWriter creates the default dry-run YAMLWriter, which prints a diff of
+// each file against the working tree to out. With preserveFormat the diff shows
+// the in-place edit --preserve-format would make.
+func MakeDiffWriter(out io.Writer, preserveFormat bool) YAMLWriter {
+	return func(ctx context.Context, path string, docs []*yaml.Node) error {
+		return showDiff(ctx, out, path, docs, preservingEncoder(path, preserveFormat, diffEncoderFor(path)))
+	}
+}
+
+// MakeBuiltinDiffWriter creates a dry-run YAMLWriter like MakeDiffWriter that
+// computes the diff itself instead of running git, for --diff-mode builtin.
+func MakeBuiltinDiffWriter(out io.Writer, preserveFormat bool) YAMLWriter {
+	return func(_ context.Context, path string, docs []*yaml.Node) error {
+		before, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", path, err)
+		}
+
+		var after bytes.Buffer
+		if err := preservingEncoder(path, preserveFormat, diffEncoderFor(path))(&after, docs); err != nil {
+			return err
+		}
+
+		name := strings.TrimPrefix(filepath.ToSlash(path), "/")
+		if _, err := io.WriteString(out, unifiedDiff("a/"+name, "b/"+name, before, after.Bytes())); err != nil {
+			return fmt.Errorf("write diff: %w", err)
+		}
+
+		return nil
+	}
+}
+
+// discardYAML is the YAMLWriter for --compact: the version delta is all that
+// is reported, so nothing is written or diffed.
+func discardYAML(context.Context, string, []*yaml.Node) error {
+	return nil
+}
+
+// MakeBaseRefDiffWriter creates a dry-run YAMLWriter that diffs the updated
+// documents against each file as committed at the git revision ref, rather
+// than against the working tree, so the output reflects the whole PR delta.
+func MakeBaseRefDiffWriter(ref string, out io.Writer, preserveFormat bool) YAMLWriter {
+	return func(ctx context.Context, path string, docs []*yaml.Node) (err error) {
+		before, err := os.CreateTemp("", "update-version-base-*.yaml")
+		if err != nil {
+			return fmt.Errorf("create temporary file: %w", err)
+		}
+
+		defer func() {
+			if removeErr := os.Remove(before.Name()); removeErr != nil && err == nil {
+				err = removeErr
+			}
+		}()
+
+		// "./" makes git resolve the path relative to the -C directory.
+		//nolint:gosec // path is validated to be within base directory in config.go
+		cmd := exec.CommandContext(ctx, "git", "-C", filepath.Dir(path), "show", ref+":./"+filepath.Base(path))
+		cmd.Stdout = before
+
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+
+		if err = cmd.Run(); err != nil {
+			closeFile(before, &err)
+			return fmt.Errorf("git show %s:%s: %w: %s", ref, path, err, strings.TrimSpace(stderr.String()))
+		}
+
+		if err = before.Close(); err != nil {
+			return fmt.Errorf("close temporary file: %w", err)
+		}
+
+		return showDiff(ctx, out, before.Name(), docs, preservingEncoder(path, preserveFormat, diffEncoderFor(path)))
+	}
+}
+
+// MakePatchWriter creates a dry-run YAMLWriter that appends a git-style diff
+// for each file to out, so the combined output applies with git apply from
+// the current working directory. preserveFormat is as for MakeDiffWriter.
+func MakePatchWriter(out io.Writer, preserveFormat bool) YAMLWriter {
+	var mu sync.Mutex
+
+	return func(ctx context.Context, path string, docs []*yaml.Node) error {
+		var buf bytes.Buffer
+
+		if err := writePatch(ctx, &buf, path, docs, preserveFormat); err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if _, err := out.Write(buf.Bytes()); err != nil {
+			return fmt.Errorf("write patch: %w", err)
+		}
+
+		return nil
+	}
+}
+
+// writePatch writes a diff between path and docs as they would be written.
+// Both sides are staged under a/ and b/ in a scratch directory so that the
+// headers name the file relative to the working directory, not temp files.
+func writePatch(ctx context.Context, out io.Writer, path string, docs []*yaml.Node, preserveFormat bool) (err error) {
+	if resolved, resolveErr := filepath.EvalSymlinks(path); resolveErr == nil {
+		path = resolved
+	}
+
+	rel, err := workingDirPath(path)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("stat %s: %w", path, err)
+	}
+
+	original, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+
+	var updated bytes.Buffer
+	if err = preservingEncoder(path, preserveFormat, encoderFor(path))(&updated, docs); err != nil {
+		return err
+	}
+
+	scratch, err := os.MkdirTemp("", "update-version-patch-*")
+	if err != nil {
+		return fmt.Errorf("create temporary directory: %w", err)
+	}
+
+	defer func() {
+		if removeErr := os.RemoveAll(scratch); removeErr != nil && err == nil {
+			err = removeErr
+		}
+	}()
+
+	for side, content := range map[string][]byte{"a": original, "b": updated.Bytes()} {
+		staged := filepath.Join(scratch, side, filepath.FromSlash(rel))
+		if err = os.MkdirAll(filepath.Dir(staged), 0o750); err != nil {
+			return fmt.Errorf("create temporary directory: %w", err)
+		}
+
+		if err = os.WriteFile(staged, content, info.Mode().Perm()); err != nil {
+			return fmt.Errorf("write temporary file: %w", err)
+		}
+	}
+
+	//nolint:gosec // path is validated to be within base directory in config.go
+	cmd := exec.CommandContext(ctx, "git", "-C", scratch, "diff", "--no-index", "--no-prefix",
+		"--no-color", "--no-ext-diff", "--", "a/"+rel, "b/"+rel)
+
+	return runGitDiff(cmd, out)
+}
+
+// workingDirPath returns path relative to the working directory, slash-separated
+// as patch headers expect.
+func workingDirPath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("resolve %s: %w", path, err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("get working directory: %w", err)
+	}
+
+	if resolved, resolveErr := filepath.EvalSymlinks(wd); resolveErr == nil {
+		wd = resolved
+	}
+
+	rel, err := filepath.Rel(wd, abs)
+	if err != nil {
+		return "", fmt.Errorf("relativize %s: %w", path, err)
+	}
+
+	return filepath.ToSlash(rel), nil
+}
+
+// diffEncoderFor returns how the dry-run diff renders docs for the file at
+// path: as JSON for a JSON manifest, otherwise as a plain YAML stream.
+func diffEncoderFor(path string) func(io.Writer, []*yaml.Node) error {
+	if isJSONFile(path) {
+		return encodeJSONDocuments
+	}
+
+	return func(w io.Writer, docs []*yaml.Node) error {
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(yamlIndent)
+
+		if err := encodeStream(enc, docs); err != nil {
+			return err
+		}
+
+		if err := enc.Close(); err != nil {
+			return fmt.Errorf("close encoder: %w", err)
+		}
+
+		return nil
+	}
+}
+
+// showDiff prints a git diff between the file at before and docs as encode
+// would write them to out.
+func showDiff(
+	ctx context.Context, out io.Writer, before string, docs []*yaml.Node, encode func(io.Writer, []*yaml.Node) error,
+) (err error) {
 	tmp, err := os.CreateTemp("", "update-version-*.yaml")
 	if err != nil {
 		return fmt.Errorf("create temporary file: %w", err)
@@ -38,33 +262,39 @@ func showDiffInternal(ctx context.Context, path string, docs []*yaml.Node) (err
 		}
 	}()
 
-	enc := yaml.NewEncoder(tmp)
-	enc.SetIndent(yamlIndent)
-
-	if err = encodeStream(enc, docs); err != nil {
+	if err = encode(tmp, docs); err != nil {
+		closeFile(tmp, &err)
 		return err
 	}
 
-	if err = enc.Close(); err != nil {
-		return fmt.Errorf("close encoder: %w", err)
-	}
-
 	if err = tmp.Close(); err != nil {
 		return fmt.Errorf("close temporary file: %w", err)
 	}
 
 	//nolint:gosec // path is validated to be within base directory in config.go
-	cmd := exec.CommandContext(ctx, "git", "diff", "--no-index", "--", path, tmp.Name())
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd := exec.CommandContext(ctx, "git", "diff", "--no-index", "--", before, tmp.Name())
+
+	return runGitDiff(cmd, out)
+}
+
+// runGitDiff runs a git diff command with its output going to out. What git
+// prints on stderr is returned in the error rather than written to the
+// process's own stderr.
+func runGitDiff(cmd *exec.Cmd, out io.Writer) error {
+	var stderr bytes.Buffer
+
+	cmd.Stdout = out
+	cmd.Stderr = &stderr
 
-	if err = cmd.Run(); err != nil {
+	if err := cmd.Run(); err != nil {
 		var ee *exec.ExitError
-		if errors.As(err, &ee) {
-			return nil // git diff returns 1 when files differ
+		// git diff returns 1 when files differ, but also when it cannot read
+		// one of them, which it reports on stderr.
+		if errors.As(err, &ee) && ee.ExitCode() == 1 && stderr.Len() == 0 {
+			return nil
 		}
 
-		return fmt.Errorf("run git diff: %w", err)
+		return fmt.Errorf("run git diff: %w: %s", err, strings.TrimSpace(stderr.String()))
 	}
 
 	return nil
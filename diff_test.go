@@ -0,0 +1,231 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const diffTestManifest = `# artifacthub: org/chart
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+spec:
+  source:
+    targetRevision: %s
+`
+
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	cmd := exec.CommandContext(context.Background(), "git",
+		append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+func writeManifest(t *testing.T, path, version string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(strings.Replace(diffTestManifest, "%s", version, 1)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiffWriter(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	path := filepath.Join(t.TempDir(), testAppFile)
+	writeManifest(t, path, "1.0.0")
+
+	docs, err := readYAMLDocuments(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updateDocuments(docs, "1.1.0")
+
+	var out bytes.Buffer
+	if err := MakeDiffWriter(&out, false)(context.Background(), path, docs); err != nil {
+		t.Fatalf("writer error = %v", err)
+	}
+
+	if got := out.String(); !strings.Contains(got, "-    targetRevision: 1.0.0\n+    targetRevision: 1.1.0\n") {
+		t.Errorf("diff = %q, want the targetRevision change", got)
+	}
+
+	out.Reset()
+
+	if err := MakeDiffWriter(&out, false)(context.Background(), filepath.Join(t.TempDir(), "missing.yaml"), docs); err == nil {
+		t.Errorf("writer error = nil for a missing file, output %q", out.String())
+	}
+}
+
+func TestBaseRefDiffWriter(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	repo := t.TempDir()
+	git(t, repo, "init", "-q")
+
+	apps := filepath.Join(repo, "argoapps")
+	if err := os.Mkdir(apps, 0o750); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(apps, testAppFile)
+
+	writeManifest(t, path, "1.0.0")
+	git(t, repo, "add", ".")
+	git(t, repo, "commit", "-q", "-m", "base")
+
+	// Committed on the PR branch after the base.
+	writeManifest(t, path, "1.1.0")
+	git(t, repo, "commit", "-q", "-am", "bump")
+	git(t, repo, "tag", "pr")
+
+	// Uncommitted working-tree change.
+	writeManifest(t, path, "1.2.0")
+
+	docs, err := readYAMLDocuments(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updateDocuments(docs, "1.3.0")
+
+	tests := []struct {
+		name    string
+		ref     string
+		removed string
+	}{
+		{name: "against base commit", ref: "HEAD~1", removed: "-    targetRevision: 1.0.0"},
+		{name: "against tagged head", ref: "pr", removed: "-    targetRevision: 1.1.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+
+			if err := MakeBaseRefDiffWriter(tt.ref, &out, false)(context.Background(), path, docs); err != nil {
+				t.Fatalf("writer error = %v", err)
+			}
+
+			got := out.String()
+			if !strings.Contains(got, tt.removed) || !strings.Contains(got, "+    targetRevision: 1.3.0") {
+				t.Errorf("diff against %s =\n%s\nwant %q and the new version", tt.ref, got, tt.removed)
+			}
+
+			if strings.Contains(got, "1.2.0") {
+				t.Errorf("diff against %s mentions the working-tree version:\n%s", tt.ref, got)
+			}
+		})
+	}
+
+	t.Run("unknown ref", func(t *testing.T) {
+		var out bytes.Buffer
+
+		if err := MakeBaseRefDiffWriter("no-such-ref", &out, false)(context.Background(), path, docs); err == nil {
+			t.Error("writer error = nil, want error for unknown ref")
+		}
+	})
+}
+
+func TestPatchWriterAppliesCleanly(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	if err := os.MkdirAll(filepath.Join("argoapps", "team"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		filepath.Join("argoapps", "a.yaml"):         "1.3.0",
+		filepath.Join("argoapps", "team", "b.yaml"): "2.0.0",
+	}
+
+	for path := range files {
+		writeManifest(t, path, "1.0.0")
+	}
+
+	var (
+		patch bytes.Buffer
+		want  = map[string]string{}
+	)
+
+	writer := MakePatchWriter(&patch, false)
+
+	for path, version := range files {
+		docs, err := readYAMLDocuments(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		updateDocuments(docs, version)
+
+		if err := writer(context.Background(), path, docs); err != nil {
+			t.Fatalf("writer(%s) error = %v", path, err)
+		}
+
+		var encoded bytes.Buffer
+		if err := encodeYAMLDocuments(&encoded, docs); err != nil {
+			t.Fatal(err)
+		}
+
+		want[path] = encoded.String()
+	}
+
+	got := patch.String()
+	if n := strings.Count(got, "diff --git "); n != len(files) {
+		t.Fatalf("patch has %d file headers, want %d:\n%s", n, len(files), got)
+	}
+
+	if !strings.Contains(got, "--- a/argoapps/team/b.yaml\n+++ b/argoapps/team/b.yaml\n") {
+		t.Errorf("patch headers do not name the working-directory path:\n%s", got)
+	}
+
+	if err := os.WriteFile("changes.patch", patch.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	git(t, dir, "apply", "--check", "changes.patch")
+	git(t, dir, "apply", "changes.patch")
+
+	for path, content := range want {
+		applied, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(applied) != content {
+			t.Errorf("%s after git apply =\n%s\nwant\n%s", path, applied, content)
+		}
+	}
+}
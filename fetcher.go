@@ -0,0 +1,334 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultFetchAttempts caps how often a fetch is tried when it fails transiently,
+// so endpoints that never return JSON fail after a bounded number of requests.
+const defaultFetchAttempts = 3
+
+// defaultRetryAfter is how long to wait after a 429 response whose
+// Retry-After header is missing or cannot be parsed.
+const defaultRetryAfter = time.Second
+
+// maxRetryAfter caps how long a rate-limited fetch waits before retrying, so
+// a Retry-After of hours does not stall the run.
+const maxRetryAfter = time.Minute
+
+// HostLimiter bounds the number of in-flight requests to each host.
+type HostLimiter struct {
+	limit int
+	mu    sync.Mutex
+	slots map[string]chan struct{}
+}
+
+// NewHostLimiter creates a HostLimiter allowing limit concurrent requests per host.
+func NewHostLimiter(limit int) *HostLimiter {
+	return &HostLimiter{limit: limit, mu: sync.Mutex{}, slots: make(map[string]chan struct{})}
+}
+
+func (l *HostLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	sem := l.semaphore(host)
+
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("wait for %s request slot: %w", host, ctx.Err())
+	}
+}
+
+func (l *HostLimiter) semaphore(host string) chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sem, ok := l.slots[host]
+	if !ok {
+		sem = make(chan struct{}, l.limit)
+		l.slots[host] = sem
+	}
+
+	return sem
+}
+
+// FetcherFactory builds the VersionFetcher a run queries, such as
+// newVersionFetcher.
+type FetcherFactory func(cfg Config, debug LogFunc) VersionFetcher
+
+// QueryHost returns the host a VersionQuery is sent to.
+type QueryHost func(q VersionQuery) string
+
+// fixedHost is the QueryHost of a source served from the single API at rawURL.
+func fixedHost(rawURL string) QueryHost {
+	host := hostOf(rawURL)
+	return func(VersionQuery) string { return host }
+}
+
+// MakeHostLimitedFetcher wraps a VersionFetcher so that calls against each
+// host, as host resolves it per query, never exceed the limiter's per-host
+// concurrency, however many run at once.
+func MakeHostLimitedFetcher(inner VersionFetcher, limiter *HostLimiter, host QueryHost) VersionFetcher {
+	return func(ctx context.Context, q VersionQuery) (VersionInfo, error) {
+		release, err := limiter.acquire(ctx, host(q))
+		if err != nil {
+			return VersionInfo{}, err
+		}
+
+		defer release()
+
+		return inner(ctx, q)
+	}
+}
+
+// errRequestQuota marks a fetch refused because --max-requests was reached.
+var errRequestQuota = errors.New("request quota reached")
+
+// RequestBudget caps the number of requests made over a whole run, however
+// many fetches run at once.
+type RequestBudget struct {
+	limit int64
+	used  atomic.Int64
+}
+
+// NewRequestBudget creates a RequestBudget allowing limit requests.
+func NewRequestBudget(limit int) *RequestBudget {
+	return &RequestBudget{limit: int64(limit), used: atomic.Int64{}}
+}
+
+// take reserves one request, reporting false once the budget is spent.
+func (b *RequestBudget) take() bool {
+	return b.used.Add(1) <= b.limit
+}
+
+// MakeBudgetedFetcher wraps a VersionFetcher so that each call spends one
+// request from budget, and calls made after it is spent fail with
+// errRequestQuota without reaching inner.
+func MakeBudgetedFetcher(inner VersionFetcher, budget *RequestBudget) VersionFetcher {
+	return func(ctx context.Context, q VersionQuery) (VersionInfo, error) {
+		if !budget.take() {
+			return VersionInfo{}, fmt.Errorf("%w (--max-requests %d)", errRequestQuota, budget.limit)
+		}
+
+		return inner(ctx, q)
+	}
+}
+
+// MakeRetryingFetcher wraps a VersionFetcher so that transient failures, such
+// as a truncated response body, are retried up to attempts times in total.
+// After a 429 response it first waits as long as the server asked, or until
+// ctx is done.
+func MakeRetryingFetcher(inner VersionFetcher, attempts int) VersionFetcher {
+	return func(ctx context.Context, q VersionQuery) (VersionInfo, error) {
+		var (
+			info VersionInfo
+			err  error
+		)
+
+		for attempt := 1; attempt <= attempts; attempt++ {
+			info, err = inner(ctx, q)
+			if err == nil || !isRetryable(err) || ctx.Err() != nil {
+				return info, err
+			}
+
+			var rateLimited *RateLimitError
+			if errors.As(err, &rateLimited) && attempt < attempts {
+				if waitErr := sleepContext(ctx, retryWait(rateLimited.RetryAfter, q)); waitErr != nil {
+					return info, fmt.Errorf("%w; %w", err, waitErr)
+				}
+			}
+		}
+
+		return info, fmt.Errorf("giving up after %d attempts: %w", attempts, err)
+	}
+}
+
+func isRetryable(err error) bool {
+	var rateLimited *RateLimitError
+
+	return errors.Is(err, errDecodeResponse) || errors.Is(err, errDecodeReleases) || errors.Is(err, errDecodeIndex) ||
+		errors.Is(err, errDecodeTags) || errors.As(err, &rateLimited)
+}
+
+// retryWait returns how long to wait before retrying a query the server asked
+// to retry after retryAfter: at most maxRetryAfter, and at most the query's own
+// timeout when it has one.
+func retryWait(retryAfter time.Duration, q VersionQuery) time.Duration {
+	wait := min(retryAfter, maxRetryAfter)
+	if q.Timeout > 0 {
+		wait = min(wait, q.Timeout)
+	}
+
+	return wait
+}
+
+// parseRetryAfter reads a Retry-After header, given either as a number of
+// seconds or as an HTTP date, into a wait from now. A missing or malformed
+// header yields defaultRetryAfter; a date in the past yields no wait.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0)
+	}
+
+	return defaultRetryAfter
+}
+
+// sleepContext waits for d, returning early with ctx's error once ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("wait to retry: %w", ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
+
+// fetchResult is a memoized VersionFetcher outcome. done is closed once info
+// and err are set.
+type fetchResult struct {
+	done chan struct{}
+	info VersionInfo
+	err  error
+}
+
+// MakeCachingFetcher wraps a VersionFetcher so that identical queries made
+// during a run, such as two Applications pinning the same chart, reach inner
+// only once; concurrent callers wait for the first. Failures are not kept, so
+// a later query for the same chart tries again.
+func MakeCachingFetcher(inner VersionFetcher) VersionFetcher {
+	var (
+		mu      sync.Mutex
+		results = map[string]*fetchResult{}
+	)
+
+	return func(ctx context.Context, q VersionQuery) (VersionInfo, error) {
+		key := cacheKey(q)
+
+		mu.Lock()
+		result, found := results[key]
+
+		if !found {
+			result = &fetchResult{done: make(chan struct{}), info: VersionInfo{}, err: nil}
+			results[key] = result
+		}
+		mu.Unlock()
+
+		if !found {
+			result.info, result.err = inner(ctx, q)
+
+			if result.err != nil {
+				mu.Lock()
+				delete(results, key)
+				mu.Unlock()
+			}
+
+			close(result.done)
+
+			return result.info, result.err
+		}
+
+		select {
+		case <-result.done:
+			return result.info, result.err
+		case <-ctx.Done():
+			return VersionInfo{}, fmt.Errorf("wait for %s: %w", q.Repo, ctx.Err())
+		}
+	}
+}
+
+// cacheKey identifies the queries MakeCachingFetcher treats as identical: those
+// that select the same version. The current version only narrows the selection
+// for partial pins and same-major prereleases, so other charts pinning the
+// same repository at different versions share one entry. The timeout bounds
+// the request, not what it resolves to, so it is left out.
+func cacheKey(q VersionQuery) string {
+	current := ""
+	if isPartialPin(q.Current) || q.PrereleaseSameMajor {
+		current = q.Current
+	}
+
+	return fmt.Sprintf("repo=%s source=%s current=%s limit=%d stability=%s signed=%t prerelease=%t constraint=%s ignore=%q",
+		q.Repo, q.Source, current, q.Limit, q.Stability, q.RequireSigned, q.AllowPrerelease, q.Constraint, q.Ignore)
+}
+
+// MakeSourceFetcher dispatches each query on its Source, sending GitHub
+// release charts to gitHub, Helm repository charts to helmRepo, OCI registry
+// charts to oci and every other chart to artifactHub.
+func MakeSourceFetcher(artifactHub, gitHub, helmRepo, oci VersionFetcher) VersionFetcher {
+	return func(ctx context.Context, q VersionQuery) (VersionInfo, error) {
+		switch q.Source {
+		case sourceGitHub:
+			return gitHub(ctx, q)
+		case sourceHelmRepo:
+			return helmRepo(ctx, q)
+		case sourceOCI:
+			return oci(ctx, q)
+		default:
+			return artifactHub(ctx, q)
+		}
+	}
+}
+
+// MakeRepoMappingFetcher wraps a VersionFetcher so that repositories which
+// moved on ArtifactHub are looked up under their new name; see mapRepo.
+func MakeRepoMappingFetcher(inner VersionFetcher, table map[string]string) VersionFetcher {
+	return func(ctx context.Context, q VersionQuery) (VersionInfo, error) {
+		if moved, ok := mapRepo(table, q.Repo); ok {
+			q.Repo = moved
+		}
+
+		return inner(ctx, q)
+	}
+}
+
+// mapRepo resolves repo through a rename table whose keys are either a full
+// "org/chart" path or just an org. An exact repo entry wins over an org entry.
+func mapRepo(table map[string]string, repo string) (string, bool) {
+	if moved, ok := table[repo]; ok {
+		return moved, true
+	}
+
+	org, chart, found := strings.Cut(repo, "/")
+	if !found {
+		return repo, false
+	}
+
+	if moved, ok := table[org]; ok {
+		return moved + "/" + chart, true
+	}
+
+	return repo, false
+}
@@ -0,0 +1,583 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testQuery returns a query for repo from source with the default dash
+// stability rule and no other options set.
+func testQuery(source, repo string) VersionQuery {
+	return VersionQuery{
+		Repo: repo, Current: "", Timeout: 0, Limit: 0, PrereleaseSameMajor: false, Stability: StabilityDash,
+		RequireSigned: false, Source: source, AllowPrerelease: false, Constraint: "", Ignore: nil,
+	}
+}
+
+// trackingFetcher records the peak number of concurrent calls.
+type trackingFetcher struct {
+	inFlight atomic.Int32
+	peak     atomic.Int32
+}
+
+func (f *trackingFetcher) fetch(_ context.Context, _ VersionQuery) (VersionInfo, error) {
+	n := f.inFlight.Add(1)
+	defer f.inFlight.Add(-1)
+
+	for {
+		p := f.peak.Load()
+		if n <= p || f.peak.CompareAndSwap(p, n) {
+			break
+		}
+	}
+
+	time.Sleep(5 * time.Millisecond)
+
+	return versionInfo("1.0.0"), nil
+}
+
+func TestHostLimitedFetcher(t *testing.T) {
+	const (
+		limit   = 2
+		callers = 10
+	)
+
+	tracker := &trackingFetcher{}
+	fetch := MakeHostLimitedFetcher(tracker.fetch, NewHostLimiter(limit), fixedHost("https://artifacthub.io/api/v1"))
+
+	var wg sync.WaitGroup
+
+	for range callers {
+		wg.Go(func() {
+			if _, err := fetch(context.Background(), VersionQuery{Repo: "org/chart", Current: ""}); err != nil {
+				t.Errorf("fetch() error = %v", err)
+			}
+		})
+	}
+
+	wg.Wait()
+
+	if peak := tracker.peak.Load(); peak > limit {
+		t.Errorf("peak concurrent requests = %d, want at most %d", peak, limit)
+	}
+}
+
+func TestHostLimitedFetcherPerHelmHost(t *testing.T) {
+	blocked, release := make(chan struct{}), make(chan struct{})
+
+	inner := func(_ context.Context, q VersionQuery) (VersionInfo, error) {
+		if helmRepoHost(q) == "charts.a.example" {
+			close(blocked)
+			<-release
+		}
+
+		return versionInfo("1.0.0"), nil
+	}
+
+	fetch := MakeHostLimitedFetcher(inner, NewHostLimiter(1), helmRepoHost)
+
+	done := make(chan error)
+
+	go func() {
+		_, err := fetch(context.Background(), VersionQuery{Repo: "https://charts.a.example/one", Current: ""})
+		done <- err
+	}()
+
+	<-blocked
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := fetch(ctx, VersionQuery{Repo: "https://charts.b.example/two", Current: ""}); err != nil {
+		t.Errorf("fetch() from a second host error = %v, want it not to wait for the first", err)
+	}
+
+	close(release)
+
+	if err := <-done; err != nil {
+		t.Errorf("fetch() from the first host error = %v", err)
+	}
+}
+
+func TestBudgetedFetcher(t *testing.T) {
+	const (
+		limit   = 5
+		callers = 20
+	)
+
+	var calls atomic.Int32
+
+	fetch := MakeBudgetedFetcher(func(context.Context, VersionQuery) (VersionInfo, error) {
+		calls.Add(1)
+		return versionInfo("1.0.0"), nil
+	}, NewRequestBudget(limit))
+
+	var (
+		wg      sync.WaitGroup
+		refused atomic.Int32
+	)
+
+	for range callers {
+		wg.Go(func() {
+			_, err := fetch(context.Background(), VersionQuery{Repo: "org/chart", Current: ""})
+
+			switch {
+			case errors.Is(err, errRequestQuota):
+				refused.Add(1)
+			case err != nil:
+				t.Errorf("fetch() error = %v", err)
+			}
+		})
+	}
+
+	wg.Wait()
+
+	if got := calls.Load(); got != limit {
+		t.Errorf("inner fetcher called %d times, want %d", got, limit)
+	}
+
+	if got := refused.Load(); got != callers-limit {
+		t.Errorf("refused %d fetches, want %d", got, callers-limit)
+	}
+}
+
+func TestBudgetedFetcherCountsRetries(t *testing.T) {
+	var calls atomic.Int32
+
+	flaky := func(context.Context, VersionQuery) (VersionInfo, error) {
+		calls.Add(1)
+		return VersionInfo{}, errDecodeResponse
+	}
+
+	fetch := MakeRetryingFetcher(MakeBudgetedFetcher(flaky, NewRequestBudget(2)), defaultFetchAttempts)
+
+	_, err := fetch(context.Background(), VersionQuery{Repo: "org/chart", Current: ""})
+	if !errors.Is(err, errRequestQuota) {
+		t.Errorf("fetch() error = %v, want the request quota once retries spent the budget", err)
+	}
+
+	if got := calls.Load(); got != 2 {
+		t.Errorf("inner fetcher called %d times, want 2", got)
+	}
+}
+
+func TestHostLimiterSeparatesHosts(t *testing.T) {
+	limiter := NewHostLimiter(1)
+
+	release, err := limiter.acquire(context.Background(), "a.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	otherRelease, err := limiter.acquire(ctx, "b.example.com")
+	if err != nil {
+		t.Fatalf("acquire() on a different host error = %v, want nil", err)
+	}
+
+	otherRelease()
+
+	if _, err := limiter.acquire(ctx, "a.example.com"); err == nil {
+		t.Error("acquire() on a saturated host error = nil, want context error")
+	}
+}
+
+func TestRetryingFetcherRecoversFromTruncatedJSON(t *testing.T) {
+	var calls atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if calls.Add(1) == 1 {
+			_, _ = w.Write([]byte(`{"available_versions": [{"version": "1.0`))
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"available_versions": [{"version": "1.0.0"}]}`))
+	}))
+	defer server.Close()
+
+	fetch := MakeRetryingFetcher(MakeArtifactHubFetcher(server.URL, server.Client(), ArtifactHubKey{}, discardLog), defaultFetchAttempts)
+
+	info, err := fetch(context.Background(), VersionQuery{Repo: "org/chart", Current: "", Timeout: 0, Limit: 0, PrereleaseSameMajor: false, Stability: StabilityDash, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil})
+	if err != nil {
+		t.Fatalf("fetch() error = %v", err)
+	}
+
+	if info.Version != "1.0.0" {
+		t.Errorf("fetch() = %q, want %q", info.Version, "1.0.0")
+	}
+
+	if got := calls.Load(); got != 2 {
+		t.Errorf("requests = %d, want 2", got)
+	}
+}
+
+func TestRetryingFetcherHonorsRetryAfter(t *testing.T) {
+	var (
+		calls   atomic.Int32
+		limited time.Time
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if calls.Add(1) == 1 {
+			limited = time.Now()
+
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+
+			return
+		}
+
+		if waited := time.Since(limited); waited < time.Second {
+			t.Errorf("retried after %v, want at least the 1s Retry-After", waited)
+		}
+
+		_, _ = w.Write([]byte(`{"available_versions": [{"version": "1.0.0"}]}`))
+	}))
+	defer server.Close()
+
+	fetch := MakeRetryingFetcher(MakeArtifactHubFetcher(server.URL, server.Client(), ArtifactHubKey{}, discardLog), defaultFetchAttempts)
+
+	info, err := fetch(context.Background(), VersionQuery{Repo: "org/chart", Current: "", Timeout: 0, Limit: 0, PrereleaseSameMajor: false, Stability: StabilityDash, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil})
+	if err != nil {
+		t.Fatalf("fetch() error = %v", err)
+	}
+
+	if info.Version != "1.0.0" || calls.Load() != 2 {
+		t.Errorf("fetch() = %q after %d requests, want %q after 2", info.Version, calls.Load(), "1.0.0")
+	}
+}
+
+func TestRetryingFetcherRetryAfterBoundedByContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	fetch := MakeRetryingFetcher(MakeArtifactHubFetcher(server.URL, server.Client(), ArtifactHubKey{}, discardLog), defaultFetchAttempts)
+
+	start := time.Now()
+
+	_, err := fetch(ctx, VersionQuery{Repo: "org/chart", Current: "", Timeout: 0, Limit: 0, PrereleaseSameMajor: false, Stability: StabilityDash, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("fetch() error = %v, want the deadline", err)
+	}
+
+	var rateLimited *RateLimitError
+	if !errors.As(err, &rateLimited) || rateLimited.RetryAfter != time.Hour {
+		t.Errorf("fetch() error = %v, want a RateLimitError asking for 1h", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("fetch() took %v, want it cut short by the context", elapsed)
+	}
+}
+
+func TestRetryingFetcherCapsRetryAfter(t *testing.T) {
+	calls := 0
+	inner := func(_ context.Context, _ VersionQuery) (VersionInfo, error) {
+		calls++
+		if calls == 1 {
+			return VersionInfo{}, &RateLimitError{RetryAfter: 24 * time.Hour}
+		}
+
+		return versionInfo("1.0.0"), nil
+	}
+
+	query := VersionQuery{Repo: "org/chart", Current: "", Timeout: 10 * time.Millisecond, Limit: 0, PrereleaseSameMajor: false, Stability: StabilityDash, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil}
+
+	start := time.Now()
+
+	info, err := MakeRetryingFetcher(inner, defaultFetchAttempts)(context.Background(), query)
+	if err != nil || info.Version != "1.0.0" {
+		t.Fatalf("fetch() = %q, %v, want %q", info.Version, err, "1.0.0")
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("fetch() took %v, want the 1d Retry-After capped by the query timeout", elapsed)
+	}
+}
+
+func TestRetryWait(t *testing.T) {
+	tests := []struct {
+		retryAfter, timeout, want time.Duration
+	}{
+		{retryAfter: 2 * time.Second, timeout: 0, want: 2 * time.Second},
+		{retryAfter: 24 * time.Hour, timeout: 0, want: maxRetryAfter},
+		{retryAfter: 24 * time.Hour, timeout: 5 * time.Second, want: 5 * time.Second},
+		{retryAfter: time.Second, timeout: 5 * time.Second, want: time.Second},
+	}
+
+	for _, tt := range tests {
+		q := VersionQuery{Repo: "org/chart", Current: "", Timeout: tt.timeout, Limit: 0, PrereleaseSameMajor: false, Stability: StabilityDash, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil}
+		if got := retryWait(tt.retryAfter, q); got != tt.want {
+			t.Errorf("retryWait(%v) with timeout %v = %v, want %v", tt.retryAfter, tt.timeout, got, tt.want)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{value: "1", want: time.Second},
+		{value: " 120 ", want: 2 * time.Minute},
+		{value: "Thu, 15 Oct 2026 12:00:30 GMT", want: 30 * time.Second},
+		{value: "Thu, 15 Oct 2026 11:00:00 GMT", want: 0},
+		{value: "", want: defaultRetryAfter},
+		{value: "-5", want: defaultRetryAfter},
+		{value: "soon", want: defaultRetryAfter},
+	}
+
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestRetryingFetcherCapsAttempts(t *testing.T) {
+	var calls atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+
+		_, _ = w.Write([]byte("<html>not json</html>"))
+	}))
+	defer server.Close()
+
+	fetch := MakeRetryingFetcher(MakeArtifactHubFetcher(server.URL, server.Client(), ArtifactHubKey{}, discardLog), defaultFetchAttempts)
+
+	_, err := fetch(context.Background(), VersionQuery{Repo: "org/chart", Current: "", Timeout: 0, Limit: 0, PrereleaseSameMajor: false, Stability: StabilityDash, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil})
+	if !errors.Is(err, errDecodeResponse) {
+		t.Fatalf("fetch() error = %v, want a decode error", err)
+	}
+
+	if got := calls.Load(); got != defaultFetchAttempts {
+		t.Errorf("requests = %d, want %d", got, defaultFetchAttempts)
+	}
+}
+
+func TestRetryingFetcherDoesNotRetryOtherErrors(t *testing.T) {
+	calls := 0
+	inner := func(_ context.Context, _ VersionQuery) (VersionInfo, error) {
+		calls++
+		return VersionInfo{}, errors.New("artifacthub HTTP 404")
+	}
+
+	if _, err := MakeRetryingFetcher(inner, defaultFetchAttempts)(context.Background(), VersionQuery{Repo: "org/chart", Current: "", Timeout: 0, Limit: 0, PrereleaseSameMajor: false, Stability: StabilityDash, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestMapRepo(t *testing.T) {
+	table := map[string]string{
+		"oldorg":         "neworg",
+		"oldorg/special": "elsewhere/special-chart",
+	}
+
+	tests := []struct {
+		repo      string
+		want      string
+		wantMoved bool
+	}{
+		{repo: "oldorg/chart", want: "neworg/chart", wantMoved: true},
+		{repo: "oldorg/special", want: "elsewhere/special-chart", wantMoved: true},
+		{repo: "other/chart", want: "other/chart", wantMoved: false},
+		{repo: "oldorgx/chart", want: "oldorgx/chart", wantMoved: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.repo, func(t *testing.T) {
+			got, moved := mapRepo(table, tt.repo)
+			if got != tt.want || moved != tt.wantMoved {
+				t.Errorf("mapRepo(%q) = %q, %v, want %q, %v", tt.repo, got, moved, tt.want, tt.wantMoved)
+			}
+		})
+	}
+}
+
+func TestRepoMappingFetcher(t *testing.T) {
+	var got string
+
+	inner := func(_ context.Context, q VersionQuery) (VersionInfo, error) {
+		got = q.Repo
+		return versionInfo("1.0.0"), nil
+	}
+
+	fetch := MakeRepoMappingFetcher(inner, map[string]string{"oldorg": "neworg"})
+
+	if _, err := fetch(context.Background(), VersionQuery{Repo: "oldorg/chart", Current: "", Timeout: 0, Limit: 0, PrereleaseSameMajor: false, Stability: StabilityDash, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got != "neworg/chart" {
+		t.Errorf("inner fetcher queried %q, want %q", got, "neworg/chart")
+	}
+}
+
+func TestSourceFetcher(t *testing.T) {
+	source := func(name string) VersionFetcher {
+		return func(context.Context, VersionQuery) (VersionInfo, error) {
+			return versionInfo(name), nil
+		}
+	}
+
+	fetch := MakeSourceFetcher(source("artifacthub"), source("github"), source("helmrepo"), source("oci"))
+
+	for _, tt := range []struct{ source, want string }{{"", "artifacthub"}, {sourceGitHub, "github"}, {sourceHelmRepo, "helmrepo"}, {sourceOCI, "oci"}} {
+		info, err := fetch(context.Background(), VersionQuery{Repo: "org/chart", Current: "", Timeout: 0, Limit: 0, PrereleaseSameMajor: false, Stability: StabilityDash, RequireSigned: false, Source: tt.source, AllowPrerelease: false, Constraint: "", Ignore: nil})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if info.Version != tt.want {
+			t.Errorf("Source %q dispatched to %q, want %q", tt.source, info.Version, tt.want)
+		}
+	}
+}
+
+func TestCachingFetcher(t *testing.T) {
+	var calls atomic.Int32
+
+	inner := func(_ context.Context, q VersionQuery) (VersionInfo, error) {
+		calls.Add(1)
+		return versionInfo(q.Repo), nil
+	}
+
+	fetch := MakeCachingFetcher(inner)
+	query := VersionQuery{Repo: "org/chart", Current: "", Timeout: 0, Limit: 0, PrereleaseSameMajor: false, Stability: StabilityDash, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			if info, err := fetch(context.Background(), query); err != nil || info.Version != "org/chart" {
+				t.Errorf("fetch() = %q, %v, want %q", info.Version, err, "org/chart")
+			}
+		})
+	}
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("calls for a repeated query = %d, want 1", got)
+	}
+
+	prerelease := query
+	prerelease.AllowPrerelease = true
+
+	constrained := query
+	constrained.Constraint = "<2.0.0"
+
+	for _, q := range []VersionQuery{prerelease, constrained} {
+		if _, err := fetch(context.Background(), q); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := calls.Load(); got != 3 {
+		t.Errorf("calls after differing queries = %d, want 3", got)
+	}
+}
+
+func TestCachingFetcherSharesPins(t *testing.T) {
+	var calls atomic.Int32
+
+	inner := func(_ context.Context, q VersionQuery) (VersionInfo, error) {
+		calls.Add(1)
+		return versionInfo(q.Repo), nil
+	}
+
+	fetch := MakeCachingFetcher(inner)
+	query := VersionQuery{Repo: "org/chart", Current: "1.0.0", Timeout: 0, Limit: 0, PrereleaseSameMajor: false, Stability: StabilityDash, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil}
+
+	other := query
+	other.Current = "1.2.0"
+
+	for _, q := range []VersionQuery{query, other} {
+		if _, err := fetch(context.Background(), q); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("calls for two pins of one repo = %d, want 1", got)
+	}
+
+	line := query
+	line.Current = "1.2"
+
+	sameMajor := query
+	sameMajor.PrereleaseSameMajor = true
+
+	for _, q := range []VersionQuery{line, sameMajor} {
+		if _, err := fetch(context.Background(), q); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := calls.Load(); got != 3 {
+		t.Errorf("calls after queries narrowed by the current version = %d, want 3", got)
+	}
+}
+
+func TestCachingFetcherRetriesFailures(t *testing.T) {
+	calls := 0
+	inner := func(_ context.Context, _ VersionQuery) (VersionInfo, error) {
+		calls++
+		if calls == 1 {
+			return VersionInfo{}, errors.New("artifacthub HTTP 500")
+		}
+
+		return versionInfo("1.0.0"), nil
+	}
+
+	fetch := MakeCachingFetcher(inner)
+	query := VersionQuery{Repo: "org/chart", Current: "", Timeout: 0, Limit: 0, PrereleaseSameMajor: false, Stability: StabilityDash, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil}
+
+	if _, err := fetch(context.Background(), query); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if info, err := fetch(context.Background(), query); err != nil || info.Version != "1.0.0" {
+		t.Errorf("fetch() after a failure = %q, %v, want %q", info.Version, err, "1.0.0")
+	}
+
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+}
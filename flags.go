@@ -0,0 +1,266 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// flagSpec describes how a single command-line flag updates the configuration.
+type flagSpec struct {
+	arg   string // Description of the required argument, empty for boolean flags
+	apply func(cfg Config, value string) (Config, error)
+}
+
+// flagSpecs returns the supported flags keyed by their canonical long name.
+func flagSpecs() map[string]flagSpec {
+	return map[string]flagSpec{
+		"--dry-run":          boolFlag(func(c *Config) { c.DryRun = true }),
+		"--check":            boolFlag(func(c *Config) { c.CheckOnly = true }),
+		"--dir":              stringFlag("a directory path", func(c *Config, v string) { c.Dir = v }),
+		"--repo":             stringFlag("an org/chart argument", func(c *Config, v string) { c.Repo = v }),
+		"--dump-response":    stringFlag("an org/chart argument", func(c *Config, v string) { c.DumpResponse = v }),
+		"--version":          stringFlag("a version argument", func(c *Config, v string) { c.Current = v }),
+		"--changelog":        stringFlag("a file path", func(c *Config, v string) { c.Changelog = v }),
+		"--summary-format":   stringFlag("a Go template", func(c *Config, v string) { c.SummaryFormat = v }),
+		"--history":          stringFlag("a file path", func(c *Config, v string) { c.History = v }),
+		"--skip-unreachable": boolFlag(func(c *Config) { c.SkipUnreachable = true }),
+		"--commit":           boolFlag(func(c *Config) { c.Commit = true }),
+		"--commit-mode":      stringFlag("per-chart or single", func(c *Config, v string) { c.CommitMode = v }),
+		"--report":           stringFlag("a report format", func(c *Config, v string) { c.Report = v }),
+		"--report-file":      stringFlag("a file path", func(c *Config, v string) { c.ReportFile = v }),
+		"--api-url":          stringFlag("a URL", func(c *Config, v string) { c.APIURL = v }),
+		"--values-file": listFlag("a comma-separated list of file paths", func(c *Config, v []string) {
+			c.ValuesFiles = append(c.ValuesFiles, v...)
+		}),
+		"--include": listFlag("a comma-separated list of glob patterns", func(c *Config, v []string) {
+			c.Include = append(c.Include, v...)
+		}),
+		"--exclude": listFlag("a comma-separated list of glob patterns", func(c *Config, v []string) {
+			c.Exclude = append(c.Exclude, v...)
+		}),
+		"--exit-code": boolFlag(func(c *Config) {
+			c.DryRun = true
+			c.ExitCode = true
+		}),
+		"--fail-on":                         listFlag("a comma-separated list", func(c *Config, v []string) { c.FailOn = v }),
+		"--dry-run-exit-code":               intFlag(func(c *Config, n int) { c.DryRunExitCode = n }),
+		"--fetch-limit":                     intFlag(func(c *Config, n int) { c.FetchLimit = n }),
+		"--max-requests":                    intFlag(func(c *Config, n int) { c.MaxRequests = n }),
+		"--batch-size":                      intFlag(func(c *Config, n int) { c.BatchSize = n }),
+		"--max-per-host":                    intFlag(func(c *Config, n int) { c.MaxPerHost = n }),
+		"--idle-timeout":                    durationFlag(func(c *Config, d time.Duration) { c.IdleTimeout = d }),
+		"--timeout":                         durationFlag(func(c *Config, d time.Duration) { c.HTTPTimeout = d }),
+		"--max-idle-conns-per-host":         intFlag(func(c *Config, n int) { c.MaxIdleConnsPerHost = n }),
+		"--prerelease-within-current-major": boolFlag(func(c *Config) { c.PrereleaseSameMajor = true }),
+		"--explain-version":                 boolFlag(func(c *Config) { c.ExplainVersion = true }),
+		"--discover-json":                   boolFlag(func(c *Config) { c.DiscoverJSON = true }),
+		"--never-downgrade":                 boolFlag(func(c *Config) { c.NeverDowngrade = true }),
+		"--verbose":                         boolFlag(func(c *Config) { c.Verbose = true }),
+		"--quiet":                           boolFlag(func(c *Config) { c.Quiet = true }),
+		"--sort-docs":                       boolFlag(func(c *Config) { c.SortDocs = true }),
+		"--verify-writes":                   boolFlag(func(c *Config) { c.VerifyWrites = true }),
+		"--preserve-format":                 boolFlag(func(c *Config) { c.PreserveFormat = true }),
+		"--require-signed":                  boolFlag(func(c *Config) { c.RequireSigned = true }),
+		"--allow-outside-base":              boolFlag(func(c *Config) { c.AllowOutsideBase = true }),
+		"--config-print":                    boolFlag(func(c *Config) { c.ConfigPrint = true }),
+		"--with-source":                     boolFlag(func(c *Config) { c.WithSource = true }),
+		"--resume":                          boolFlag(func(c *Config) { c.Resume = true }),
+		"--check-chart-name":                boolFlag(func(c *Config) { c.CheckChartName = true }),
+		"--check-consistency":               boolFlag(func(c *Config) { c.CheckConsistency = true }),
+		"--probe":                           boolFlag(func(c *Config) { c.Probe = true }),
+		"--selftest":                        boolFlag(func(c *Config) { c.SelfTest = true }),
+		"--patch-out":                       stringFlag("a file", func(c *Config, v string) { c.PatchOut = v }),
+		"--diff-base":                       stringFlag("a git revision", func(c *Config, v string) { c.DiffBase = v }),
+		"--diff-mode":                       stringFlag("git or builtin", func(c *Config, v string) { c.DiffMode = v }),
+		"--compact":                         boolFlag(func(c *Config) { c.Compact = true }),
+		"--output":                          stringFlag("a format", func(c *Config, v string) { c.Output = v }),
+		"--concurrency":                     intFlag(func(c *Config, n int) { c.Concurrency = n }),
+		"--max-bump":                        stringFlag("a level", func(c *Config, v string) { c.MaxBump = BumpLevel(v) }),
+		"--suggest":                         boolFlag(func(c *Config) { c.Suggest = true }),
+		"--print-effective-versions":        boolFlag(func(c *Config) { c.PrintEffective = true }),
+		"--map-repo":                        mappingFlag(func(c *Config, from, to string) { c.RepoMap = withEntry(c.RepoMap, from, to) }),
+		"--rewrite-moved":                   boolFlag(func(c *Config) { c.RewriteMoved = true }),
+		"--stamp-checked":                   boolFlag(func(c *Config) { c.StampChecked = true }),
+		"--chart":                           stringFlag("a chart name", func(c *Config, v string) { c.ChartName = v }),
+		"--stable-rule":                     stringFlag("a rule name", func(c *Config, v string) { c.StableRule = StabilityRule(v) }),
+		"--only-kind":                       stringFlag("a resource kind", func(c *Config, v string) { c.OnlyKind = v }),
+		"--opt-out-label":                   stringFlag("a label key", func(c *Config, v string) { c.OptOutLabel = v }),
+		"--freeze-until":                    timeFlag(func(c *Config, t time.Time) { c.FreezeUntil = t }),
+		"--changed-files":                   stringFlag("a file path", func(c *Config, v string) { c.ChangedFiles = v }),
+		"--profile":                         stringFlag("a profile name", func(c *Config, v string) { c.Profile = v }),
+		"--config":                          stringFlag("a file path", func(c *Config, v string) { c.ConfigFile = v }),
+		"--help": {arg: "", apply: func(cfg Config, _ string) (Config, error) {
+			return cfg, errors.New("help requested")
+		}},
+	}
+}
+
+// flagAliases maps short flags to their canonical long name.
+func flagAliases() map[string]string {
+	return map[string]string{
+		"-n": "--dry-run",
+		"-C": "--check",
+		"-d": "--dir",
+		"-r": "--repo",
+		"-h": "--help",
+		"-v": "--verbose",
+		"-q": "--quiet",
+	}
+}
+
+// Subcommands accepted as the first argument.
+const (
+	cmdUpdate   = "update"
+	cmdCheck    = "check"
+	cmdDiscover = "discover"
+	cmdVersion  = "version"
+)
+
+// subcommands maps each subcommand to the flags it stands for.
+func subcommands() map[string][]string {
+	return map[string][]string{
+		cmdUpdate:   nil,
+		cmdCheck:    {"--check"},
+		cmdDiscover: {"--discover-json"},
+		cmdVersion:  nil,
+	}
+}
+
+// splitSubcommand returns the subcommand named by the first argument, or
+// "update" when there is none, together with the arguments to parse: the
+// subcommand's flags followed by the remaining arguments.
+func splitSubcommand(args []string) (string, []string) {
+	if len(args) > 0 {
+		if implied, ok := subcommands()[args[0]]; ok {
+			return args[0], append(slices.Clone(implied), args[1:]...)
+		}
+	}
+
+	return cmdUpdate, args
+}
+
+// lookupFlag resolves a flag (or alias) to its canonical name and spec.
+func lookupFlag(name string) (string, flagSpec, bool) {
+	if long, ok := flagAliases()[name]; ok {
+		name = long
+	}
+
+	spec, ok := flagSpecs()[name]
+
+	return name, spec, ok
+}
+
+func boolFlag(set func(*Config)) flagSpec {
+	return flagSpec{arg: "", apply: func(cfg Config, _ string) (Config, error) {
+		set(&cfg)
+		return cfg, nil
+	}}
+}
+
+func stringFlag(arg string, set func(*Config, string)) flagSpec {
+	return flagSpec{arg: arg, apply: func(cfg Config, v string) (Config, error) {
+		set(&cfg, v)
+		return cfg, nil
+	}}
+}
+
+func intFlag(set func(*Config, int)) flagSpec {
+	return flagSpec{arg: "a number", apply: func(cfg Config, v string) (Config, error) {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid number %q", v)
+		}
+
+		set(&cfg, n)
+
+		return cfg, nil
+	}}
+}
+
+func durationFlag(set func(*Config, time.Duration)) flagSpec {
+	return flagSpec{arg: "a duration", apply: func(cfg Config, v string) (Config, error) {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid duration %q", v)
+		}
+
+		set(&cfg, d)
+
+		return cfg, nil
+	}}
+}
+
+func timeFlag(set func(*Config, time.Time)) flagSpec {
+	return flagSpec{arg: "an RFC3339 timestamp", apply: func(cfg Config, v string) (Config, error) {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid RFC3339 timestamp %q", v)
+		}
+
+		set(&cfg, t)
+
+		return cfg, nil
+	}}
+}
+
+// mappingFlag parses an "old=new" argument. It may be given more than once.
+func mappingFlag(set func(*Config, string, string)) flagSpec {
+	return flagSpec{arg: "an old=new mapping", apply: func(cfg Config, v string) (Config, error) {
+		from, to, ok := strings.Cut(v, "=")
+		if !ok || from == "" || to == "" {
+			return cfg, fmt.Errorf("invalid mapping %q: want old=new", v)
+		}
+
+		set(&cfg, from, to)
+
+		return cfg, nil
+	}}
+}
+
+// withEntry returns a copy of m with key set to value, so configs passed by
+// value never share a mutated map.
+func withEntry(m map[string]string, key, value string) map[string]string {
+	out := maps.Clone(m)
+	if out == nil {
+		out = make(map[string]string)
+	}
+
+	out[key] = value
+
+	return out
+}
+
+// listFlag parses a comma-separated argument, dropping empty items.
+func listFlag(arg string, set func(*Config, []string)) flagSpec {
+	return flagSpec{arg: arg, apply: func(cfg Config, v string) (Config, error) {
+		items := slices.DeleteFunc(strings.Split(v, ","), func(s string) bool { return strings.TrimSpace(s) == "" })
+		for i := range items {
+			items[i] = strings.TrimSpace(items[i])
+		}
+
+		set(&cfg, items)
+
+		return cfg, nil
+	}}
+}
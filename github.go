@@ -0,0 +1,172 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	gitHubAPIURL      = "https://api.github.com"
+	gitHubTokenEnvVar = "GITHUB_TOKEN"
+
+	// gitHubReleasesPerPage is the most releases the API returns per page;
+	// only the newest page is considered.
+	gitHubReleasesPerPage = 100
+)
+
+// errDecodeReleases marks a 200 response whose release list could not be
+// decoded; like errDecodeResponse it is worth retrying.
+var errDecodeReleases = errors.New("decode github releases")
+
+// GitHubRelease represents a release entry in the GitHub releases API response.
+type GitHubRelease struct {
+	TagName     string    `json:"tag_name"`
+	Draft       bool      `json:"draft"`
+	Prerelease  bool      `json:"prerelease"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// MakeGitHubReleasesFetcher creates a VersionFetcher for charts published as
+// GitHub releases. The query's Repo is an "owner/repo" path. Drafts are
+// rejected, as are releases marked as pre-releases unless the query allows
+// them; a leading "v" is stripped from tag names and the rest is selected
+// from like MakeArtifactHubFetcher does. A non-empty token is sent as a bearer
+// token, which raises the API's rate limit and allows private repositories.
+// Releases carry no signature data, so queries requiring signed versions fail.
+func MakeGitHubReleasesFetcher(apiURL string, client *http.Client, token string) VersionFetcher {
+	return func(ctx context.Context, q VersionQuery) (VersionInfo, error) {
+		if q.RequireSigned {
+			return VersionInfo{}, errors.New("--require-signed: github releases carry no signature data")
+		}
+
+		releases, err := fetchGitHubReleases(ctx, apiURL, withTimeout(client, q.Timeout), q.Repo, token, q.Limit)
+		if err != nil {
+			return VersionInfo{}, err
+		}
+
+		versions, rejected, released := gitHubReleaseVersions(releases, q)
+		if len(versions) == 0 && len(rejected) == 0 {
+			return VersionInfo{}, errNoVersions
+		}
+
+		latest, sel, ok := selectVersion(versions, q)
+		sel.Rejected = append(rejected, sel.Rejected...)
+
+		if !ok {
+			if constraintBlocked(sel, q) {
+				return VersionInfo{}, constraintError(q.Constraint, sel)
+			}
+
+			if isPartialPin(q.Current) {
+				return VersionInfo{}, fmt.Errorf("no stable releases found in the %s.x line", q.Current)
+			}
+
+			return VersionInfo{}, fmt.Errorf("no stable releases found, %s rejected", plural(len(sel.Rejected), "release"))
+		}
+
+		return VersionInfo{Version: latest, Selection: sel, ReleasedAt: released[latest], Deprecated: false, AppVersion: "", Digest: ""}, nil
+	}
+}
+
+// gitHubReleaseVersions turns releases into candidate versions, rejecting
+// drafts, tags that are not versions and releases marked as pre-releases,
+// unless the query allows pre-releases, at all or within the current major.
+func gitHubReleaseVersions(releases []GitHubRelease, q VersionQuery) ([]string, []Rejection, map[string]time.Time) {
+	var (
+		tags     []string
+		rejected []Rejection
+	)
+
+	released := map[string]time.Time{}
+
+	for _, r := range releases {
+		version := strings.TrimPrefix(r.TagName, "v")
+		allowed := q.AllowPrerelease || (q.PrereleaseSameMajor && sameMajor(version, q.Current))
+
+		switch {
+		case r.Draft:
+			rejected = append(rejected, Rejection{Version: r.TagName, Reason: "draft release"})
+		case r.Prerelease && !allowed:
+			rejected = append(rejected, Rejection{Version: r.TagName, Reason: "marked as pre-release"})
+		default:
+			tags = append(tags, version)
+
+			if _, seen := released[version]; !r.PublishedAt.IsZero() && !seen {
+				released[version] = r.PublishedAt.UTC()
+			}
+		}
+	}
+
+	versions, dropped := cleanVersions(tags)
+
+	return versions, append(rejected, dropped...), released
+}
+
+// fetchGitHubReleases lists the newest releases of repo, at most limit of them
+// when it is positive.
+func fetchGitHubReleases(
+	ctx context.Context, apiURL string, client *http.Client, repo, token string, limit int,
+) ([]GitHubRelease, error) {
+	perPage := gitHubReleasesPerPage
+	if limit > 0 {
+		perPage = min(limit, perPage)
+	}
+
+	endpoint := fmt.Sprintf("%s/repos/%s/releases?per_page=%d", apiURL, repo, perPage)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch releases from github: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("github HTTP %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errDecodeReleases, err)
+	}
+
+	var releases []GitHubRelease
+	if err := json.Unmarshal(body, &releases); err != nil {
+		return nil, fmt.Errorf("%w: %w", errDecodeReleases, err)
+	}
+
+	return releases, nil
+}
@@ -0,0 +1,304 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGitHubReleasesFetcher(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		response     string
+		wantVer      string
+		wantErr      string
+		wantRejected []Rejection
+	}{
+		{
+			name:   "drafts and pre-releases are skipped",
+			status: http.StatusOK,
+			response: `[
+				{"tag_name": "v2.0.0", "draft": true, "prerelease": false},
+				{"tag_name": "v1.3.0-rc.1", "draft": false, "prerelease": true},
+				{"tag_name": "v1.2.0", "draft": false, "prerelease": false},
+				{"tag_name": "v1.1.0", "draft": false, "prerelease": false}
+			]`,
+			wantVer: "1.2.0",
+			wantErr: "",
+			wantRejected: []Rejection{
+				{Version: "v2.0.0", Reason: "draft release"},
+				{Version: "v1.3.0-rc.1", Reason: "marked as pre-release"},
+			},
+		},
+		{
+			name:         "tags without a v prefix",
+			status:       http.StatusOK,
+			response:     `[{"tag_name": "0.9.0"}, {"tag_name": "0.10.0"}]`,
+			wantVer:      "0.10.0",
+			wantErr:      "",
+			wantRejected: nil,
+		},
+		{
+			name:         "non-version tags are dropped",
+			status:       http.StatusOK,
+			response:     `[{"tag_name": "nightly"}, {"tag_name": "v1.0.0"}]`,
+			wantVer:      "1.0.0",
+			wantErr:      "",
+			wantRejected: []Rejection{{Version: "nightly", Reason: "unparseable version"}},
+		},
+		{
+			name:         "no releases",
+			status:       http.StatusOK,
+			response:     `[]`,
+			wantVer:      "",
+			wantErr:      "no versions published",
+			wantRejected: nil,
+		},
+		{
+			name:         "only pre-releases",
+			status:       http.StatusOK,
+			response:     `[{"tag_name": "v1.0.0-rc.1", "prerelease": true}, {"tag_name": "v0.1.0", "draft": true}]`,
+			wantVer:      "",
+			wantErr:      "no stable releases found, 2 releases rejected",
+			wantRejected: nil,
+		},
+		{
+			name:         "not found",
+			status:       http.StatusNotFound,
+			response:     `{"message": "Not Found"}`,
+			wantVer:      "",
+			wantErr:      "github HTTP 404",
+			wantRejected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/repos/owner/chart/releases" {
+					http.NotFound(w, r)
+					return
+				}
+
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			fetch := MakeGitHubReleasesFetcher(server.URL, server.Client(), "")
+			ver, err := fetch(context.Background(), testQuery(sourceGitHub, "owner/chart"))
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("fetcher() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("fetcher() error = %v", err)
+			}
+
+			if ver.Version != tt.wantVer {
+				t.Errorf("fetcher() = %q, want %q", ver.Version, tt.wantVer)
+			}
+
+			if !slices.Equal(ver.Selection.Rejected, tt.wantRejected) {
+				t.Errorf("rejected = %v, want %v", ver.Selection.Rejected, tt.wantRejected)
+			}
+		})
+	}
+}
+
+func TestGitHubReleasesFetcherSendsToken(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+		want  string
+	}{
+		{name: "token", token: "secret", want: "Bearer secret"},
+		{name: "anonymous", token: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Get("Authorization")
+				_, _ = w.Write([]byte(`[{"tag_name": "v1.0.0"}]`))
+			}))
+			defer server.Close()
+
+			fetch := MakeGitHubReleasesFetcher(server.URL, server.Client(), tt.token)
+			if _, err := fetch(context.Background(), testQuery(sourceGitHub, "owner/chart")); err != nil {
+				t.Fatalf("fetcher() error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("Authorization = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitHubReleasesFetcherReleasedAt(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`[{"tag_name": "v1.0.0", "published_at": "2026-03-01T12:00:00Z"}]`))
+	}))
+	defer server.Close()
+
+	fetch := MakeGitHubReleasesFetcher(server.URL, server.Client(), "")
+	ver, err := fetch(context.Background(), testQuery(sourceGitHub, "owner/chart"))
+	if err != nil {
+		t.Fatalf("fetcher() error = %v", err)
+	}
+
+	if want := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC); !ver.ReleasedAt.Equal(want) {
+		t.Errorf("ReleasedAt = %v, want %v", ver.ReleasedAt, want)
+	}
+}
+
+func TestGitHubReleasesFetcherAllowPrerelease(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`[
+			{"tag_name": "v2.0.0", "draft": true},
+			{"tag_name": "v1.3.0-rc.1", "prerelease": true},
+			{"tag_name": "v1.2.0"}
+		]`))
+	}))
+	defer server.Close()
+
+	q := testQuery(sourceGitHub, "owner/chart")
+	q.AllowPrerelease = true
+
+	ver, err := MakeGitHubReleasesFetcher(server.URL, server.Client(), "")(context.Background(), q)
+	if err != nil {
+		t.Fatalf("fetcher() error = %v", err)
+	}
+
+	if ver.Version != "1.3.0-rc.1" {
+		t.Errorf("Version = %q, want %q", ver.Version, "1.3.0-rc.1")
+	}
+
+	want := []Rejection{{Version: "v2.0.0", Reason: "draft release"}}
+	if !slices.Equal(ver.Selection.Rejected, want) {
+		t.Errorf("Rejected = %+v, want %+v", ver.Selection.Rejected, want)
+	}
+}
+
+func TestGitHubReleasesFetcherSelection(t *testing.T) {
+	const releases = `[
+		{"tag_name": "v1.16.0"},
+		{"tag_name": "v1.16.1-rc.1", "prerelease": true},
+		{"tag_name": "v1.15.4"},
+		{"tag_name": "v1.15.3"},
+		{"tag_name": "v2.0.0-rc.1", "prerelease": true}
+	]`
+
+	tests := []struct {
+		name  string
+		query func(q VersionQuery) VersionQuery
+		want  string
+	}{
+		{
+			name:  "partial pin stays in its line",
+			query: func(q VersionQuery) VersionQuery { q.Current = "1.15"; return q },
+			want:  "1.15.4",
+		},
+		{
+			name: "pre-releases within the current major",
+			query: func(q VersionQuery) VersionQuery {
+				q.Current, q.PrereleaseSameMajor = "1.15.3", true
+				return q
+			},
+			want: "1.16.1-rc.1",
+		},
+		{
+			name:  "constraint",
+			query: func(q VersionQuery) VersionQuery { q.Constraint = "<1.16.0"; return q },
+			want:  "1.15.4",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte(releases))
+			}))
+			defer server.Close()
+
+			fetch := MakeGitHubReleasesFetcher(server.URL, server.Client(), "")
+			ver, err := fetch(context.Background(), tt.query(testQuery(sourceGitHub, "owner/chart")))
+			if err != nil || ver.Version != tt.want {
+				t.Errorf("fetcher() = %q, %v, want %q", ver.Version, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitHubReleasesFetcherLimit(t *testing.T) {
+	var got string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.Query().Get("per_page")
+
+		_, _ = w.Write([]byte(`[{"tag_name": "v1.0.0"}]`))
+	}))
+	defer server.Close()
+
+	q := testQuery(sourceGitHub, "owner/chart")
+	q.Limit = 20
+
+	if _, err := MakeGitHubReleasesFetcher(server.URL, server.Client(), "")(context.Background(), q); err != nil {
+		t.Fatalf("fetcher() error = %v", err)
+	}
+
+	if got != "20" {
+		t.Errorf("per_page = %q, want %q", got, "20")
+	}
+}
+
+func TestGitHubReleasesFetcherRequireSigned(t *testing.T) {
+	requested := false
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requested = true
+
+		_, _ = w.Write([]byte(`[{"tag_name": "v1.0.0"}]`))
+	}))
+	defer server.Close()
+
+	q := testQuery(sourceGitHub, "owner/chart")
+	q.RequireSigned = true
+
+	_, err := MakeGitHubReleasesFetcher(server.URL, server.Client(), "")(context.Background(), q)
+	if err == nil || !strings.Contains(err.Error(), "--require-signed") {
+		t.Errorf("fetcher() error = %v, want a --require-signed error", err)
+	}
+
+	if requested {
+		t.Error("fetcher() requested releases despite failing closed")
+	}
+}
@@ -0,0 +1,189 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// sourceHelmRepo is the ChartInfo and VersionQuery Source of charts read from a
+// classic Helm repository's index.yaml. Their Repo is the repository URL
+// followed by "/" and the chart name; see splitHelmRepo.
+const sourceHelmRepo = "helmrepo"
+
+// errDecodeIndex marks a 200 response whose index.yaml could not be decoded;
+// like errDecodeResponse it is worth retrying.
+var errDecodeIndex = errors.New("decode helm repository index")
+
+// HelmIndex is the part of a Helm repository's index.yaml the updater reads.
+type HelmIndex struct {
+	Entries map[string][]HelmIndexEntry `yaml:"entries"`
+}
+
+// HelmIndexEntry is one published version of a chart in a HelmIndex.
+type HelmIndexEntry struct {
+	Version    string `yaml:"version"`
+	Created    string `yaml:"created"`
+	AppVersion string `yaml:"appVersion"`
+	Digest     string `yaml:"digest"`
+}
+
+// MakeHelmRepoFetcher creates a VersionFetcher for charts served by a classic
+// Helm repository. It downloads the repository's index.yaml and selects from
+// the chart's entries like MakeArtifactHubFetcher does from ArtifactHub's.
+// Provenance files are not verified, so queries requiring signed versions fail.
+func MakeHelmRepoFetcher(client *http.Client) VersionFetcher {
+	return func(ctx context.Context, q VersionQuery) (VersionInfo, error) {
+		if q.RequireSigned {
+			return VersionInfo{}, errors.New("--require-signed: helm repository provenance is not verified")
+		}
+
+		baseURL, chart := splitHelmRepo(q.Repo)
+
+		index, err := fetchHelmIndex(ctx, withTimeout(client, q.Timeout), baseURL)
+		if err != nil {
+			return VersionInfo{}, err
+		}
+
+		entries, ok := index.Entries[chart]
+		if !ok {
+			return VersionInfo{}, fmt.Errorf("chart %q not found in %s/index.yaml", chart, baseURL)
+		}
+
+		raw := make([]string, 0, len(entries))
+		released := map[string]time.Time{}
+		described := map[string]HelmIndexEntry{}
+
+		for _, e := range entries {
+			raw = append(raw, e.Version)
+
+			if _, seen := described[e.Version]; !seen {
+				described[e.Version] = e
+			}
+
+			if created, parseErr := time.Parse(time.RFC3339Nano, e.Created); parseErr == nil {
+				if _, seen := released[e.Version]; !seen {
+					released[e.Version] = created.UTC()
+				}
+			}
+		}
+
+		versions, dropped := cleanVersions(raw)
+		if len(versions) == 0 {
+			return VersionInfo{}, errNoVersions
+		}
+
+		latest, sel, ok := selectVersion(versions, q)
+		sel.Rejected = append(dropped, sel.Rejected...)
+
+		if !ok {
+			if constraintBlocked(sel, q) {
+				return VersionInfo{}, constraintError(q.Constraint, sel)
+			}
+
+			return VersionInfo{}, fmt.Errorf("no stable versions found, %s rejected", plural(len(sel.Rejected), "version"))
+		}
+
+		return VersionInfo{
+			Version: latest, Selection: sel, ReleasedAt: released[latest],
+			Deprecated: false, AppVersion: described[latest].AppVersion, Digest: described[latest].Digest,
+		}, nil
+	}
+}
+
+// splitHelmRepo splits a helm repository Repo into the repository URL and the
+// chart name, which is its last path segment.
+func splitHelmRepo(repo string) (string, string) {
+	i := strings.LastIndex(repo, "/")
+	if i < 0 {
+		return repo, ""
+	}
+
+	return repo[:i], repo[i+1:]
+}
+
+// helmRepoHost is the QueryHost of Helm repository charts: the host of the
+// repository URL.
+func helmRepoHost(q VersionQuery) string {
+	baseURL, _ := splitHelmRepo(q.Repo)
+	return hostOf(baseURL)
+}
+
+// parseHelmRepoComment validates the text following the helmrepo prefix: a
+// repository URL and a chart name, then the optional prerelease marker and
+// constraint every source comment accepts. The URL and chart are joined into
+// the returned Repo.
+func parseHelmRepoComment(value string) (RepoComment, error) {
+	fields := strings.Fields(value)
+	if len(fields) < 2 || isConstraintTerm(fields[1]) || fields[1] == prereleaseMarker {
+		return RepoComment{}, fmt.Errorf("%s comment %q must give a repository URL and a chart name", sourceHelmRepo, value)
+	}
+
+	u, err := url.Parse(fields[0])
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return RepoComment{}, fmt.Errorf("invalid %s URL %q: must be an http(s) URL", sourceHelmRepo, fields[0])
+	}
+
+	if strings.Contains(fields[1], "/") {
+		return RepoComment{}, fmt.Errorf("invalid %s chart name %q: must not contain /", sourceHelmRepo, fields[1])
+	}
+
+	repo := strings.TrimSuffix(fields[0], "/") + "/" + fields[1]
+
+	return parseSourceComment(sourceHelmRepo, strings.Join(append([]string{repo}, fields[2:]...), " "))
+}
+
+// fetchHelmIndex downloads and decodes the index.yaml of the repository at
+// baseURL.
+func fetchHelmIndex(ctx context.Context, client *http.Client, baseURL string) (HelmIndex, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/index.yaml", nil)
+	if err != nil {
+		return HelmIndex{}, fmt.Errorf("create request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return HelmIndex{}, fmt.Errorf("fetch helm repository index: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return HelmIndex{}, fmt.Errorf("helm repository HTTP %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return HelmIndex{}, fmt.Errorf("%w: %w", errDecodeIndex, err)
+	}
+
+	var index HelmIndex
+	if err := yaml.Unmarshal(body, &index); err != nil {
+		return HelmIndex{}, fmt.Errorf("%w: %w", errDecodeIndex, err)
+	}
+
+	return index, nil
+}
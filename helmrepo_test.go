@@ -0,0 +1,200 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testHelmIndex = `apiVersion: v1
+entries:
+  mychart:
+    - name: mychart
+      version: 2.1.0-rc.1
+      created: "2026-03-01T10:00:00.123456789Z"
+    - name: mychart
+      version: 2.0.1
+      appVersion: v3.4.0
+      digest: 0f1e2d
+      created: "2026-02-01T10:00:00Z"
+      urls:
+        - https://charts.example.com/mychart-2.0.1.tgz
+    - name: mychart
+      version: 1.9.0
+      created: "2025-12-01T10:00:00Z"
+  other:
+    - name: other
+      version: 9.9.9
+generated: "2026-03-01T10:00:00Z"
+`
+
+func TestHelmRepoFetcher(t *testing.T) {
+	var requested string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.Path
+
+		_, _ = w.Write([]byte(testHelmIndex))
+	}))
+	defer server.Close()
+
+	fetch := MakeHelmRepoFetcher(server.Client())
+
+	info, err := fetch(context.Background(), testQuery(sourceHelmRepo, server.URL+"/stable/mychart"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if requested != "/stable/index.yaml" {
+		t.Errorf("requested %q, want %q", requested, "/stable/index.yaml")
+	}
+
+	if info.Version != "2.0.1" {
+		t.Errorf("Version = %q, want %q", info.Version, "2.0.1")
+	}
+
+	if want := time.Date(2026, 2, 1, 10, 0, 0, 0, time.UTC); !info.ReleasedAt.Equal(want) {
+		t.Errorf("ReleasedAt = %v, want %v", info.ReleasedAt, want)
+	}
+
+	if info.AppVersion != "v3.4.0" || info.Digest != "0f1e2d" {
+		t.Errorf("AppVersion, Digest = %q, %q, want %q, %q", info.AppVersion, info.Digest, "v3.4.0", "0f1e2d")
+	}
+
+	q := testQuery(sourceHelmRepo, server.URL+"/stable/mychart")
+	q.AllowPrerelease = true
+
+	if info, err := fetch(context.Background(), q); err != nil || info.Version != "2.1.0-rc.1" {
+		t.Errorf("fetch() allowing pre-releases = %q, %v, want %q", info.Version, err, "2.1.0-rc.1")
+	}
+
+	q.AllowPrerelease, q.Constraint = false, "<2.0.0"
+
+	if info, err := fetch(context.Background(), q); err != nil || info.Version != "1.9.0" {
+		t.Errorf("fetch() with constraint = %q, %v, want %q", info.Version, err, "1.9.0")
+	}
+}
+
+func TestHelmRepoFetcherErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		chart   string
+		wantErr string
+	}{
+		{name: "chart not in index", status: http.StatusOK, body: testHelmIndex, chart: "missing", wantErr: `chart "missing" not found`},
+		{name: "http error", status: http.StatusNotFound, body: "", chart: "mychart", wantErr: "helm repository HTTP 404"},
+		{name: "malformed index", status: http.StatusOK, body: "entries: [", chart: "mychart", wantErr: errDecodeIndex.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			_, err := MakeHelmRepoFetcher(server.Client())(context.Background(),
+				testQuery(sourceHelmRepo, server.URL+"/"+tt.chart))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("fetch() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseHelmRepoComment(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    RepoComment
+		wantErr bool
+	}{
+		{value: "https://charts.example.com mychart", want: RepoComment{Repo: "https://charts.example.com/mychart", AllowPrerelease: false, Constraint: ""}, wantErr: false},
+		{value: "https://charts.example.com/stable/ mychart", want: RepoComment{Repo: "https://charts.example.com/stable/mychart", AllowPrerelease: false, Constraint: ""}, wantErr: false},
+		{value: "https://charts.example.com mychart prerelease <3.0.0", want: RepoComment{Repo: "https://charts.example.com/mychart", AllowPrerelease: true, Constraint: "<3.0.0"}, wantErr: false},
+		{value: "https://charts.example.com", want: RepoComment{}, wantErr: true},
+		{value: "https://charts.example.com >=1.0.0", want: RepoComment{}, wantErr: true},
+		{value: "charts.example.com mychart", want: RepoComment{}, wantErr: true},
+		{value: "https://charts.example.com org/mychart", want: RepoComment{}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseHelmRepoComment(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseHelmRepoComment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseHelmRepoComment() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractChartInfoHelmRepoSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), testAppFile)
+
+	content := "# helmrepo: https://charts.example.com mychart\nkind: Application\nspec:\n  source:\n    chart: mychart\n    targetRevision: 1.9.0\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, read := range []YAMLReader{readYAMLDocuments, readFirstArtifactHubApplication} {
+		got, warnings := chartInfoAt(read, path)
+		if warnings != "" {
+			t.Fatal(warnings)
+		}
+
+		if got.Repo != "https://charts.example.com/mychart" || got.Source != sourceHelmRepo {
+			t.Errorf("toChartInfo() = %q from %q, want %q from %q", got.Repo, got.Source, "https://charts.example.com/mychart", sourceHelmRepo)
+		}
+	}
+}
+
+func TestHelmRepoFetcherRequireSigned(t *testing.T) {
+	requested := false
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requested = true
+
+		_, _ = w.Write([]byte(testHelmIndex))
+	}))
+	defer server.Close()
+
+	q := testQuery(sourceHelmRepo, server.URL+"/mychart")
+	q.RequireSigned = true
+
+	_, err := MakeHelmRepoFetcher(server.Client())(context.Background(), q)
+	if err == nil || !strings.Contains(err.Error(), "--require-signed") {
+		t.Errorf("fetch() error = %v, want a --require-signed error", err)
+	}
+
+	if requested {
+		t.Error("fetch() downloaded the index despite failing closed")
+	}
+}
@@ -0,0 +1,69 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"time"
+)
+
+// appendHistory appends one CSV row per result to the history log at path,
+// writing the header first when the file is new or empty.
+func appendHistory(path string, now time.Time, results *Results) (err error) {
+	//nolint:gosec // history path is supplied by the user on the command line
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open history file: %w", err)
+	}
+
+	defer closeFile(f, &err)
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("stat history file: %w", err)
+	}
+
+	w := csv.NewWriter(f)
+
+	if info.Size() == 0 {
+		if err = w.Write(historyHeader()); err != nil {
+			return fmt.Errorf("write history header: %w", err)
+		}
+	}
+
+	timestamp := now.UTC().Format(time.RFC3339)
+
+	for _, r := range results.All() {
+		if err = w.Write([]string{timestamp, r.File, r.Repo, r.Current, r.Latest, string(r.Status)}); err != nil {
+			return fmt.Errorf("write history row: %w", err)
+		}
+	}
+
+	w.Flush()
+
+	if err = w.Error(); err != nil {
+		return fmt.Errorf("flush history file: %w", err)
+	}
+
+	return nil
+}
+
+func historyHeader() []string {
+	return []string{"timestamp", "file", "repo", "current", "latest", "status"}
+}
@@ -0,0 +1,91 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAppendHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.csv")
+	first := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	second := first.Add(24 * time.Hour)
+
+	err := appendHistory(path, first, NewResults([]UpdateResult{
+		{File: "a.yaml", Repo: "org/a", Current: "1.0.0", Latest: "1.1.0", Status: StatusUpdated, Error: nil},
+		{File: "b.yaml", Repo: "org/b", Current: "2.0.0", Latest: "2.0.0", Status: StatusUpToDate, Error: nil},
+	}...))
+	if err != nil {
+		t.Fatalf("appendHistory() error = %v", err)
+	}
+
+	err = appendHistory(path, second, NewResults([]UpdateResult{
+		{File: "c.yaml", Repo: "org/c", Current: "", Latest: "", Status: StatusError, Error: errors.New("boom")},
+	}...))
+	if err != nil {
+		t.Fatalf("appendHistory() second run error = %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "timestamp,file,repo,current,latest,status\n" +
+		"2026-01-02T03:04:05Z,a.yaml,org/a,1.0.0,1.1.0,updated\n" +
+		"2026-01-02T03:04:05Z,b.yaml,org/b,2.0.0,2.0.0,up-to-date\n" +
+		"2026-01-03T03:04:05Z,c.yaml,org/c,,,error\n"
+
+	if string(content) != want {
+		t.Errorf("history file =\n%s\nwant\n%s", content, want)
+	}
+}
+
+func TestAppendHistoryExistingFileKeepsHeaderOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.csv")
+	if err := os.WriteFile(path, []byte("timestamp,file,repo,current,latest,status\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := appendHistory(path, now, NewResults([]UpdateResult{
+		{File: "a.yaml", Repo: "org/a", Current: "1.0.0", Latest: "1.0.0", Status: StatusUpToDate, Error: nil},
+	}...)); err != nil {
+		t.Fatalf("appendHistory() error = %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "timestamp,file,repo,current,latest,status\n2026-01-02T03:04:05Z,a.yaml,org/a,1.0.0,1.0.0,up-to-date\n"
+	if string(content) != want {
+		t.Errorf("history file = %q, want %q", content, want)
+	}
+}
+
+func TestAppendHistoryUnwritablePath(t *testing.T) {
+	err := appendHistory(filepath.Join(t.TempDir(), "missing", "history.csv"), time.Now(), NewResults())
+	if err == nil {
+		t.Error("appendHistory() error = nil, want error")
+	}
+}
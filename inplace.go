@@ -0,0 +1,167 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// scalarEdit is one scalar whose value changed between a file as read and
+// the documents about to be written.
+type scalarEdit struct {
+	node  *yaml.Node // The scalar as read, with its position in the file
+	value string     // The value to write in its place
+}
+
+// preservingEncoder returns an encoder that, when preserve is set, writes the
+// current content of path with only the changed scalars replaced, so that
+// comments, indentation and quoting elsewhere stay byte-identical and a
+// version bump is a one-line diff. Documents it cannot edit that way, such as
+// JSON manifests, reordered documents or added comments, go through encode.
+func preservingEncoder(path string, preserve bool, encode func(io.Writer, []*yaml.Node) error) func(io.Writer, []*yaml.Node) error {
+	if !preserve || isJSONFile(path) {
+		return encode
+	}
+
+	return func(w io.Writer, docs []*yaml.Node) error {
+		//nolint:gosec // path is validated to be within base directory in config.go
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return encode(w, docs)
+		}
+
+		edited, ok := editInPlace(src, docs)
+		if !ok {
+			return encode(w, docs)
+		}
+
+		if _, err := w.Write(edited); err != nil {
+			return fmt.Errorf("write yaml: %w", err)
+		}
+
+		return nil
+	}
+}
+
+// writeYAMLDocumentsInPlace is writeYAMLDocuments for --preserve-format.
+func writeYAMLDocumentsInPlace(_ context.Context, path string, docs []*yaml.Node) error {
+	return writeYAMLFile(path, docs, preservingEncoder(path, true, encoderFor(path)), nil)
+}
+
+// editInPlace rewrites src, the YAML that docs were read from, so that it
+// decodes to docs. It only succeeds when docs differ from src in scalar values
+// alone and the result decodes back to docs.
+func editInPlace(src []byte, docs []*yaml.Node) ([]byte, bool) {
+	before, err := decodeStream(yaml.NewDecoder(bytes.NewReader(src)))
+	if err != nil || len(before) != len(docs) {
+		return nil, false
+	}
+
+	var edits []scalarEdit
+
+	for i := range docs {
+		if !diffScalars(before[i], docs[i], &edits) {
+			return nil, false
+		}
+	}
+
+	edited, ok := applyEdits(src, edits)
+	if !ok {
+		return nil, false
+	}
+
+	after, err := decodeStream(yaml.NewDecoder(bytes.NewReader(edited)))
+	if err != nil || len(after) != len(docs) {
+		return nil, false
+	}
+
+	for i := range docs {
+		var rest []scalarEdit
+		if !diffScalars(after[i], docs[i], &rest) || len(rest) > 0 {
+			return nil, false
+		}
+	}
+
+	return edited, true
+}
+
+// diffScalars appends to edits every scalar of a whose value differs in b and
+// reports whether a and b otherwise have the same shape, styles and comments.
+func diffScalars(a, b *yaml.Node, edits *[]scalarEdit) bool {
+	if a.Kind != b.Kind || a.Style != b.Style || a.Tag != b.Tag || a.Anchor != b.Anchor ||
+		a.HeadComment != b.HeadComment || a.LineComment != b.LineComment || a.FootComment != b.FootComment ||
+		len(a.Content) != len(b.Content) {
+		return false
+	}
+
+	if a.Kind == yaml.AliasNode {
+		return a.Value == b.Value
+	}
+
+	if a.Value != b.Value {
+		if a.Kind != yaml.ScalarNode {
+			return false
+		}
+
+		*edits = append(*edits, scalarEdit{node: a, value: b.Value})
+	}
+
+	for i := range a.Content {
+		if !diffScalars(a.Content[i], b.Content[i], edits) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// applyEdits replaces the text of each edited scalar in src with its new
+// value, quoted like the scalar was. It fails when a scalar's text in src is
+// not its value in that quoting on a single line.
+func applyEdits(src []byte, edits []scalarEdit) ([]byte, bool) {
+	lines := strings.Split(string(src), "\n")
+
+	// Edit each line right to left, so that earlier columns stay valid.
+	slices.SortFunc(edits, func(a, b scalarEdit) int {
+		return cmp.Or(cmp.Compare(a.node.Line, b.node.Line), cmp.Compare(b.node.Column, a.node.Column))
+	})
+
+	for _, e := range edits {
+		i := e.node.Line - 1
+		if i < 0 || i >= len(lines) {
+			return nil, false
+		}
+
+		start := byteOffset(lines[i], e.node.Column)
+		if start < 0 || lines[i][start:scalarEnd(lines[i], start)] != quoteScalar(e.node.Value, e.node.Style) {
+			return nil, false
+		}
+
+		lines[i] = replaceScalarAt(lines[i], e.node.Column, e.node.Style, e.value)
+	}
+
+	return []byte(strings.Join(lines, "\n")), true
+}
@@ -0,0 +1,167 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const testLooseYAML = `# artifacthub: org/mychart
+apiVersion:   argoproj.io/v1alpha1   # extra spaces
+kind: Application
+metadata: {name: mychart, labels: {team: "platform"}}
+spec:
+    source:
+        chart: mychart
+        targetRevision: "1.2.3"  # pinned
+        helm:
+            values: |
+                image:
+                  tag: 1.2.3
+---
+kind: ConfigMap
+data:
+  note: 'éclair'
+`
+
+func decodeTestYAML(t *testing.T, src string) []*yaml.Node {
+	t.Helper()
+
+	docs, err := decodeStream(yaml.NewDecoder(strings.NewReader(src)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return docs
+}
+
+func TestEditInPlace(t *testing.T) {
+	docs := decodeTestYAML(t, testLooseYAML)
+	setTargetRevision(docs[0], "1.10.0")
+
+	got, ok := editInPlace([]byte(testLooseYAML), docs)
+	if !ok {
+		t.Fatal("editInPlace() ok = false, want true")
+	}
+
+	want := strings.Replace(testLooseYAML, `targetRevision: "1.2.3"`, `targetRevision: "1.10.0"`, 1)
+	if string(got) != want {
+		t.Errorf("editInPlace() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestEditInPlaceKeepsStyle(t *testing.T) {
+	tests := []struct {
+		name, src, version, want string
+	}{
+		{name: "plain", src: "spec:\n  source:\n    targetRevision: 1.2.3\n", version: "1.3.0", want: "spec:\n  source:\n    targetRevision: 1.3.0\n"},
+		{name: "single quoted", src: "spec:\n  source:\n    targetRevision: '1.2.3'\n", version: "1.3.0", want: "spec:\n  source:\n    targetRevision: '1.3.0'\n"},
+		// Quoting would change the scalar's style, which is left to a full re-encode.
+		{name: "plain needing quotes", src: "spec:\n  source:\n    targetRevision: v1.2.3\n", version: "1.10", want: ""},
+		{name: "flow mapping", src: "spec: {source: {targetRevision: 1.2.3, chart: c}}\n", version: "2.0.0", want: "spec: {source: {targetRevision: 2.0.0, chart: c}}\n"},
+		{name: "multibyte before", src: "spec: {source: {chart: café, targetRevision: 1.2.3}}\n", version: "2.0.0", want: "spec: {source: {chart: café, targetRevision: 2.0.0}}\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs := decodeTestYAML(t, tt.src)
+			set(docRoot(docs[0]), tt.version, "spec", "source", "targetRevision")
+
+			got, ok := editInPlace([]byte(tt.src), docs)
+			if ok != (tt.want != "") || string(got) != tt.want {
+				t.Errorf("editInPlace() = %q, %v, want %q", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestEditInPlaceFallsBack(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(docs []*yaml.Node) []*yaml.Node
+	}{
+		{name: "comment added", change: func(docs []*yaml.Node) []*yaml.Node {
+			docRoot(docs[0]).Content[0].HeadComment = "# last-checked: 2026-01-01"
+			return docs
+		}},
+		{name: "key added", change: func(docs []*yaml.Node) []*yaml.Node {
+			set(docRoot(docs[0]), "x", "spec", "source", "repoURL")
+			return docs
+		}},
+		{name: "documents reordered", change: func(docs []*yaml.Node) []*yaml.Node {
+			return []*yaml.Node{docs[1], docs[0]}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs := tt.change(decodeTestYAML(t, testLooseYAML))
+
+			if got, ok := editInPlace([]byte(testLooseYAML), docs); ok {
+				t.Errorf("editInPlace() = %q, want fallback", got)
+			}
+		})
+	}
+}
+
+func TestWriteYAMLDocumentsInPlace(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, testAppFile)
+
+	if err := os.WriteFile(path, []byte(testLooseYAML), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	docs, err := readYAMLDocuments(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	setTargetRevision(docs[0], "1.3.0")
+
+	if err := writeYAMLDocumentsInPlace(context.Background(), path, docs); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.Replace(testLooseYAML, `"1.2.3"`, `"1.3.0"`, 1)
+	if !bytes.Equal(got, []byte(want)) {
+		t.Errorf("file =\n%s\nwant\n%s", got, want)
+	}
+
+	// A comment the file did not have forces a full re-encode.
+	docRoot(docs[1]).Content[0].HeadComment = "# generated"
+
+	if err := writeYAMLDocumentsInPlace(context.Background(), path, docs); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err = os.ReadFile(path); err != nil || !strings.Contains(string(got), "# generated") {
+		t.Errorf("file after fallback = %q, %v, want it re-encoded with the new comment", got, err)
+	}
+}
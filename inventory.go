@@ -0,0 +1,60 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/BooleanCat/go-functional/v2/it"
+)
+
+// inventoryEntry is the JSON form of a discovered ChartInfo.
+type inventoryEntry struct {
+	File    string            `json:"file"`
+	Repo    string            `json:"repo"`
+	Timeout string            `json:"timeout,omitempty"` // Go duration, e.g. "30s"
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+// writeInventory writes the discovered charts to w as a JSON array, including
+// the annotations parsed for each. No charts yields an empty array.
+func writeInventory(w io.Writer, cfg Config, charts []ChartInfo) error {
+	entries := slices.Collect(it.Map(slices.Values(charts), func(c ChartInfo) inventoryEntry {
+		entry := inventoryEntry{File: chartPath(cfg, c), Repo: c.Repo, Timeout: "", Labels: c.Labels}
+		if c.Timeout > 0 {
+			entry.Timeout = c.Timeout.String()
+		}
+
+		return entry
+	}))
+
+	if entries == nil {
+		entries = []inventoryEntry{}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(entries); err != nil {
+		return fmt.Errorf("encode inventory: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestWriteInventoryMatchesDiscovery(t *testing.T) {
+	dir := t.TempDir()
+	createTestFiles(t, dir, map[string]string{
+		"cilium.yaml": "# artifacthub: cilium/cilium\n# artifacthub-timeout: 30s\nkind: Application\n" +
+			"metadata:\n  labels:\n    team: network\n",
+		"redis.yaml":  "# artifacthub: bitnami/redis\nkind: Application\n",
+		"secret.yaml": "kind: Secret\n",
+	})
+
+	cfg := Config{Dir: dir}
+
+	charts, err := MakeChartDiscoverer(os.Stat, os.ReadDir, readFirstArtifactHubApplication, anyFile, isValidPath, discardLog)(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := writeInventory(&buf, cfg, charts); err != nil {
+		t.Fatalf("writeInventory() error = %v", err)
+	}
+
+	var got []inventoryEntry
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, buf.String())
+	}
+
+	want := []inventoryEntry{
+		{File: filepath.Join(dir, "cilium.yaml"), Repo: "cilium/cilium", Timeout: "30s", Labels: map[string]string{"team": "network"}},
+		{File: filepath.Join(dir, "redis.yaml"), Repo: "bitnami/redis", Timeout: "", Labels: nil},
+	}
+
+	slices.SortFunc(got, func(a, b inventoryEntry) int { return strings.Compare(a.File, b.File) })
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("inventory = %+v, want %+v", got, want)
+	}
+
+	if len(got) != len(charts) {
+		t.Errorf("inventory has %d entries, discovery found %d", len(got), len(charts))
+	}
+}
+
+func TestWriteInventoryEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeInventory(&buf, Config{Dir: "."}, nil); err != nil {
+		t.Fatalf("writeInventory() error = %v", err)
+	}
+
+	if got := strings.TrimSpace(buf.String()); got != "[]" {
+		t.Errorf("writeInventory() = %q, want %q", got, "[]")
+	}
+}
@@ -0,0 +1,82 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// jsonIndent is the indentation of rewritten JSON manifests.
+const jsonIndent = "  "
+
+// isJSONFile reports whether path is a JSON manifest, judged by its extension.
+func isJSONFile(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".json")
+}
+
+// decodeJSONDocument parses a JSON manifest into a single document node, so it
+// goes through the same lookups and updates as a YAML manifest. JSON has no
+// comments, so its artifacthub repo comes from the chart-sources sidecar.
+// An empty file has no documents.
+func decodeJSONDocument(r io.Reader) ([]*yaml.Node, error) {
+	var v any
+	if err := json.NewDecoder(r).Decode(&v); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("decode json: %w", err)
+	}
+
+	var root yaml.Node
+	if err := root.Encode(v); err != nil {
+		return nil, fmt.Errorf("convert json: %w", err)
+	}
+
+	return []*yaml.Node{{Kind: yaml.DocumentNode, Content: []*yaml.Node{&root}}}, nil
+}
+
+// encodeJSONDocuments writes the single document of a JSON manifest as JSON
+// indented by two spaces. Object keys come out sorted, so the formatting is
+// stable from one run to the next whatever the original key order was.
+func encodeJSONDocuments(w io.Writer, docs []*yaml.Node) error {
+	if len(docs) != 1 {
+		return fmt.Errorf("encode json: a JSON manifest holds one document, got %d", len(docs))
+	}
+
+	var v any
+	if err := docs[0].Decode(&v); err != nil {
+		return fmt.Errorf("encode json: %w", err)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", jsonIndent)
+	enc.SetEscapeHTML(false)
+
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("encode json: %w", err)
+	}
+
+	return nil
+}
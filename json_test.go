@@ -0,0 +1,160 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const jsonTestApplication = `{
+    "kind": "Application",
+    "apiVersion": "argoproj.io/v1alpha1",
+    "metadata": {"name": "cilium", "labels": {"team": "net"}},
+    "spec": {
+        "source": {"chart": "cilium", "targetRevision": "1.15.0", "helm": {"values": "a: <b>"}},
+        "syncPolicy": {"automated": {"prune": true}, "retry": {"limit": 5}},
+        "ignoreDifferences": null
+    }
+}
+`
+
+func TestJSONManifestRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cilium.json")
+	if err := os.WriteFile(path, []byte(jsonTestApplication), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	docs, err := readYAMLDocuments(path)
+	if err != nil {
+		t.Fatalf("readYAMLDocuments() error = %v", err)
+	}
+
+	if got, ok := findCurrentVersion(docs); !ok || got != "1.15.0" {
+		t.Fatalf("findCurrentVersion() = %q, %v, want %q", got, ok, "1.15.0")
+	}
+
+	if got := metadataLabels(docs[0])["team"]; got != "net" {
+		t.Errorf("metadataLabels()[team] = %q, want %q", got, "net")
+	}
+
+	updateDocuments(docs, "1.16.2")
+
+	if err := writeYAMLDocuments(context.Background(), path, docs); err != nil {
+		t.Fatalf("writeYAMLDocuments() error = %v", err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{
+  "apiVersion": "argoproj.io/v1alpha1",
+  "kind": "Application",
+  "metadata": {
+    "labels": {
+      "team": "net"
+    },
+    "name": "cilium"
+  },
+  "spec": {
+    "ignoreDifferences": null,
+    "source": {
+      "chart": "cilium",
+      "helm": {
+        "values": "a: <b>"
+      },
+      "targetRevision": "1.16.2"
+    },
+    "syncPolicy": {
+      "automated": {
+        "prune": true
+      },
+      "retry": {
+        "limit": 5
+      }
+    }
+  }
+}
+`
+	if string(got) != want {
+		t.Errorf("written JSON =\n%s\nwant\n%s", got, want)
+	}
+
+	// A second run over the rewritten file is a no-op.
+	again, err := readYAMLDocuments(path)
+	if err != nil {
+		t.Fatalf("readYAMLDocuments() of written file error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := encodeJSONDocuments(&buf, again); err != nil {
+		t.Fatalf("encodeJSONDocuments() error = %v", err)
+	}
+
+	if buf.String() != want {
+		t.Errorf("re-encoded JSON =\n%s\nwant it unchanged", buf.String())
+	}
+}
+
+func TestDiscoverJSONManifestFromSidecar(t *testing.T) {
+	dir := t.TempDir()
+	createTestFiles(t, dir, map[string]string{
+		"cilium.json":    jsonTestApplication,
+		"configmap.json": `{"kind": "ConfigMap"}`,
+		chartSourcesFile: "cilium.json: cilium/cilium\nconfigmap.json: org/unused\n",
+	})
+
+	charts, err := MakeChartDiscoverer(os.Stat, os.ReadDir, readFirstArtifactHubApplication, anyFile, isValidPath, discardLog)(dir)
+	if err != nil {
+		t.Fatalf("discover error = %v", err)
+	}
+
+	if len(charts) != 1 || charts[0].File != "cilium.json" || charts[0].Repo != "cilium/cilium" {
+		t.Errorf("discovered %+v, want only cilium.json tracking cilium/cilium", charts)
+	}
+}
+
+func TestDecodeJSONDocument(t *testing.T) {
+	t.Run("empty file", func(t *testing.T) {
+		docs, err := decodeJSONDocument(strings.NewReader(""))
+		if err != nil || len(docs) != 0 {
+			t.Errorf("decodeJSONDocument() = %v, %v, want no documents", docs, err)
+		}
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		if _, err := decodeJSONDocument(strings.NewReader(`{"kind": `)); err == nil {
+			t.Error("decodeJSONDocument() error = nil, want error")
+		}
+	})
+}
+
+func TestEncodeJSONDocumentsRejectsStreams(t *testing.T) {
+	docs := []*yaml.Node{{Kind: yaml.DocumentNode}, {Kind: yaml.DocumentNode}}
+
+	if err := encodeJSONDocuments(&bytes.Buffer{}, docs); err == nil {
+		t.Error("encodeJSONDocuments() error = nil, want error for more than one document")
+	}
+}
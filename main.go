@@ -17,30 +17,48 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"slices"
+	"strings"
+	"syscall"
+	"text/tabwriter"
 	"time"
-
-	"github.com/BooleanCat/go-functional/v2/it"
 )
 
+// version is the release this binary was built from, set at build time with
+// -ldflags "-X main.version=<version>".
+var version = "dev" //nolint:gochecknoglobals // set via -ldflags
+
 func main() {
-	if err := run(os.Args, os.Getenv, os.Stderr); err != nil {
+	if err := run(os.Args, os.Getenv, os.Stdout, os.Stderr); err != nil {
 		fmt.Fprintln(os.Stderr, "❌", err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
 
-func run(args []string, getEnv func(string) string, stderr io.Writer) error {
+// run parses args and runs the program. Data meant for other tools, such as
+// JSON, diffs and file lists, goes to stdout; logs and summaries go to stderr,
+// so a pipeline can consume stdout while a person watches stderr.
+func run(args []string, getEnv func(string) string, stdout, stderr io.Writer) error {
 	programName := filepath.Base(args[0])
-	flags := args[1:]
 
-	cfg, err := ParseConfig(flags, getEnv)
+	cmd, flags := splitSubcommand(args[1:])
+	if cmd == cmdVersion {
+		return printVersion(stdout, programName)
+	}
+
+	cfg, sources, err := ParseConfigWithSources(flags, getEnv)
 	if err != nil {
 		if err.Error() == "help requested" {
 			printUsage(stderr, programName)
@@ -50,75 +68,686 @@ func run(args []string, getEnv func(string) string, stderr io.Writer) error {
 		return err
 	}
 
-	return runApp(cfg, stderr)
+	if cfg.ConfigPrint {
+		return printConfig(stdout, cfg, sources, cfg.WithSource)
+	}
+
+	return runApp(cfg, newVersionFetcher, time.Now, stdout, stderr)
+}
+
+// logLevel returns the level --quiet and --verbose select.
+func logLevel(cfg Config) LogLevel {
+	switch {
+	case cfg.Quiet:
+		return LogQuiet
+	case cfg.Verbose:
+		return LogVerbose
+	default:
+		return LogNormal
+	}
+}
+
+// printVersion writes the program name and the version it was built as.
+func printVersion(w io.Writer, exe string) error {
+	if _, err := fmt.Fprintf(w, "%s %s\n", exe, version); err != nil {
+		return fmt.Errorf("write version: %w", err)
+	}
+
+	return nil
 }
 
-func runApp(cfg Config, w io.Writer) error {
-	discover := MakeChartDiscoverer(os.Stat, os.ReadDir, readYAMLDocuments)
+func runApp(cfg Config, newFetcher FetcherFactory, now Clock, out, w io.Writer) error {
+	log := NewLogger(w, logLevel(cfg))
+
+	if cfg.SelfTest {
+		return runSelfTest(context.Background(), newFetcher(cfg, log.Debug), log)
+	}
+
+	if cfg.DumpResponse != "" {
+		client := newHTTPClient(cfg.MaxIdleConnsPerHost, cfg.IdleTimeout, requestTimeout(cfg))
+		return runDumpResponse(context.Background(), cfg.DumpResponse,
+			MakeArtifactHubResponseFetcher(artifactHubURL(cfg), client, cfg.ArtifactHubKey), out)
+	}
+
+	if cfg.Repo != "" {
+		return runQuery(context.Background(), cfg, newFetcher(cfg, log.Debug), log)
+	}
+
+	if cfg.Probe {
+		return runProbe(cfg, MakeDirProber(os.Stat, os.ReadDir), log)
+	}
+
+	inBase := PathChecker(isValidPath)
+	if cfg.AllowOutsideBase {
+		log.Warn("--allow-outside-base disables the path containment check; "+
+			"manifests resolving outside %s will be read and may be rewritten", cfg.Dir)
+
+		inBase = anyPath
+	}
+
+	keep := anyFile
+	if len(cfg.Include) > 0 || len(cfg.Exclude) > 0 {
+		keep = MakeGlobFilter(cfg.Include, cfg.Exclude)
+	}
+
+	discover := MakeChartDiscoverer(os.Stat, os.ReadDir, readFirstArtifactHubApplication, keep, inBase, log.Warn)
+
+	charts, err := discoverDirs(discover, cfg.Dir, log)
+	if err != nil {
+		return err
+	}
 
-	charts, err := discover(cfg.Dir)
+	values, err := discoverValuesFiles(MakeValuesDiscoverer(readYAMLDocuments), cfg.ValuesFiles)
 	if err != nil {
 		return err
 	}
 
+	charts = append(charts, values...)
+
+	if cfg.DiscoverJSON {
+		charts = filterByChartName(charts, cfg.ChartName)
+		if err := writeInventory(out, cfg, charts); err != nil {
+			return err
+		}
+
+		log.Info("%s discovered", plural(len(charts), "chart"))
+
+		return nil
+	}
+
 	if len(charts) == 0 {
-		return fmt.Errorf("no charts with artifacthub comments found in %s", cfg.Dir)
+		scan, scanErr := scanDirs(os.ReadDir, readYAMLDocuments, cfg.Dir)
+		if scanErr != nil {
+			return fmt.Errorf("no charts with artifacthub comments found in %s", cfg.Dir)
+		}
+
+		return noChartsError(cfg.Dir, scan)
+	}
+
+	charts = filterByChartName(charts, cfg.ChartName)
+	if len(charts) == 0 {
+		return fmt.Errorf("no charts named %q found in %s", cfg.ChartName, cfg.Dir)
+	}
+
+	if cfg.CheckConsistency {
+		warnDivergentPins(log, findDivergentPins(readYAMLDocuments, cfg, charts))
 	}
 
+	if cfg.CheckChartName {
+		if err := checkChartNames(log, findChartNameMismatches(readYAMLDocuments, cfg, charts)); err != nil {
+			return err
+		}
+	}
+
+	cfg = applyFreeze(cfg, now(), log)
+
 	if cfg.CheckOnly {
-		runCheck(charts, w)
-		return nil
+		return newReporter(cfg, out, log).Checked(charts)
 	}
 
-	return runUpdate(cfg, charts, w)
+	return runUpdate(cfg, charts, newFetcher(cfg, log.Debug), now, out, log)
 }
 
-func runCheck(charts []ChartInfo, w io.Writer) {
-	logwf(w, "discovered %d chart(s) with artifacthub comments:", len(charts))
-	ForEach(slices.Values(charts), func(c ChartInfo) {
-		logwf(w, "  %s → %s", c.File, c.Repo)
+// runProbe checks that every directory cfg.Dir names is readable.
+func runProbe(cfg Config, probe func(string) error, log *Logger) error {
+	_, err := discoverDirs(func(dir string) ([]ChartInfo, error) {
+		return nil, probe(dir)
+	}, cfg.Dir, log)
+	if err != nil {
+		return err
+	}
+
+	log.Info("%s is readable", cfg.Dir)
+
+	return nil
+}
+
+// applyFreeze switches to check-only mode while now is inside the change
+// freeze configured by --freeze-until, whatever other mode was requested.
+func applyFreeze(cfg Config, now time.Time, log *Logger) Config {
+	if cfg.FreezeUntil.IsZero() || !now.Before(cfg.FreezeUntil) {
+		return cfg
+	}
+
+	log.Info("change freeze in effect until %s: running in check-only mode, no files will be changed",
+		cfg.FreezeUntil.Format(time.RFC3339))
+
+	cfg.CheckOnly = true
+	cfg.DryRun = false
+
+	return cfg
+}
+
+// runDumpResponse writes the ArtifactHub response for repo to w as indented
+// JSON, without selecting a version or touching any files.
+func runDumpResponse(ctx context.Context, repo string, fetch ResponseFetcher, w io.Writer) error {
+	body, err := fetch(ctx, repo)
+	if err != nil {
+		return fmt.Errorf("%s: %w", repo, err)
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, body, "", "  "); err != nil {
+		return fmt.Errorf("%s: %w: %w", repo, errDecodeResponse, err)
+	}
+
+	out.WriteByte('\n')
+
+	if _, err := out.WriteTo(w); err != nil {
+		return fmt.Errorf("write response: %w", err)
+	}
+
+	return nil
+}
+
+// runQuery resolves the latest version of a single repository without scanning any files.
+func runQuery(ctx context.Context, cfg Config, fetch VersionFetcher, log *Logger) error {
+	info, err := fetch(ctx, VersionQuery{
+		Repo:    cfg.Repo,
+		Current: cfg.Current,
+		Timeout: 0,
+		Limit:   cfg.FetchLimit,
+
+		PrereleaseSameMajor: cfg.PrereleaseSameMajor,
+		Stability:           cfg.StableRule,
+		RequireSigned:       cfg.RequireSigned,
+
+		Source:          "",
+		AllowPrerelease: false,
+		Constraint:      "",
+		Ignore:          nil,
 	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", cfg.Repo, err)
+	}
+
+	latest := info.Version
+
+	switch {
+	case cfg.Current == "":
+		log.Info("%s: latest %s", cfg.Repo, latest)
+	case isPartialPin(cfg.Current):
+		log.Info("%s: %s resolves to %s", cfg.Repo, cfg.Current, latest)
+	case versionLess(cfg.Current, latest):
+		log.Notice("%s: %s → %s (update available)", cfg.Repo, cfg.Current, latest)
+	default:
+		log.Info("%s: already up to date (%s)", cfg.Repo, cfg.Current)
+	}
+
+	logReleaseAge(log, latest, info.ReleasedAt, time.Now())
+
+	if cfg.ExplainVersion {
+		logExplanation(log, cfg.Repo, latest, info.Selection)
+	}
+
+	return nil
 }
 
-func runUpdate(cfg Config, charts []ChartInfo, w io.Writer) error {
-	const (
-		apiURL            = "https://artifacthub.io/api/v1/packages/helm"
-		httpClientTimeout = 60 * time.Second
+// runSelfTest verifies that ArtifactHub is reachable and that a well-known
+// repository resolves to a plausible version. It never touches any files.
+func runSelfTest(ctx context.Context, fetch VersionFetcher, log *Logger) error {
+	const selfTestRepo = "cilium/cilium"
+
+	info, err := fetch(ctx, VersionQuery{Repo: selfTestRepo, Current: "", Timeout: 0, Limit: 0, PrereleaseSameMajor: false, Stability: StabilityDash, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil})
+	if err != nil {
+		return fmt.Errorf("self-test failed: %s: %w", selfTestRepo, err)
+	}
+
+	if !isPlausibleVersion(info.Version) {
+		return fmt.Errorf("self-test failed: %s resolved to implausible version %q", selfTestRepo, info.Version)
+	}
+
+	log.Info("self-test passed: %s latest %s", selfTestRepo, info.Version)
+
+	return nil
+}
+
+// logReleaseAge prints how long ago version was released with --verbose.
+// Nothing is printed when the source did not date the release.
+func logReleaseAge(log *Logger, version string, releasedAt, now time.Time) {
+	if version == "" || releasedAt.IsZero() {
+		return
+	}
+
+	log.Debug("  %s released %s", version, relativeTime(now, releasedAt))
+}
+
+// logExplanation prints the candidates considered for a repo, why each rejected
+// one was filtered out, and the version that was finally selected.
+func logExplanation(log *Logger, repo, selected string, sel Selection) {
+	log.Info("%s: considered %d version(s)", repo, len(sel.Candidates))
+	ForEach(slices.Values(sel.Rejected), func(r Rejection) {
+		log.Info("  %s rejected: %s", r.Version, r.Reason)
+	})
+	log.Info("  selected %s", selected)
+}
+
+const (
+	artifactHubAPIURL = "https://artifacthub.io/api/v1/packages/helm"
+	httpClientTimeout = 60 * time.Second
+)
+
+// requestTimeout returns the overall timeout of each request: --timeout when
+// set, httpClientTimeout otherwise.
+func requestTimeout(cfg Config) time.Duration {
+	if cfg.HTTPTimeout > 0 {
+		return cfg.HTTPTimeout
+	}
+
+	return httpClientTimeout
+}
+
+// artifactHubURL returns the ArtifactHub API base URL for cfg: --api-url or
+// $ARTIFACTHUB_API_URL when set, the public endpoint otherwise.
+func artifactHubURL(cfg Config) string {
+	if cfg.APIURL != "" {
+		return strings.TrimSuffix(cfg.APIURL, "/")
+	}
+
+	return artifactHubAPIURL
+}
+
+// newVersionFetcher builds the fetcher for a run, dispatching each chart to
+// ArtifactHub or, for "# github:" charts, to the GitHub releases API
+// authenticated with $GITHUB_TOKEN when it is set, for "# helmrepo:" charts
+// to the repository's index.yaml and for "# oci:" charts to the registry's
+// tag list.
+func newVersionFetcher(cfg Config, debug LogFunc) VersionFetcher {
+	client := newHTTPClient(cfg.MaxIdleConnsPerHost, cfg.IdleTimeout, requestTimeout(cfg))
+
+	var budget *RequestBudget
+	if cfg.MaxRequests > 0 {
+		budget = NewRequestBudget(cfg.MaxRequests)
+	}
+
+	limiter := NewHostLimiter(cfg.MaxPerHost)
+	limit := func(fetcher VersionFetcher, host QueryHost) VersionFetcher {
+		// Innermost, so that every retry counts as a request.
+		if budget != nil {
+			fetcher = MakeBudgetedFetcher(fetcher, budget)
+		}
+
+		if cfg.MaxPerHost > 0 {
+			fetcher = MakeHostLimitedFetcher(fetcher, limiter, host)
+		}
+
+		return fetcher
+	}
+
+	fetcher := MakeSourceFetcher(
+		limit(MakeArtifactHubFetcher(artifactHubURL(cfg), client, cfg.ArtifactHubKey, debug), fixedHost(artifactHubURL(cfg))),
+		limit(MakeGitHubReleasesFetcher(gitHubAPIURL, client, os.Getenv(gitHubTokenEnvVar)), fixedHost(gitHubAPIURL)),
+		// Helm repositories and OCI registries live on many hosts, each
+		// limited on its own.
+		limit(MakeHelmRepoFetcher(client), helmRepoHost),
+		limit(MakeOCIFetcher(client, "https"), ociHost),
 	)
+	fetcher = MakeRetryingFetcher(fetcher, defaultFetchAttempts)
+
+	if len(cfg.RepoMap) > 0 {
+		fetcher = MakeRepoMappingFetcher(fetcher, cfg.RepoMap)
+	}
+
+	return fetcher
+}
+
+// newHTTPClient returns a client whose transport keeps up to maxIdlePerHost
+// idle connections per host (defaultMaxIdleConnsPerHost when 0) and attempts
+// HTTP/2, so concurrent fetches against ArtifactHub share a few connections
+// instead of dialling a new one for most requests.
+//
+// A positive idleTimeout bounds the wait for response headers and every read
+// from the connection, so a response that stalls mid-body fails with a timeout
+// error, which the retrying fetcher retries, instead of hanging until timeout.
+func newHTTPClient(maxIdlePerHost int, idleTimeout, timeout time.Duration) *http.Client {
+	if maxIdlePerHost == 0 {
+		maxIdlePerHost = defaultMaxIdleConnsPerHost
+	}
+
+	transport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return &http.Client{Timeout: timeout}
+	}
+
+	transport = transport.Clone()
+	transport.MaxIdleConnsPerHost = maxIdlePerHost
+	transport.MaxIdleConns = max(transport.MaxIdleConns, maxIdlePerHost)
+	transport.ForceAttemptHTTP2 = true
+
+	if idleTimeout > 0 {
+		transport.ResponseHeaderTimeout = idleTimeout
+		transport.DialContext = dialWithReadDeadline(idleTimeout)
+	}
+
+	return &http.Client{Transport: transport, Timeout: timeout}
+}
+
+// dialWithReadDeadline returns a dialer whose connections fail any read that
+// waits longer than idle for data.
+func dialWithReadDeadline(idle time.Duration) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: httpClientTimeout, KeepAlive: httpClientTimeout}
 
-	client := &http.Client{Timeout: httpClientTimeout}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, fmt.Errorf("dial %s: %w", addr, err)
+		}
+
+		return &idleConn{Conn: conn, idle: idle}, nil
+	}
+}
 
-	fetcher := MakeArtifactHubFetcher(apiURL, client)
+// idleConn is a net.Conn whose every Read must make progress within idle.
+type idleConn struct {
+	net.Conn
+
+	idle time.Duration
+}
+
+func (c *idleConn) Read(b []byte) (int, error) {
+	if err := c.Conn.SetReadDeadline(time.Now().Add(c.idle)); err != nil {
+		return 0, fmt.Errorf("set read deadline: %w", err)
+	}
+
+	return c.Conn.Read(b) //nolint:wrapcheck // io.EOF must reach the transport unwrapped
+}
+
+// hostOf returns the host component of rawURL, or rawURL itself if it cannot be parsed.
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+
+	return u.Host
+}
+
+func runUpdate(
+	cfg Config, charts []ChartInfo, fetch VersionFetcher, now Clock, out io.Writer, log *Logger,
+) (err error) {
+	completed := false
+	fetcher := MakeCachingFetcher(fetch)
+
+	writer, patch, err := selectWriter(cfg, out)
+	if err != nil {
+		return err
+	}
 
-	var writer YAMLWriter = writeYAMLDocuments
-	if cfg.DryRun {
-		writer = showDiffInternal
+	if patch != nil {
+		defer closeFile(patch, &err)
 	}
 
-	updater := MakeChartUpdater(cfg, readYAMLDocuments, fetcher, writer)
+	updater := MakeChartUpdater(cfg, readYAMLDocuments, fetcher, serializedWriter(writer), now, log.Debug)
 
-	ctx := context.Background()
+	// An interrupt cancels in-flight requests instead of killing the process
+	// part way through a write.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// Pipeline: Iterate -> Map(process) -> ForEach(log)
-	process := func(c ChartInfo) UpdateResult {
-		return updater(ctx, c.File, c.Repo)
+	process := serializedByPath(cfg, func(c ChartInfo) UpdateResult {
+		return updater(ctx, c)
+	})
+
+	if cfg.Resume {
+		checkpoint, openErr := OpenCheckpoint(checkpointFile)
+		if openErr != nil {
+			return openErr
+		}
+
+		defer func() {
+			err = errors.Join(err, finishCheckpoint(checkpoint, completed))
+		}()
+
+		if pending := checkpoint.Pending(cfg, charts); len(pending) < len(charts) {
+			log.Info("resuming: skipping %s already processed", plural(len(charts)-len(pending), "chart"))
+			charts = pending
+		}
+
+		process = checkpointed(cfg, checkpoint, process, log)
+	}
+
+	results := NewResults()
+	report := newReporter(cfg, out, log)
+
+	progress := func(done, total int) {
+		log.Info("batch complete: %d of %d charts processed", done, total)
+	}
+
+	workers := cfg.Concurrency
+	if workers == 0 {
+		workers = defaultConcurrency
 	}
 
-	return ForEachWithError(it.Map(slices.Values(charts), process), func(result UpdateResult) error {
-		return logResult(result, w)
+	err = processBatches(ctx, charts, cfg.BatchSize, workers, process, func(result UpdateResult) error {
+		results.Add(result)
+
+		if cfg.ExplainVersion && result.Latest != "" {
+			logExplanation(log, result.Repo, result.Latest, result.Selection)
+		}
+
+		reportErr := report.Result(result)
+
+		if result.Error != nil && !failsOn(cfg, FailOnError) {
+			log.Error("%s: %v", result.File, result.Error)
+			return nil
+		}
+
+		if reportErr != nil {
+			return reportErr
+		}
+
+		logReleaseAge(log, result.Latest, result.LatestReleasedAt, now())
+
+		return nil
+	}, progress)
+	completed = err == nil
+
+	if err == nil {
+		err = pendingChangesError(cfg, results)
+	}
+
+	if err == nil {
+		err = failOnResults(cfg, results)
+	}
+
+	if finishErr := finishRun(ctx, cfg, charts, results, report, out, log, now); finishErr != nil {
+		err = errors.Join(err, finishErr)
+	}
+
+	return err
+}
+
+// selectWriter returns the YAMLWriter cfg asks for. With --patch-out it also
+// returns the patch file, which the caller must close.
+func selectWriter(cfg Config, out io.Writer) (YAMLWriter, *os.File, error) {
+	switch {
+	case cfg.DryRun && (cfg.Compact || (cfg.Output == OutputJSON && cfg.PatchOut == "")):
+		return discardYAML, nil, nil
+	case cfg.DryRun && cfg.PatchOut != "":
+		//nolint:gosec // patch path is supplied by the user on the command line
+		patch, err := os.Create(cfg.PatchOut)
+		if err != nil {
+			return nil, nil, fmt.Errorf("create patch file: %w", err)
+		}
+
+		return MakePatchWriter(patch, cfg.PreserveFormat), patch, nil
+	case cfg.VerifyWrites && !cfg.DryRun:
+		return MakeVerifyingYAMLWriter(cfg.PreserveFormat), nil, nil
+	case cfg.DryRun && cfg.Suggest:
+		return MakeSuggestionWriter(out), nil, nil
+	case cfg.DryRun && cfg.DiffBase != "":
+		return MakeBaseRefDiffWriter(cfg.DiffBase, out, cfg.PreserveFormat), nil, nil
+	case cfg.DryRun && cfg.DiffMode == DiffModeBuiltin:
+		return MakeBuiltinDiffWriter(out, cfg.PreserveFormat), nil, nil
+	case cfg.DryRun:
+		return MakeDiffWriter(out, cfg.PreserveFormat), nil, nil
+	case cfg.PreserveFormat:
+		return writeYAMLDocumentsInPlace, nil, nil
+	default:
+		return writeYAMLDocuments, nil, nil
+	}
+}
+
+// finishRun runs the steps that follow processing: reports, summaries,
+// changed files, changelog, commit and history. Every step runs even if an
+// earlier one fails, and their errors are joined.
+func finishRun(
+	ctx context.Context, cfg Config, charts []ChartInfo, results *Results,
+	report Reporter, out io.Writer, log *Logger, now Clock,
+) error {
+	var errs []error
+
+	if cfg.PrintEffective {
+		printEffectiveVersions(log, results, !cfg.DryRun)
+	}
+
+	errs = append(errs, report.Finish())
+
+	if cfg.Compact {
+		errs = append(errs, printDeltas(out, results))
+	}
+
+	errs = append(errs, printSummary(log, cfg.SummaryFormat, summarize(results)))
+
+	if cfg.ChangedFiles != "" {
+		errs = append(errs, writeChangedFiles(cfg.ChangedFiles, changedFiles(cfg, charts, results), out))
+	}
+
+	if cfg.Changelog != "" {
+		errs = append(errs, updateChangelog(cfg.Changelog, results))
+	}
+
+	if cfg.Report != "" {
+		errs = append(errs, writeReport(cfg, results, out))
+	}
+
+	if cfg.Commit {
+		errs = append(errs, commitUpdates(ctx, cfg, charts, results, gitCommit))
+	}
+
+	if cfg.History != "" {
+		errs = append(errs, appendHistory(cfg.History, now(), results))
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkpointed wraps process so that every chart processed without an error is
+// recorded in checkpoint; charts that failed are left to be retried on resume.
+func checkpointed(cfg Config, checkpoint *Checkpoint, process func(ChartInfo) UpdateResult, log *Logger) func(ChartInfo) UpdateResult {
+	return func(c ChartInfo) UpdateResult {
+		result := process(c)
+		if result.Error == nil {
+			if err := checkpoint.Record(cfg, c); err != nil {
+				log.Warn("%v", err)
+			}
+		}
+
+		return result
+	}
+}
+
+// finishCheckpoint removes the checkpoint once every chart has been processed
+// and keeps it for --resume otherwise.
+func finishCheckpoint(checkpoint *Checkpoint, completed bool) error {
+	if completed {
+		return checkpoint.Clear()
+	}
+
+	return checkpoint.Close()
+}
+
+// processBatches passes each chart through process and its result to handle,
+// a batch of size charts at a time (all at once when size is 0), reporting
+// progress after every batch but the last. Within a batch, up to workers
+// charts are processed at once, but results reach handle in input order. It
+// stops at the first error from handle.
+func processBatches(
+	ctx context.Context,
+	charts []ChartInfo,
+	size, workers int,
+	process func(ChartInfo) UpdateResult,
+	handle func(UpdateResult) error,
+	progress func(done, total int),
+) error {
+	if size <= 0 {
+		size = max(len(charts), 1)
+	}
+
+	done := 0
+
+	for batch := range slices.Chunk(charts, size) {
+		if err := ForEachWithError(processConcurrently(ctx, batch, workers, process), handle); err != nil {
+			return err
+		}
+
+		done += len(batch)
+		if done < len(charts) {
+			progress(done, len(charts))
+		}
+	}
+
+	return nil
+}
+
+// printDeltas writes a "file: repo current → latest" line for every chart
+// that would be updated, in processing order.
+func printDeltas(w io.Writer, results *Results) error {
+	for _, r := range results.WithStatus(StatusUpdated) {
+		if _, err := fmt.Fprintf(w, "%s: %s %s → %s\n", r.File, r.Repo, r.Current, r.Latest); err != nil {
+			return fmt.Errorf("write deltas: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// printEffectiveVersions prints every processed chart with the version its
+// manifest pins after the run. Updates only count as applied outside dry-run.
+func printEffectiveVersions(w io.Writer, results *Results, applied bool) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	_, _ = fmt.Fprintln(tw, "FILE\tREPO\tVERSION\tSTATUS")
+	ForEach(slices.Values(results.All()), func(r UpdateResult) {
+		version := r.Current
+		if applied && r.Status == StatusUpdated {
+			version = r.Latest
+		}
+
+		if version == "" {
+			version = "-"
+		}
+
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.File, r.Repo, version, r.Status)
 	})
+
+	_ = tw.Flush()
 }
 
-func logResult(r UpdateResult, w io.Writer) error {
+func logResult(r UpdateResult, log *Logger) error {
 	if r.Error != nil {
 		return r.Error
 	}
 
 	switch r.Status {
 	case StatusUpdated:
-		logwf(w, "%s: %s → %s", r.File, r.Current, r.Latest)
+		log.Notice("%s: %s → %s", r.File, r.Current, r.Latest)
 	case StatusUpToDate:
-		logwf(w, "%s: already up to date (%s)", r.File, r.Current)
+		if isPartialPin(r.Current) {
+			log.Info("%s: already up to date (%s, resolves to %s)", r.File, r.Current, r.Latest)
+			break
+		}
+
+		log.Info("%s: already up to date (%s)", r.File, r.Current)
+	case StatusSkipped:
+		log.Info("%s: skipped (%s)", r.File, r.Reason)
+	case StatusBlocked:
+		log.Info("%s: blocked (%s)", r.File, r.Reason)
+	case StatusHeld:
+		log.Info("%s: held (%s)", r.File, r.Reason)
+	case StatusPinned:
+		log.Info("%s: pinned at %s", r.File, r.Current)
 	case StatusError:
 		if r.Error != nil {
 			return r.Error
@@ -127,12 +756,16 @@ func logResult(r UpdateResult, w io.Writer) error {
 		return fmt.Errorf("%s: unknown error", r.File)
 	}
 
+	for _, warning := range r.Warnings {
+		log.Warn("%s: %s", r.File, warning)
+	}
+
 	return nil
 }
 
 func printUsage(w io.Writer, exe string) {
 	_, _ = fmt.Fprintf(w, `Usage:
-  %s [flags]
+  %s [command] [flags]
 
 Description:
   Updates Argo CD Application Helm chart versions by scanning for manifests
@@ -142,24 +775,180 @@ Description:
 License:
   GNU GPL v3.0 only - https://spdx.org/licenses/GPL-3.0-only.html
 
+Commands:
+  update              Update chart versions (the default without a command)
+  check               Same as --check
+  discover            Same as --discover-json
+  version             Print the version and exit
+
 Flags:
-  -d, --dir <path>    Path to argoapps directory (default: %s)
+  -d, --dir <path>    Path to argoapps directory, or a glob matching several
+                      (default: %s)
+      --config <path> Read settings from a YAML config file (flags and env win;
+                      default: .chart-version-updater.yaml, if present)
+      --profile <name>
+                      Merge the named profile of the config file over its base
   -n, --dry-run       Show git diff without modifying files
+      --fail-on <list>
+                      Outcomes that cause a non-zero exit: error, outdated,
+                      deprecated, downgrade, skipped (default: error)
+      --dry-run-exit-code <n>
+                      With --dry-run, exit with code <n> if any chart would change
+      --exit-code     Dry run exiting with code 2 if any chart would change,
+                      for drift checks in CI; not with --dry-run-exit-code
+      --compact       With --dry-run, print "file: repo current → latest" per
+                      chart to stdout instead of diffs
+      --suggest       With --dry-run, print GitHub suggestion blocks instead of a diff
+      --diff-base <ref>
+                      With --dry-run, diff against each file at git revision <ref>
+      --diff-mode <mode>
+                      With --dry-run, git (default) to diff with git diff, or
+                      builtin to compute the diff without running git
+      --patch-out <file>
+                      With --dry-run, write all changes to <file> as a single
+                      patch for git apply instead of printing diffs
   -C, --check         Discover charts and show what would be updated
+  -r, --repo <repo>   Query the latest version of a single org/chart repository
+      --version <ver> Current version to compare against (requires --repo)
+      --dump-response <repo>
+                      Print the raw ArtifactHub JSON for an org/chart and exit
+      --print-effective-versions
+                      Print a table of every chart and its version after the run
+      --map-repo <old=new>
+                      Look up charts whose org or org/chart moved under the new
+                      name (repeatable)
+      --rewrite-moved With --map-repo, also rewrite the comment of updated files
+      --chart <name>  Only process charts with this name (the part after "/"), in any org
+      --include <globs>
+                      Only scan manifests whose file name matches one of these
+                      patterns; comma-separated and repeatable
+      --exclude <globs>
+                      Skip manifests whose file name matches one of these
+                      patterns, even if --include matches; repeatable
+      --only-kind <kind>
+                      Only update resources of this kind (supported: Application, ApplicationSet)
+      --freeze-until <time>
+                      Only check, never update, until this RFC3339 instant
+      --changed-files <path>
+                      Write the files that were changed, one per line ("-" for stdout)
+      --stamp-checked Record a "# last-checked:" comment in every checked file
+      --values-file <paths>
+                      Also update versions annotated in these Helm values files
+                      (comma-separated, repeatable)
+      --sort-docs     Order the documents of rewritten files by kind
+      --verify-writes Re-read every written file and keep the original if it no
+                      longer parses or has the wrong targetRevision
+      --preserve-format
+                      Change only the version text of rewritten files, keeping
+                      all other formatting and comments byte-identical
+      --check-chart-name
+                      Fail when spec.source.chart differs from the chart in the
+                      artifacthub comment
+      --check-consistency
+                      Warn when manifests pin the same chart to different versions
+      --never-downgrade
+                      Report charts pinned above the latest version as blocked
+                      and never write them
+      --max-bump <level>
+                      Only update within this segment of the current version:
+                      major, minor or patch; charts with only larger updates
+                      are reported as held
+      --history <csv> Append a row per chart to a CSV history log
+      --summary-format <template>
+                      Go template for the final summary line, with the counts
+                      .Updated, .UpToDate, .Errors, .Skipped, .Blocked, .Held
+                      and .Pinned
+      --changelog <path>
+                      Add a "Bump org/chart from X to Y" line per update to the
+                      Unreleased section of a Markdown changelog
+      --report markdown
+                      After the run, write a Markdown table of the updated charts
+                      for a pull request body
+      --report-file <path>
+                      Write the --report to <path> instead of stdout
+      --api-url <url> ArtifactHub API base URL, for a self-hosted instance or
+                      a proxy (default: the public artifacthub.io endpoint)
+      --commit        Commit each rewritten manifest with git as
+                      "chore(deps): bump org/chart X → Y"
+      --commit-mode <mode>
+                      per-chart (default) for a commit per manifest, or single
+                      for one commit covering the whole run
+      --opt-out-label <key>
+                      Skip Applications labeled or annotated <key>: disabled
+                      (default: %s)
+      --stable-rule <rule>
+                      How pre-releases are recognized: dash (default),
+                      semver-prerelease or none
+      --prerelease-within-current-major
+                      Accept pre-releases that share the current major version
+      --require-signed
+                      Only accept versions ArtifactHub marks as signed; fails
+                      when the response has no signature data
+      --explain-version
+                      Show which versions were considered and why one was chosen
+      --skip-unreachable
+                      Report charts whose repo cannot be fetched as skipped
+      --fetch-limit <n>
+                      Request at most <n> versions per chart (0 = unlimited)
+      --max-requests <n>
+                      Make at most <n> ArtifactHub requests, retries included;
+                      charts left over are reported as skipped (0 = no limit)
+      --resume        Record progress in .chartupdater.progress and skip the
+                      charts an interrupted --resume run already processed
+      --batch-size <n>
+                      Process charts <n> at a time, reporting progress between
+                      batches (0 = all at once)
+      --output <format>
+                      Result format: text (default) or json, a JSON array on
+                      stdout of updated charts, or of discovered ones with --check
+      --concurrency <n>
+                      Process up to <n> charts at once; results are still
+                      reported in discovery order (default: 4)
+      --max-per-host <n>
+                      Limit concurrent requests to a single API host (0 = unlimited)
+      --timeout <duration>
+                      Overall timeout of each request, e.g. 30s (default: 60s)
+      --idle-timeout <duration>
+                      Abort and retry a request whose response stalls for this
+                      long, e.g. 10s (0 = wait for the overall --timeout)
+      --max-idle-conns-per-host <n>
+                      Keep up to <n> idle connections per API host for reuse
+                      (default 16)
+      --allow-outside-base
+                      Keep manifests that resolve outside --dir instead of
+                      dropping them (disables a security check; warns each run)
+      --config-print  Print the resolved configuration and exit
+      --with-source   With --config-print, show whether each setting came from
+                      a default, the config file, the environment or a flag
+      --discover-json Print the discovered charts and their annotations as JSON
+                      and exit, without contacting ArtifactHub
+      --probe         Only check that the directory exists and is readable
+      --selftest      Check that ArtifactHub is reachable and responses parse
+  -v, --verbose       Print extra detail per chart, such as the age of its latest
+                      release, the ArtifactHub URLs requested, the candidate
+                      versions and why each chart was or was not updated
+  -q, --quiet         Only print updated charts, warnings and errors, then the
+                      summary
   -h, --help          Show this help message
+  @<file>             Read additional whitespace-separated arguments from a file
 
 Environment:
   %s    Directory path (used if --dir is not provided)
+  ARTIFACTHUB_API_URL   ArtifactHub API base URL (used if --api-url is not provided)
 
 Exit codes:
   0  Success
   1  Error
+  2  Changes pending with --exit-code
+  n  Changes pending in a dry run with --dry-run-exit-code <n>
 
 Examples:
   %s
   %s --dir ./my-apps
   %s --dry-run
+  %s check --dir ./my-apps
   %s=./my-apps %s --check
+  %s --repo cilium/cilium --version 1.16.0
 
-`, exe, defaultArgoAppsDir, argoAppsDirEnvVar, exe, exe, exe, argoAppsDirEnvVar, exe)
+`, exe, defaultArgoAppsDir, defaultOptOutLabel, argoAppsDirEnvVar, exe, exe, exe, exe, argoAppsDirEnvVar, exe, exe)
 }
@@ -0,0 +1,1083 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		current string
+		latest  string
+		fetch   error
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "latest only",
+			current: "",
+			latest:  "1.2.0",
+			fetch:   nil,
+			want:    "▶ org/chart: latest 1.2.0\n",
+			wantErr: false,
+		},
+		{
+			name:    "current is behind",
+			current: "1.0.0",
+			latest:  "1.2.0",
+			fetch:   nil,
+			want:    "▶ org/chart: 1.0.0 → 1.2.0 (update available)\n",
+			wantErr: false,
+		},
+		{
+			name:    "current is up to date",
+			current: "1.2.0",
+			latest:  "1.2.0",
+			fetch:   nil,
+			want:    "▶ org/chart: already up to date (1.2.0)\n",
+			wantErr: false,
+		},
+		{
+			name:    "minor-line pin resolves",
+			current: "1.2",
+			latest:  "1.2.5",
+			fetch:   nil,
+			want:    "▶ org/chart: 1.2 resolves to 1.2.5\n",
+			wantErr: false,
+		},
+		{
+			name:    "fetch error",
+			current: "",
+			latest:  "",
+			fetch:   errors.New("fetch failed"),
+			want:    "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotRepo string
+
+			fetch := func(_ context.Context, q VersionQuery) (VersionInfo, error) {
+				gotRepo = q.Repo
+				return versionInfo(tt.latest), tt.fetch
+			}
+
+			cfg := Config{Dir: defaultArgoAppsDir, DryRun: false, CheckOnly: false, Repo: "org/chart", Current: tt.current}
+
+			var buf bytes.Buffer
+
+			err := runQuery(context.Background(), cfg, fetch, NewLogger(&buf, LogNormal))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if gotRepo != "org/chart" {
+				t.Errorf("runQuery() fetched %q, want %q", gotRepo, "org/chart")
+			}
+
+			if got := buf.String(); got != tt.want {
+				t.Errorf("runQuery() output = %q, want %q", got, tt.want)
+			}
+
+			if tt.wantErr && !strings.Contains(err.Error(), "org/chart") {
+				t.Errorf("runQuery() error = %q, want it to mention the repo", err.Error())
+			}
+		})
+	}
+}
+
+func TestLogResultSkipped(t *testing.T) {
+	var buf bytes.Buffer
+
+	r := newSkippedResult("app.yaml", "org/chart", "1.0.0", "connection refused")
+	if err := logResult(r, NewLogger(&buf, LogNormal)); err != nil {
+		t.Fatalf("logResult() error = %v, want nil for skipped result", err)
+	}
+
+	want := "▶ app.yaml: skipped (connection refused)\n"
+	if got := buf.String(); got != want {
+		t.Errorf("logResult() output = %q, want %q", got, want)
+	}
+}
+
+func TestLogResultWarnings(t *testing.T) {
+	var buf bytes.Buffer
+
+	r := UpdateResult{
+		File: "app.yaml", Repo: "org/chart", Current: "1.0.0", Latest: "1.0.0", Status: StatusUpToDate,
+		Warnings: []string{deprecatedWarning},
+	}
+	if err := logResult(r, NewLogger(&buf, LogNormal)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "▶ app.yaml: already up to date (1.0.0)\n▶ warning: app.yaml: chart is deprecated by its publisher\n"
+	if got := buf.String(); got != want {
+		t.Errorf("logResult() output = %q, want %q", got, want)
+	}
+}
+
+func TestLogExplanation(t *testing.T) {
+	var buf bytes.Buffer
+
+	sel := Selection{
+		Candidates: []string{"1.0.0", "1.1.0-rc1", "1.0.1"},
+		Rejected:   []Rejection{{Version: "1.1.0-rc1", Reason: "pre-release"}},
+	}
+
+	logExplanation(NewLogger(&buf, LogNormal), "org/chart", "1.0.1", sel)
+
+	want := "▶ org/chart: considered 3 version(s)\n" +
+		"▶   1.1.0-rc1 rejected: pre-release\n" +
+		"▶   selected 1.0.1\n"
+	if got := buf.String(); got != want {
+		t.Errorf("logExplanation() output =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRunSelfTest(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		response   string
+		want       string
+		wantErr    string
+	}{
+		{
+			name:       "healthy",
+			statusCode: http.StatusOK,
+			response:   `{"available_versions": [{"version": "1.16.0"}, {"version": "1.17.0-rc.1"}]}`,
+			want:       "▶ self-test passed: cilium/cilium latest 1.16.0\n",
+			wantErr:    "",
+		},
+		{
+			name:       "server error",
+			statusCode: http.StatusInternalServerError,
+			response:   "",
+			want:       "",
+			wantErr:    "self-test failed: cilium/cilium: artifacthub HTTP 500",
+		},
+		{
+			name:       "unparseable body",
+			statusCode: http.StatusOK,
+			response:   "<html>maintenance</html>",
+			want:       "",
+			wantErr:    "self-test failed",
+		},
+		{
+			name:       "implausible version",
+			statusCode: http.StatusOK,
+			response:   `{"available_versions": [{"version": "latest"}]}`,
+			want:       "",
+			wantErr:    "self-test failed: cilium/cilium: no versions published (1 version dropped as invalid)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPath string
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
+				w.WriteHeader(tt.statusCode)
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			var buf bytes.Buffer
+
+			err := runSelfTest(context.Background(), MakeArtifactHubFetcher(server.URL, server.Client(), ArtifactHubKey{}, discardLog), NewLogger(&buf, LogNormal))
+
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("runSelfTest() error = %v", err)
+			}
+
+			if tt.wantErr != "" && (err == nil || !strings.HasPrefix(err.Error(), tt.wantErr)) {
+				t.Fatalf("runSelfTest() error = %v, want prefix %q", err, tt.wantErr)
+			}
+
+			if gotPath != "/cilium/cilium" {
+				t.Errorf("runSelfTest() requested %q, want %q", gotPath, "/cilium/cilium")
+			}
+
+			if got := buf.String(); got != tt.want {
+				t.Errorf("runSelfTest() output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintDeltas(t *testing.T) {
+	results := NewResults(
+		UpdateResult{File: "a.yaml", Repo: "org/a", Current: "1.0.0", Latest: "1.1.0", Status: StatusUpdated},
+		UpdateResult{File: "b.yaml", Repo: "org/b", Current: "2.0.0", Latest: "2.0.0", Status: StatusUpToDate},
+		UpdateResult{File: "c.yaml", Repo: "org/c", Current: "", Latest: "", Status: StatusError, Error: errors.New("boom")},
+		UpdateResult{File: "d.yaml", Repo: "org/d", Current: "0.9", Latest: "1.0.0", Status: StatusUpdated},
+	)
+
+	var buf bytes.Buffer
+	if err := printDeltas(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "a.yaml: org/a 1.0.0 → 1.1.0\nd.yaml: org/d 0.9 → 1.0.0\n"; buf.String() != want {
+		t.Errorf("printDeltas() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestSelectWriterPatchFile(t *testing.T) {
+	patchOut := filepath.Join(t.TempDir(), "out.patch")
+
+	_, patch, err := selectWriter(Config{DryRun: true, PatchOut: patchOut}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if patch == nil {
+		t.Fatal("selectWriter() returned no patch file for --patch-out")
+	}
+
+	if err := patch.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, patch, _ := selectWriter(Config{DryRun: true}, io.Discard); patch != nil {
+		t.Error("selectWriter() returned a patch file without --patch-out")
+	}
+}
+
+func TestFinishRunJoinsErrors(t *testing.T) {
+	report := Reporter{
+		Checked: func([]ChartInfo) error { return nil },
+		Result:  func(UpdateResult) error { return nil },
+		Finish:  func() error { return errors.New("finish failed") },
+	}
+	cfg := Config{ChangedFiles: filepath.Join(t.TempDir(), "missing", "changed.txt")}
+	log := NewLogger(io.Discard, LogQuiet)
+
+	err := finishRun(context.Background(), cfg, nil, NewResults(), report, io.Discard, log, time.Now)
+	if err == nil || !strings.Contains(err.Error(), "finish failed") || !strings.Contains(err.Error(), "changed.txt") {
+		t.Fatalf("finishRun() error = %v, want both the reporter and the changed-files error", err)
+	}
+}
+
+func TestCompactDryRunLeavesFilesUntouched(t *testing.T) {
+	const manifest = "# artifacthub: org/chart\nkind: Application\nspec:\n  source:\n    targetRevision: 1.0.0\n"
+
+	dir := t.TempDir()
+	createTestFiles(t, dir, map[string]string{testAppFile: manifest})
+
+	fetch := func(context.Context, VersionQuery) (VersionInfo, error) { return versionInfo("1.1.0"), nil }
+	cfg := Config{Dir: dir, DryRun: true, Compact: true}
+
+	result := MakeChartUpdater(cfg, readYAMLDocuments, fetch, discardYAML, time.Now, discardLog)(
+		context.Background(), ChartInfo{File: testAppFile, Repo: "org/chart"})
+
+	var buf bytes.Buffer
+	if err := printDeltas(&buf, NewResults(result)); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := testAppFile + ": org/chart 1.0.0 → 1.1.0\n"; buf.String() != want {
+		t.Errorf("compact output = %q, want %q", buf.String(), want)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, testAppFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(content) != manifest {
+		t.Errorf("manifest changed by a compact dry run:\n%s", content)
+	}
+}
+
+func TestPrintEffectiveVersions(t *testing.T) {
+	results := []UpdateResult{
+		{File: "a.yaml", Repo: "org/a", Current: "1.0.0", Latest: "1.1.0", Status: StatusUpdated},
+		{File: "b.yaml", Repo: "org/b", Current: "2.0.0", Latest: "2.0.0", Status: StatusUpToDate},
+		{File: "c.yaml", Repo: "org/c", Current: "", Latest: "", Status: StatusError, Error: errors.New("boom")},
+	}
+
+	tests := []struct {
+		name    string
+		applied bool
+		want    string
+	}{
+		{
+			name:    "applied updates report the new version",
+			applied: true,
+			want: "FILE    REPO   VERSION  STATUS\n" +
+				"a.yaml  org/a  1.1.0    updated\n" +
+				"b.yaml  org/b  2.0.0    up-to-date\n" +
+				"c.yaml  org/c  -        error\n",
+		},
+		{
+			name:    "dry-run keeps the current version",
+			applied: false,
+			want: "FILE    REPO   VERSION  STATUS\n" +
+				"a.yaml  org/a  1.0.0    updated\n" +
+				"b.yaml  org/b  2.0.0    up-to-date\n" +
+				"c.yaml  org/c  -        error\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			printEffectiveVersions(&buf, NewResults(results...), tt.applied)
+
+			if got := buf.String(); got != tt.want {
+				t.Errorf("printEffectiveVersions() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPendingChangesError(t *testing.T) {
+	updated := UpdateResult{File: "a.yaml", Repo: "org/a", Current: "1.0.0", Latest: "1.1.0", Status: StatusUpdated}
+	upToDate := UpdateResult{File: "b.yaml", Repo: "org/b", Current: "2.0.0", Latest: "2.0.0", Status: StatusUpToDate}
+
+	tests := []struct {
+		name     string
+		cfg      Config
+		results  []UpdateResult
+		wantCode int
+	}{
+		{
+			name:     "changes pending",
+			cfg:      Config{DryRun: true, DryRunExitCode: 3},
+			results:  []UpdateResult{updated, upToDate},
+			wantCode: 3,
+		},
+		{
+			name:     "no changes pending",
+			cfg:      Config{DryRun: true, DryRunExitCode: 3},
+			results:  []UpdateResult{upToDate},
+			wantCode: 0,
+		},
+		{
+			name:     "exit code not requested",
+			cfg:      Config{DryRun: true, DryRunExitCode: 0},
+			results:  []UpdateResult{updated},
+			wantCode: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := pendingChangesError(tt.cfg, NewResults(tt.results...))
+			if tt.wantCode == 0 {
+				if err != nil {
+					t.Errorf("pendingChangesError() = %v, want nil", err)
+				}
+
+				return
+			}
+
+			if got := exitCode(err); got != tt.wantCode {
+				t.Errorf("exitCode() = %d, want %d (err = %v)", got, tt.wantCode, err)
+			}
+
+			if !strings.Contains(err.Error(), "1 chart(s) would be updated") {
+				t.Errorf("pendingChangesError() = %q, want pending count", err)
+			}
+		})
+	}
+}
+
+func TestExitCodeFlag(t *testing.T) {
+	cfg, err := ParseConfig([]string{"--exit-code"}, func(string) string { return "" })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updated := UpdateResult{File: "a.yaml", Repo: "org/a", Current: "1.0.0", Latest: "1.1.0", Status: StatusUpdated}
+	upToDate := UpdateResult{File: "b.yaml", Repo: "org/b", Current: "2.0.0", Latest: "2.0.0", Status: StatusUpToDate}
+
+	if got := exitCode(pendingChangesError(cfg, NewResults(updated, upToDate))); got != exitCodePending {
+		t.Errorf("exit code with an outdated chart = %d, want %d", got, exitCodePending)
+	}
+
+	if err := pendingChangesError(cfg, NewResults(upToDate)); err != nil {
+		t.Errorf("pendingChangesError() with every chart current = %v, want nil", err)
+	}
+}
+
+func TestExitCodeDefaultsToOne(t *testing.T) {
+	if got := exitCode(errors.New("boom")); got != 1 {
+		t.Errorf("exitCode() = %d, want 1", got)
+	}
+}
+
+func TestApplyFreeze(t *testing.T) {
+	freezeEnd := time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		cfg       Config
+		now       time.Time
+		wantCheck bool
+		wantDry   bool
+		wantLog   bool
+	}{
+		{
+			name:      "inside freeze window",
+			cfg:       Config{DryRun: true, FreezeUntil: freezeEnd},
+			now:       freezeEnd.Add(-time.Hour),
+			wantCheck: true,
+			wantDry:   false,
+			wantLog:   true,
+		},
+		{
+			name:      "freeze window ended",
+			cfg:       Config{DryRun: true, FreezeUntil: freezeEnd},
+			now:       freezeEnd,
+			wantCheck: false,
+			wantDry:   true,
+			wantLog:   false,
+		},
+		{
+			name:      "no freeze configured",
+			cfg:       Config{DryRun: false, FreezeUntil: time.Time{}},
+			now:       freezeEnd,
+			wantCheck: false,
+			wantDry:   false,
+			wantLog:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			got := applyFreeze(tt.cfg, tt.now, NewLogger(&buf, LogNormal))
+
+			if got.CheckOnly != tt.wantCheck || got.DryRun != tt.wantDry {
+				t.Errorf("applyFreeze() CheckOnly = %v, DryRun = %v, want %v, %v", got.CheckOnly, got.DryRun, tt.wantCheck, tt.wantDry)
+			}
+
+			if logged := strings.Contains(buf.String(), "change freeze in effect until 2026-12-31T00:00:00Z"); logged != tt.wantLog {
+				t.Errorf("applyFreeze() output = %q, want freeze message: %v", buf.String(), tt.wantLog)
+			}
+		})
+	}
+}
+
+func TestRunProbe(t *testing.T) {
+	dir := t.TempDir()
+
+	var buf bytes.Buffer
+
+	if err := runProbe(Config{Dir: dir, Probe: true}, MakeDirProber(os.Stat, os.ReadDir), NewLogger(&buf, LogNormal)); err != nil {
+		t.Fatalf("runProbe() error = %v", err)
+	}
+
+	if want := "▶ " + dir + " is readable\n"; buf.String() != want {
+		t.Errorf("runProbe() output = %q, want %q", buf.String(), want)
+	}
+
+	if err := runProbe(Config{Dir: filepath.Join(dir, "missing"), Probe: true}, MakeDirProber(os.Stat, os.ReadDir), NewLogger(&buf, LogNormal)); err == nil {
+		t.Error("runProbe() error = nil, want error for missing directory")
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	if got := requestTimeout(Config{HTTPTimeout: 0}); got != httpClientTimeout {
+		t.Errorf("requestTimeout() default = %v, want %v", got, httpClientTimeout)
+	}
+
+	if got := requestTimeout(Config{HTTPTimeout: 30 * time.Second}); got != 30*time.Second {
+		t.Errorf("requestTimeout() = %v, want %v", got, 30*time.Second)
+	}
+}
+
+func TestArtifactHubURL(t *testing.T) {
+	tests := []struct {
+		apiURL string
+		want   string
+	}{
+		{apiURL: "", want: artifactHubAPIURL},
+		{apiURL: "https://hub.internal/api/v1/packages/helm/", want: "https://hub.internal/api/v1/packages/helm"},
+	}
+
+	for _, tt := range tests {
+		if got := artifactHubURL(Config{APIURL: tt.apiURL}); got != tt.want {
+			t.Errorf("artifactHubURL(%q) = %q, want %q", tt.apiURL, got, tt.want)
+		}
+	}
+}
+
+func TestNewHTTPClientReusesConnections(t *testing.T) {
+	const fetches = 5
+
+	for _, http2 := range []bool{false, true} {
+		t.Run(fmt.Sprintf("http2 %v", http2), func(t *testing.T) {
+			var conns atomic.Int32
+
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if http2 && r.ProtoMajor != 2 {
+					t.Errorf("request used %s, want HTTP/2", r.Proto)
+				}
+
+				_, _ = w.Write([]byte(`{"available_versions":[{"version":"1.0.0"}]}` + "\n"))
+			}))
+			server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+				if state == http.StateNew {
+					conns.Add(1)
+				}
+			}
+
+			client := newHTTPClient(0, 0, time.Second)
+
+			if http2 {
+				server.EnableHTTP2 = true
+				server.StartTLS()
+
+				transport, _ := client.Transport.(*http.Transport)
+				serverTransport, _ := server.Client().Transport.(*http.Transport)
+				transport.TLSClientConfig = serverTransport.TLSClientConfig
+			} else {
+				server.Start()
+			}
+			defer server.Close()
+
+			fetch := MakeArtifactHubFetcher(server.URL, client, ArtifactHubKey{}, discardLog)
+			for range fetches {
+				if _, err := fetch(context.Background(), VersionQuery{Repo: "org/chart", Current: "", Timeout: 0, Limit: 0, PrereleaseSameMajor: false, Stability: StabilityDash, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if got := conns.Load(); got != 1 {
+				t.Errorf("%d fetches opened %d connections, want 1", fetches, got)
+			}
+		})
+	}
+}
+
+func TestNewHTTPClientIdleTimeoutAbortsStalledBody(t *testing.T) {
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "64")
+
+		// The first response stalls after its headers and part of the body.
+		if requests.Add(1) == 1 {
+			_, _ = w.Write([]byte(`{"available_versions":`))
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+
+			return
+		}
+
+		_, _ = w.Write([]byte(fmt.Sprintf("%-64s", `{"available_versions":[{"version":"1.0.0"}]}`)))
+	}))
+	defer server.Close()
+
+	fetch := MakeRetryingFetcher(MakeArtifactHubFetcher(server.URL, newHTTPClient(0, 50*time.Millisecond, time.Minute), ArtifactHubKey{}, discardLog), defaultFetchAttempts)
+
+	start := time.Now()
+
+	info, err := fetch(context.Background(), VersionQuery{Repo: "org/chart", Current: "", Timeout: 0, Limit: 0, PrereleaseSameMajor: false, Stability: StabilityDash, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Version != "1.0.0" {
+		t.Errorf("fetch() = %q, want %q", info.Version, "1.0.0")
+	}
+
+	if got := requests.Load(); got != 2 {
+		t.Errorf("server saw %d requests, want 2", got)
+	}
+
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("fetch() took %v, want the stall aborted by the idle timeout", elapsed)
+	}
+}
+
+func TestNewHTTPClientIdleTimeout(t *testing.T) {
+	for _, idle := range []time.Duration{0, 5 * time.Second} {
+		transport, ok := newHTTPClient(0, idle, time.Second).Transport.(*http.Transport)
+		if !ok {
+			t.Fatal("client does not use an *http.Transport")
+		}
+
+		if transport.ResponseHeaderTimeout != idle {
+			t.Errorf("idle %v: ResponseHeaderTimeout = %v, want %v", idle, transport.ResponseHeaderTimeout, idle)
+		}
+	}
+}
+
+func TestNewHTTPClientIdleConns(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int
+		want int
+	}{
+		{name: "default", n: 0, want: defaultMaxIdleConnsPerHost},
+		{name: "explicit", n: 4, want: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport, ok := newHTTPClient(tt.n, 0, time.Second).Transport.(*http.Transport)
+			if !ok {
+				t.Fatal("client does not use an *http.Transport")
+			}
+
+			if transport.MaxIdleConnsPerHost != tt.want {
+				t.Errorf("MaxIdleConnsPerHost = %d, want %d", transport.MaxIdleConnsPerHost, tt.want)
+			}
+
+			if !transport.ForceAttemptHTTP2 {
+				t.Error("ForceAttemptHTTP2 = false, want true")
+			}
+		})
+	}
+}
+
+func TestLogResultBlocked(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := logResult(UpdateResult{
+		File:    "app.yaml",
+		Repo:    "org/chart",
+		Current: "2.0.0",
+		Latest:  "1.9.0",
+		Status:  StatusBlocked,
+		Error:   nil,
+		Reason:  "latest 1.9.0 is lower than current 2.0.0",
+	}, NewLogger(&buf, LogNormal))
+	if err != nil {
+		t.Fatalf("logResult() error = %v", err)
+	}
+
+	if want := "app.yaml: blocked (latest 1.9.0 is lower than current 2.0.0)"; !strings.Contains(buf.String(), want) {
+		t.Errorf("logResult() = %q, want it to contain %q", buf.String(), want)
+	}
+}
+
+func TestSplitSubcommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantCmd  string
+		wantArgs []string
+	}{
+		{name: "default", args: []string{"--dir", "apps"}, wantCmd: cmdUpdate, wantArgs: []string{"--dir", "apps"}},
+		{name: "no arguments", args: nil, wantCmd: cmdUpdate, wantArgs: nil},
+		{name: "update", args: []string{"update", "--dry-run"}, wantCmd: cmdUpdate, wantArgs: []string{"--dry-run"}},
+		{name: "check", args: []string{"check", "--dir", "apps"}, wantCmd: cmdCheck, wantArgs: []string{"--check", "--dir", "apps"}},
+		{name: "discover", args: []string{"discover"}, wantCmd: cmdDiscover, wantArgs: []string{"--discover-json"}},
+		{name: "version", args: []string{"version"}, wantCmd: cmdVersion, wantArgs: []string{}},
+		{name: "subcommand only first", args: []string{"--dir", "check"}, wantCmd: cmdUpdate, wantArgs: []string{"--dir", "check"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, args := splitSubcommand(tt.args)
+			if cmd != tt.wantCmd {
+				t.Errorf("splitSubcommand() command = %q, want %q", cmd, tt.wantCmd)
+			}
+
+			if !slices.Equal(args, tt.wantArgs) {
+				t.Errorf("splitSubcommand() args = %q, want %q", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestSubcommandsMatchFlags(t *testing.T) {
+	getEnv := func(string) string { return "" }
+
+	tests := []struct {
+		name string
+		args []string
+		want func(Config) bool
+	}{
+		{name: "update", args: []string{"update"}, want: func(c Config) bool { return !c.CheckOnly && !c.DiscoverJSON }},
+		{name: "check", args: []string{"check"}, want: func(c Config) bool { return c.CheckOnly }},
+		{name: "discover", args: []string{"discover"}, want: func(c Config) bool { return c.DiscoverJSON }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, args := splitSubcommand(tt.args)
+
+			cfg, err := ParseConfig(args, getEnv)
+			if err != nil {
+				t.Fatalf("ParseConfig() error = %v", err)
+			}
+
+			if !tt.want(cfg) {
+				t.Errorf("ParseConfig(%q) = %+v", args, cfg)
+			}
+		})
+	}
+}
+
+func TestPrintVersion(t *testing.T) {
+	var buf bytes.Buffer
+
+	if err := printVersion(&buf, "updater"); err != nil {
+		t.Fatalf("printVersion() error = %v", err)
+	}
+
+	if want := "updater " + version + "\n"; buf.String() != want {
+		t.Errorf("printVersion() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestRunDumpResponse(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		response   string
+		want       string
+		wantErr    string
+	}{
+		{
+			name:       "pretty-printed",
+			statusCode: http.StatusOK,
+			response:   `{"name":"cilium","available_versions":[{"version":"1.16.0"},{"version":""}]}`,
+			want: `{
+  "name": "cilium",
+  "available_versions": [
+    {
+      "version": "1.16.0"
+    },
+    {
+      "version": ""
+    }
+  ]
+}
+`,
+			wantErr: "",
+		},
+		{
+			name:       "server error",
+			statusCode: http.StatusNotFound,
+			response:   "",
+			want:       "",
+			wantErr:    "cilium/cilium: artifacthub HTTP 404",
+		},
+		{
+			name:       "not JSON",
+			statusCode: http.StatusOK,
+			response:   "<html>maintenance</html>",
+			want:       "",
+			wantErr:    "cilium/cilium: decode artifacthub response",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPath string
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
+				w.WriteHeader(tt.statusCode)
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			var buf bytes.Buffer
+
+			err := runDumpResponse(context.Background(), "cilium/cilium",
+				MakeArtifactHubResponseFetcher(server.URL, server.Client(), ArtifactHubKey{}), &buf)
+
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("runDumpResponse() error = %v", err)
+			}
+
+			if tt.wantErr != "" && (err == nil || !strings.HasPrefix(err.Error(), tt.wantErr)) {
+				t.Fatalf("runDumpResponse() error = %v, want prefix %q", err, tt.wantErr)
+			}
+
+			if gotPath != "/cilium/cilium" {
+				t.Errorf("runDumpResponse() requested %q, want %q", gotPath, "/cilium/cilium")
+			}
+
+			if got := buf.String(); got != tt.want {
+				t.Errorf("runDumpResponse() output =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRelativeTime(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		t    time.Time
+		want string
+	}{
+		{t: now.Add(-30 * time.Second), want: "just now"},
+		{t: now.Add(time.Hour), want: "just now"},
+		{t: now.Add(-time.Minute), want: "1 minute ago"},
+		{t: now.Add(-45 * time.Minute), want: "45 minutes ago"},
+		{t: now.Add(-5 * time.Hour), want: "5 hours ago"},
+		{t: now.Add(-25 * time.Hour), want: "1 day ago"},
+		{t: now.Add(-3 * 24 * time.Hour), want: "3 days ago"},
+	}
+
+	for _, tt := range tests {
+		if got := relativeTime(now, tt.t); got != tt.want {
+			t.Errorf("relativeTime(%v) = %q, want %q", now.Sub(tt.t), got, tt.want)
+		}
+	}
+}
+
+func TestLogReleaseAge(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		releasedAt time.Time
+		want       string
+	}{
+		{name: "dated", releasedAt: now.Add(-3 * 24 * time.Hour), want: "▶   1.2.0 released 3 days ago\n"},
+		{name: "missing timestamp", releasedAt: time.Time{}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			logReleaseAge(NewLogger(&buf, LogVerbose), "1.2.0", tt.releasedAt, now)
+
+			if got := buf.String(); got != tt.want {
+				t.Errorf("logReleaseAge() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessBatches(t *testing.T) {
+	charts := make([]ChartInfo, 7)
+	for i := range charts {
+		charts[i] = ChartInfo{File: fmt.Sprintf("app-%d.yaml", i), Repo: "", Timeout: 0, Labels: nil, Dir: "", ValuesKey: nil, Source: "", Transforms: nil, AllowPrerelease: false, Constraint: ""}
+	}
+
+	tests := []struct {
+		name         string
+		size         int
+		wantProgress []int
+	}{
+		{name: "unbatched", size: 0, wantProgress: nil},
+		{name: "uneven batches", size: 3, wantProgress: []int{3, 6}},
+		{name: "even batches", size: 7, wantProgress: nil},
+		{name: "batch larger than run", size: 10, wantProgress: nil},
+		{name: "single chart batches", size: 1, wantProgress: []int{1, 2, 3, 4, 5, 6}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				processed []string
+				handled   []string
+				progress  []int
+			)
+
+			process := func(c ChartInfo) UpdateResult {
+				processed = append(processed, c.File)
+
+				return UpdateResult{File: c.File, Status: StatusUpToDate}
+			}
+			handle := func(r UpdateResult) error {
+				handled = append(handled, r.File)
+
+				return nil
+			}
+			report := func(done, total int) {
+				if total != len(charts) {
+					t.Errorf("progress total = %d, want %d", total, len(charts))
+				}
+
+				progress = append(progress, done)
+			}
+
+			if err := processBatches(context.Background(), charts, tt.size, 1, process, handle, report); err != nil {
+				t.Fatalf("processBatches() error = %v", err)
+			}
+
+			want := make([]string, 0, len(charts))
+			for _, c := range charts {
+				want = append(want, c.File)
+			}
+
+			if !slices.Equal(processed, want) || !slices.Equal(handled, want) {
+				t.Errorf("processed %v, handled %v, want %v", processed, handled, want)
+			}
+
+			if !slices.Equal(progress, tt.wantProgress) {
+				t.Errorf("progress = %v, want %v", progress, tt.wantProgress)
+			}
+		})
+	}
+}
+
+func TestProcessBatchesStopsOnError(t *testing.T) {
+	charts := []ChartInfo{{File: "a.yaml"}, {File: "b.yaml"}, {File: "c.yaml"}, {File: "d.yaml"}}
+	errStop := errors.New("stop")
+
+	var processed int
+
+	process := func(c ChartInfo) UpdateResult {
+		processed++
+
+		return UpdateResult{File: c.File}
+	}
+	handle := func(r UpdateResult) error {
+		if r.File == "b.yaml" {
+			return errStop
+		}
+
+		return nil
+	}
+
+	err := processBatches(context.Background(), charts, 2, 1, process, handle, func(int, int) { t.Error("progress reported after error") })
+	if !errors.Is(err, errStop) {
+		t.Fatalf("processBatches() error = %v, want %v", err, errStop)
+	}
+
+	if processed != 2 {
+		t.Errorf("processed %d charts, want 2", processed)
+	}
+}
+
+func TestRunSeparatesDataFromLogs(t *testing.T) {
+	dir := t.TempDir()
+	createTestFiles(t, dir, map[string]string{testAppFile: testAppContent})
+
+	var stdout, stderr bytes.Buffer
+
+	if err := run([]string{"updater", "--discover-json", "--dir", dir}, func(string) string { return "" }, &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+
+	var inventory []map[string]any
+	if err := json.Unmarshal(stdout.Bytes(), &inventory); err != nil {
+		t.Fatalf("stdout is not a JSON inventory: %v\n%s", err, stdout.String())
+	}
+
+	if len(inventory) != 1 || inventory[0]["repo"] != testChartRepo {
+		t.Errorf("inventory = %v, want the one chart in %s", inventory, dir)
+	}
+
+	if got, want := stderr.String(), "▶ 1 chart discovered\n"; got != want {
+		t.Errorf("stderr = %q, want %q", got, want)
+	}
+}
+
+func TestRunAppExitCodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		latest   string
+		fetchErr error
+		wantCode int
+	}{
+		{name: "up to date", args: []string{"--exit-code"}, latest: "1.0.0", fetchErr: nil, wantCode: 0},
+		{name: "changes pending", args: []string{"--exit-code"}, latest: "1.1.0", fetchErr: nil, wantCode: exitCodePending},
+		{name: "fetch error", args: []string{"--dry-run"}, latest: "", fetchErr: errors.New("unreachable"), wantCode: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			createTestFiles(t, dir, map[string]string{testAppFile: "# artifacthub: " + testChartRepo +
+				"\nkind: Application\nspec:\n  source:\n    targetRevision: 1.0.0\n"})
+
+			args := append(slices.Clone(tt.args), "--dir", dir, "--diff-mode", DiffModeBuiltin)
+
+			cfg, err := ParseConfig(args, func(string) string { return "" })
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			fake := func(Config, LogFunc) VersionFetcher {
+				return func(context.Context, VersionQuery) (VersionInfo, error) {
+					if tt.fetchErr != nil {
+						return VersionInfo{}, tt.fetchErr
+					}
+
+					return versionInfo(tt.latest), nil
+				}
+			}
+
+			err = runApp(cfg, fake, time.Now, io.Discard, io.Discard)
+			if tt.wantCode == 0 {
+				if err != nil {
+					t.Errorf("runApp() = %v, want nil", err)
+				}
+
+				return
+			}
+
+			if got := exitCode(err); err == nil || got != tt.wantCode {
+				t.Errorf("runApp() exit code = %d, want %d (err = %v)", got, tt.wantCode, err)
+			}
+		})
+	}
+}
+
+func TestRunCheckJSONOutput(t *testing.T) {
+	dir := t.TempDir()
+	createTestFiles(t, dir, map[string]string{testAppFile: testAppContent})
+
+	var stdout, stderr bytes.Buffer
+
+	if err := run([]string{"updater", "--check", "--output", "json", "--dir", dir}, func(string) string { return "" }, &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+
+	var checked []map[string]any
+	if err := json.Unmarshal(stdout.Bytes(), &checked); err != nil {
+		t.Fatalf("stdout is not JSON: %v\n%s", err, stdout.String())
+	}
+
+	if len(checked) != 1 || checked[0]["file"] != testAppFile || checked[0]["repo"] != testChartRepo {
+		t.Errorf("checked = %v, want the one chart in %s", checked, dir)
+	}
+
+	if strings.Contains(stderr.String(), testChartRepo) {
+		t.Errorf("stderr = %q, want the chart listed only on stdout", stderr.String())
+	}
+}
@@ -0,0 +1,315 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// sourceOCI is the ChartInfo and VersionQuery Source of charts pushed to an
+// OCI registry. Their Repo is the registry host followed by the repository
+// name, e.g. "ghcr.io/org/chart".
+const sourceOCI = "oci"
+
+// ociMaxPages caps how many pages of a paginated tag list are followed.
+const ociMaxPages = 20
+
+// errDecodeTags marks a 200 response whose tag list could not be decoded; like
+// errDecodeResponse it is worth retrying.
+var errDecodeTags = errors.New("decode oci tag list")
+
+// ociTagList is the body of the distribution API's tags/list endpoint.
+type ociTagList struct {
+	Tags []string `json:"tags"`
+}
+
+// ociToken is the body of a registry token endpoint. Registries use either
+// field name.
+type ociToken struct {
+	Token       string `json:"token"`
+	AccessToken string `json:"access_token"`
+}
+
+// MakeOCIFetcher creates a VersionFetcher for charts stored in OCI registries.
+// It lists the repository's tags through the distribution API at
+// scheme://host/v2/, answering a bearer challenge with an anonymous pull
+// token, and selects from them like MakeArtifactHubFetcher does. Helm stores
+// "+" in versions as "_" in tags, which is undone. Tags carry no signature
+// data, so queries requiring signed versions fail.
+func MakeOCIFetcher(client *http.Client, scheme string) VersionFetcher {
+	return func(ctx context.Context, q VersionQuery) (VersionInfo, error) {
+		if q.RequireSigned {
+			return VersionInfo{}, errors.New("--require-signed: oci registries are not checked for signatures")
+		}
+
+		host, name, _ := strings.Cut(q.Repo, "/")
+
+		tags, err := fetchOCITags(ctx, withTimeout(client, q.Timeout), scheme+"://"+host, name)
+		if err != nil {
+			return VersionInfo{}, err
+		}
+
+		for i, tag := range tags {
+			tags[i] = strings.ReplaceAll(tag, "_", "+")
+		}
+
+		versions, dropped := cleanVersions(tags)
+		if len(versions) == 0 {
+			return VersionInfo{}, fmt.Errorf("%w (%s dropped as invalid)", errNoVersions, plural(len(dropped), "tag"))
+		}
+
+		latest, sel, ok := selectVersion(versions, q)
+		sel.Rejected = append(dropped, sel.Rejected...)
+
+		if !ok {
+			if constraintBlocked(sel, q) {
+				return VersionInfo{}, constraintError(q.Constraint, sel)
+			}
+
+			return VersionInfo{}, fmt.Errorf("no stable versions found, %s rejected", plural(len(sel.Rejected), "tag"))
+		}
+
+		return VersionInfo{Version: latest, Selection: sel, ReleasedAt: time.Time{}, Deprecated: false, AppVersion: "", Digest: ""}, nil
+	}
+}
+
+// ociHost is the QueryHost of OCI registry charts: the registry their Repo
+// starts with.
+func ociHost(q VersionQuery) string {
+	host, _, _ := strings.Cut(q.Repo, "/")
+	return host
+}
+
+// parseOCIComment validates the text following the oci prefix: a reference
+// such as "ghcr.io/org/chart", optionally written with an "oci://" scheme, and
+// then the optional prerelease marker and constraint.
+func parseOCIComment(value string) (RepoComment, error) {
+	comment, err := parseSourceComment(sourceOCI, strings.TrimPrefix(strings.TrimSpace(value), "oci://"))
+	if err != nil {
+		return RepoComment{}, err
+	}
+
+	if host, name, _ := strings.Cut(comment.Repo, "/"); host == "" || name == "" || strings.Contains(comment.Repo, "://") {
+		return RepoComment{}, fmt.Errorf("invalid %s reference %q: want registry/name, e.g. ghcr.io/org/chart", sourceOCI, comment.Repo)
+	}
+
+	return comment, nil
+}
+
+// fetchOCITags lists every tag of repository name on the registry at
+// registryURL, following pagination links.
+func fetchOCITags(ctx context.Context, client *http.Client, registryURL, name string) ([]string, error) {
+	var (
+		tags  []string
+		token string
+	)
+
+	next := registryURL + "/v2/" + name + "/tags/list"
+
+	for page := 0; next != "" && page < ociMaxPages; page++ {
+		resp, err := getOCI(ctx, client, next, token)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized && token == "" {
+			challenge := resp.Header.Get("WWW-Authenticate")
+			resp.Body.Close()
+
+			if token, err = fetchOCIToken(ctx, client, challenge); err != nil {
+				return nil, err
+			}
+
+			page--
+
+			continue
+		}
+
+		list, link, err := readOCITags(resp)
+		if err != nil {
+			return nil, err
+		}
+
+		tags = append(tags, list.Tags...)
+
+		if next, err = nextPage(next, link); err != nil {
+			return nil, err
+		}
+	}
+
+	return tags, nil
+}
+
+// getOCI sends a GET to endpoint, with token as bearer token when set.
+func getOCI(ctx context.Context, client *http.Client, endpoint, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch tags from oci registry: %w", err)
+	}
+
+	return resp, nil
+}
+
+// readOCITags decodes a tags/list response and returns its Link header.
+func readOCITags(resp *http.Response) (ociTagList, string, error) {
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ociTagList{}, "", fmt.Errorf("oci registry HTTP %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return ociTagList{}, "", fmt.Errorf("%w: %w", errDecodeTags, err)
+	}
+
+	var list ociTagList
+	if err := json.Unmarshal(body, &list); err != nil {
+		return ociTagList{}, "", fmt.Errorf("%w: %w", errDecodeTags, err)
+	}
+
+	return list, resp.Header.Get("Link"), nil
+}
+
+// nextPage resolves the target of a `<url>; rel="next"` Link header against
+// the current page, or returns "" when there is no next page.
+func nextPage(current, link string) (string, error) {
+	target, params, found := strings.Cut(link, ";")
+	if !found || !strings.Contains(params, `rel="next"`) {
+		return "", nil
+	}
+
+	base, err := url.Parse(current)
+	if err != nil {
+		return "", fmt.Errorf("parse page url: %w", err)
+	}
+
+	ref, err := url.Parse(strings.Trim(strings.TrimSpace(target), "<>"))
+	if err != nil {
+		return "", fmt.Errorf("parse oci link header %q: %w", link, err)
+	}
+
+	return base.ResolveReference(ref).String(), nil
+}
+
+// fetchOCIToken answers a registry's WWW-Authenticate bearer challenge by
+// requesting an anonymous token from the realm it names.
+func fetchOCIToken(ctx context.Context, client *http.Client, challenge string) (string, error) {
+	params, ok := parseBearerChallenge(challenge)
+	if !ok || params["realm"] == "" {
+		return "", fmt.Errorf("oci registry HTTP 401 without a bearer challenge (WWW-Authenticate: %q)", challenge)
+	}
+
+	realm, err := url.Parse(params["realm"])
+	if err != nil {
+		return "", fmt.Errorf("parse oci token realm: %w", err)
+	}
+
+	query := realm.Query()
+
+	for _, key := range []string{"service", "scope"} {
+		if v := params[key]; v != "" {
+			query.Set(key, v)
+		}
+	}
+
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("create request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fetch oci registry token: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("oci token endpoint HTTP %d", resp.StatusCode)
+	}
+
+	var token ociToken
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("decode oci registry token: %w", err)
+	}
+
+	if token.Token != "" {
+		return token.Token, nil
+	}
+
+	if token.AccessToken != "" {
+		return token.AccessToken, nil
+	}
+
+	return "", errors.New("oci token endpoint returned no token")
+}
+
+// parseBearerChallenge reads the parameters of a `Bearer realm="...",
+// service="...",scope="..."` WWW-Authenticate header. Quoted values may
+// contain commas.
+func parseBearerChallenge(header string) (map[string]string, bool) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return nil, false
+	}
+
+	params := map[string]string{}
+
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimLeft(rest, ", ") {
+		key, value, found := strings.Cut(rest, "=")
+		if !found {
+			return nil, false
+		}
+
+		if strings.HasPrefix(value, `"`) {
+			end := strings.Index(value[1:], `"`)
+			if end < 0 {
+				return nil, false
+			}
+
+			params[strings.ToLower(strings.TrimSpace(key))] = value[1 : end+1]
+			rest = value[end+2:]
+
+			continue
+		}
+
+		value, rest, _ = strings.Cut(value, ",")
+		params[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+	}
+
+	return params, true
+}
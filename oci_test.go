@@ -0,0 +1,211 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newOCIRegistry serves a registry that requires an anonymous bearer token for
+// org/mychart, returning its tags over two pages.
+func newOCIRegistry(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	const token = "pull-token"
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("service") != "registry.test" || r.URL.Query().Get("scope") != "repository:org/mychart:pull" {
+			http.Error(w, "bad token request "+r.URL.RawQuery, http.StatusBadRequest)
+			return
+		}
+
+		_, _ = fmt.Fprintf(w, `{"token": %q}`, token)
+	})
+
+	mux.HandleFunc("/v2/org/mychart/tags/list", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry.test",scope="repository:org/mychart:pull"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		if r.URL.Query().Get("last") == "" {
+			w.Header().Set("Link", `</v2/org/mychart/tags/list?last=1.9.0&n=3>; rel="next"`)
+			_, _ = w.Write([]byte(`{"name": "org/mychart", "tags": ["latest", "1.8.0", "1.9.0"]}`))
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"name": "org/mychart", "tags": ["2.0.1", "2.0.2_build.1", "2.1.0-rc.1"]}`))
+	})
+
+	return server
+}
+
+func TestOCIFetcher(t *testing.T) {
+	server := newOCIRegistry(t)
+	fetch := MakeOCIFetcher(server.Client(), "http")
+	repo := strings.TrimPrefix(server.URL, "http://") + "/org/mychart"
+
+	info, err := fetch(context.Background(), testQuery(sourceOCI, repo))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Helm pushes 2.0.2+build.1 as the tag 2.0.2_build.1.
+	if info.Version != "2.0.2+build.1" {
+		t.Errorf("Version = %q, want %q", info.Version, "2.0.2+build.1")
+	}
+
+	if got := info.Selection.Rejected; len(got) == 0 || got[0].Version != "latest" {
+		t.Errorf("Rejected = %+v, want latest first", got)
+	}
+
+	q := testQuery(sourceOCI, repo)
+	q.AllowPrerelease = true
+
+	if info, err := fetch(context.Background(), q); err != nil || info.Version != "2.1.0-rc.1" {
+		t.Errorf("fetch() allowing pre-releases = %q, %v, want %q", info.Version, err, "2.1.0-rc.1")
+	}
+
+	q.AllowPrerelease, q.Constraint = false, "<1.9.0"
+
+	if info, err := fetch(context.Background(), q); err != nil || info.Version != "1.8.0" {
+		t.Errorf("fetch() with constraint = %q, %v, want %q", info.Version, err, "1.8.0")
+	}
+
+	q.Constraint, q.RequireSigned = "", true
+
+	if _, err := fetch(context.Background(), q); err == nil || !strings.Contains(err.Error(), "--require-signed") {
+		t.Errorf("fetch() requiring signed versions error = %v, want a --require-signed error", err)
+	}
+}
+
+func TestOCIFetcherErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		challenge string
+		status    int
+		body      string
+		wantErr   string
+	}{
+		{name: "no challenge", challenge: "", status: http.StatusUnauthorized, body: "", wantErr: "without a bearer challenge"},
+		{name: "basic challenge", challenge: `Basic realm="registry"`, status: http.StatusUnauthorized, body: "", wantErr: "without a bearer challenge"},
+		{name: "http error", challenge: "", status: http.StatusNotFound, body: "", wantErr: "oci registry HTTP 404"},
+		{name: "malformed tag list", challenge: "", status: http.StatusOK, body: `{"tags": [`, wantErr: errDecodeTags.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				if tt.challenge != "" {
+					w.Header().Set("WWW-Authenticate", tt.challenge)
+				}
+
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			_, err := MakeOCIFetcher(server.Client(), "http")(context.Background(),
+				testQuery(sourceOCI, strings.TrimPrefix(server.URL, "http://")+"/mychart"))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("fetch() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseBearerChallenge(t *testing.T) {
+	got, ok := parseBearerChallenge(`Bearer realm="https://ghcr.io/token",service="ghcr.io",scope="repository:org/a:pull,push"`)
+	if !ok {
+		t.Fatal("parseBearerChallenge() ok = false, want true")
+	}
+
+	want := map[string]string{"realm": "https://ghcr.io/token", "service": "ghcr.io", "scope": "repository:org/a:pull,push"}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("parseBearerChallenge()[%q] = %q, want %q", key, got[key], value)
+		}
+	}
+
+	for _, header := range []string{"", `Basic realm="x"`, `Bearer realm="unterminated`} {
+		if _, ok := parseBearerChallenge(header); ok {
+			t.Errorf("parseBearerChallenge(%q) ok = true, want false", header)
+		}
+	}
+}
+
+func TestParseOCIComment(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    RepoComment
+		wantErr bool
+	}{
+		{value: "ghcr.io/org/mychart", want: RepoComment{Repo: "ghcr.io/org/mychart", AllowPrerelease: false, Constraint: ""}, wantErr: false},
+		{value: "oci://ghcr.io/org/mychart", want: RepoComment{Repo: "ghcr.io/org/mychart", AllowPrerelease: false, Constraint: ""}, wantErr: false},
+		{value: "ghcr.io/org/mychart prerelease <3.0.0", want: RepoComment{Repo: "ghcr.io/org/mychart", AllowPrerelease: true, Constraint: "<3.0.0"}, wantErr: false},
+		{value: "mychart", want: RepoComment{}, wantErr: true},
+		{value: "https://ghcr.io/org/mychart", want: RepoComment{}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseOCIComment(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseOCIComment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseOCIComment() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractChartInfoOCISource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), testAppFile)
+
+	content := "# oci: ghcr.io/org/mychart\nkind: Application\nspec:\n  source:\n    chart: mychart\n    targetRevision: 1.9.0\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, read := range []YAMLReader{readYAMLDocuments, readFirstArtifactHubApplication} {
+		got, warnings := chartInfoAt(read, path)
+		if warnings != "" {
+			t.Fatal(warnings)
+		}
+
+		if got.Repo != "ghcr.io/org/mychart" || got.Source != sourceOCI {
+			t.Errorf("toChartInfo() = %q from %q, want %q from %q", got.Repo, got.Source, "ghcr.io/org/mychart", sourceOCI)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/BooleanCat/go-functional/v2/it"
+)
+
+// Outcomes accepted by --fail-on.
+const (
+	FailOnError      = "error"      // A chart could not be checked or written
+	FailOnOutdated   = "outdated"   // A dry run found a chart that would be updated
+	FailOnDeprecated = "deprecated" // A chart is deprecated by its publisher
+	FailOnDowngrade  = "downgrade"  // A chart's latest version is below its current one
+	FailOnSkipped    = "skipped"    // A chart was skipped, e.g. unreachable or opted out
+)
+
+// exitCodePending is the exit code --exit-code sets for a dry run that would
+// update at least one chart, leaving 1 for errors.
+const exitCodePending = 2
+
+// ExitCodeError is returned when the process should exit with a specific
+// non-zero code rather than the generic error code 1.
+type ExitCodeError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitCodeError) Error() string { return e.Err.Error() }
+
+func (e *ExitCodeError) Unwrap() error { return e.Err }
+
+// exitCode maps an error returned by run to the process exit code.
+func exitCode(err error) int {
+	var ee *ExitCodeError
+	if errors.As(err, &ee) {
+		return ee.Code
+	}
+
+	return 1
+}
+
+// failOnValues lists the outcomes --fail-on accepts, in display order.
+func failOnValues() []string {
+	return []string{FailOnError, FailOnOutdated, FailOnDeprecated, FailOnDowngrade, FailOnSkipped}
+}
+
+// validateFailOn rejects outcomes --fail-on does not know about.
+func validateFailOn(values []string) error {
+	for _, v := range values {
+		if !slices.Contains(failOnValues(), v) {
+			return fmt.Errorf("--fail-on: unknown outcome %q (want %s)", v, strings.Join(failOnValues(), ", "))
+		}
+	}
+
+	return nil
+}
+
+// failsOn reports whether outcome makes the run fail. Without --fail-on only
+// errors do.
+func failsOn(cfg Config, outcome string) bool {
+	if cfg.FailOn == nil {
+		return outcome == FailOnError
+	}
+
+	return slices.Contains(cfg.FailOn, outcome)
+}
+
+// failOnResults reports the non-error outcomes that --fail-on asks to fail on.
+// Errors are handled as they occur, so they are not counted here.
+func failOnResults(cfg Config, results *Results) error {
+	var problems []string
+
+	if failsOn(cfg, FailOnOutdated) && cfg.DryRun {
+		if n := results.Count(StatusUpdated); n > 0 {
+			problems = append(problems, fmt.Sprintf("%d chart(s) outdated", n))
+		}
+	}
+
+	if failsOn(cfg, FailOnDeprecated) {
+		if n := countResults(results, isDeprecated); n > 0 {
+			problems = append(problems, fmt.Sprintf("%d chart(s) deprecated", n))
+		}
+	}
+
+	if failsOn(cfg, FailOnDowngrade) {
+		if n := countResults(results, isDowngrade); n > 0 {
+			problems = append(problems, fmt.Sprintf("%d chart(s) ahead of the latest version", n))
+		}
+	}
+
+	if failsOn(cfg, FailOnSkipped) {
+		if n := results.Count(StatusSkipped); n > 0 {
+			problems = append(problems, fmt.Sprintf("%d chart(s) skipped", n))
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return errors.New("failing due to --fail-on: " + strings.Join(problems, ", "))
+}
+
+// countResults counts the results matching keep.
+func countResults(results *Results, keep func(UpdateResult) bool) int {
+	return len(slices.Collect(it.Filter(slices.Values(results.All()), keep)))
+}
+
+// isDeprecated reports whether r's chart is deprecated by its publisher.
+func isDeprecated(r UpdateResult) bool {
+	return slices.Contains(r.Warnings, deprecatedWarning)
+}
+
+// isDowngrade reports whether r's latest version is below its current one,
+// whether or not --never-downgrade blocked the chart.
+func isDowngrade(r UpdateResult) bool {
+	return r.Status == StatusBlocked ||
+		(r.Latest != "" && !isPartialPin(r.Current) && versionLess(r.Latest, r.Current))
+}
+
+// pendingChangesError reports, for a dry run with --dry-run-exit-code or
+// --exit-code, that at least one chart would have been updated.
+func pendingChangesError(cfg Config, results *Results) error {
+	code := cfg.DryRunExitCode
+	if cfg.ExitCode {
+		code = exitCodePending
+	}
+
+	if !cfg.DryRun || code == 0 {
+		return nil
+	}
+
+	pending := results.Count(StatusUpdated)
+	if pending == 0 {
+		return nil
+	}
+
+	return &ExitCodeError{Code: code, Err: fmt.Errorf("%d chart(s) would be updated", pending)}
+}
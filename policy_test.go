@@ -0,0 +1,130 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFailsOn(t *testing.T) {
+	tests := []struct {
+		name    string
+		failOn  []string
+		outcome string
+		want    bool
+	}{
+		{name: "default fails on error", failOn: nil, outcome: FailOnError, want: true},
+		{name: "default ignores outdated", failOn: nil, outcome: FailOnOutdated, want: false},
+		{name: "default ignores skipped", failOn: nil, outcome: FailOnSkipped, want: false},
+		{name: "explicit list without error", failOn: []string{FailOnOutdated}, outcome: FailOnError, want: false},
+		{name: "explicit list with skipped", failOn: []string{FailOnError, FailOnSkipped}, outcome: FailOnSkipped, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := failsOn(Config{FailOn: tt.failOn}, tt.outcome); got != tt.want {
+				t.Errorf("failsOn(%v, %q) = %v, want %v", tt.failOn, tt.outcome, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFailOnResults(t *testing.T) {
+	updated := UpdateResult{File: "a.yaml", Repo: "org/a", Current: "1.0.0", Latest: "1.1.0", Status: StatusUpdated}
+	upToDate := UpdateResult{File: "b.yaml", Repo: "org/b", Current: "2.0.0", Latest: "2.0.0", Status: StatusUpToDate}
+	skipped := UpdateResult{File: "c.yaml", Repo: "org/c", Current: "3.0.0", Status: StatusSkipped}
+	deprecated := UpdateResult{File: "d.yaml", Repo: "org/d", Current: "1.0.0", Latest: "1.0.0", Status: StatusUpToDate, Warnings: []string{deprecatedWarning}}
+	behind := UpdateResult{File: "e.yaml", Repo: "org/e", Current: "2.1.0", Latest: "2.0.0", Status: StatusUpToDate}
+	blocked := UpdateResult{File: "f.yaml", Repo: "org/f", Current: "3.1.0", Latest: "3.0.0", Status: StatusBlocked}
+	partial := UpdateResult{File: "g.yaml", Repo: "org/g", Current: "1.2", Latest: "1.2.5", Status: StatusUpToDate}
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		results []UpdateResult
+		wantErr string
+	}{
+		{
+			name:    "default ignores outdated and skipped",
+			cfg:     Config{DryRun: true},
+			results: []UpdateResult{updated, skipped},
+		},
+		{
+			name:    "outdated in dry run",
+			cfg:     Config{DryRun: true, FailOn: []string{FailOnOutdated}},
+			results: []UpdateResult{updated, upToDate},
+			wantErr: "1 chart(s) outdated",
+		},
+		{
+			name:    "outdated ignored when writing",
+			cfg:     Config{FailOn: []string{FailOnOutdated}},
+			results: []UpdateResult{updated},
+		},
+		{
+			name:    "skipped",
+			cfg:     Config{FailOn: []string{FailOnError, FailOnSkipped}},
+			results: []UpdateResult{upToDate, skipped, skipped},
+			wantErr: "2 chart(s) skipped",
+		},
+		{
+			name:    "outdated and skipped together",
+			cfg:     Config{DryRun: true, FailOn: []string{FailOnOutdated, FailOnSkipped}},
+			results: []UpdateResult{updated, skipped},
+			wantErr: "1 chart(s) outdated, 1 chart(s) skipped",
+		},
+		{
+			name:    "deprecated",
+			cfg:     Config{FailOn: []string{FailOnDeprecated}},
+			results: []UpdateResult{upToDate, deprecated},
+			wantErr: "1 chart(s) deprecated",
+		},
+		{
+			name:    "downgrade blocked or not",
+			cfg:     Config{FailOn: []string{FailOnDowngrade}},
+			results: []UpdateResult{upToDate, behind, blocked, partial},
+			wantErr: "2 chart(s) ahead of the latest version",
+		},
+		{
+			name:    "deprecated and downgrade ignored by default",
+			cfg:     Config{},
+			results: []UpdateResult{deprecated, behind, blocked},
+		},
+		{
+			name:    "nothing to report",
+			cfg:     Config{DryRun: true, FailOn: []string{FailOnOutdated, FailOnSkipped}},
+			results: []UpdateResult{upToDate},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := failOnResults(tt.cfg, NewResults(tt.results...))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("failOnResults() = %v, want nil", err)
+				}
+
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("failOnResults() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"iter"
+	"slices"
+	"sync"
+
+	"github.com/BooleanCat/go-functional/v2/it"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConcurrency is how many charts are processed at once when
+// --concurrency is not set.
+const defaultConcurrency = 4
+
+// processConcurrently runs process over charts on up to workers goroutines,
+// fed from a channel, and yields the results in the order of charts whatever
+// order they finish in. When the consumer stops early, or ctx is done, no
+// further chart is started; charts already in flight still finish. With a
+// single worker charts are processed lazily, one per result consumed.
+func processConcurrently(
+	ctx context.Context, charts []ChartInfo, workers int, process func(ChartInfo) UpdateResult,
+) iter.Seq[UpdateResult] {
+	if workers <= 1 {
+		return it.Map(slices.Values(charts), process)
+	}
+
+	return func(yield func(UpdateResult) bool) {
+		var wg sync.WaitGroup
+		defer wg.Wait()
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		// One buffered slot per chart, so nobody blocks on a result that is
+		// never consumed.
+		results := make([]chan UpdateResult, len(charts))
+		for i := range results {
+			results[i] = make(chan UpdateResult, 1)
+		}
+
+		jobs := make(chan int)
+
+		for range min(workers, len(charts)) {
+			wg.Go(func() {
+				for i := range jobs {
+					results[i] <- process(charts[i])
+				}
+			})
+		}
+
+		wg.Go(func() {
+			defer close(jobs)
+
+			for i, c := range charts {
+				if ctx.Err() != nil {
+					results[i] <- newErrorResult(c.File, c.Repo, ctx.Err())
+					continue
+				}
+
+				select {
+				case <-ctx.Done():
+					results[i] <- newErrorResult(c.File, c.Repo, ctx.Err())
+				case jobs <- i:
+				}
+			}
+		})
+
+		for _, result := range results {
+			if !yield(<-result) {
+				return
+			}
+		}
+	}
+}
+
+// serializedByPath wraps process so that charts sharing a manifest, such as
+// two pins in one Helm values file, are processed one at a time; otherwise
+// each would read the file before the other wrote it and one update would be
+// lost.
+func serializedByPath(cfg Config, process func(ChartInfo) UpdateResult) func(ChartInfo) UpdateResult {
+	var (
+		mu    sync.Mutex
+		locks = map[string]*sync.Mutex{}
+	)
+
+	lockFor := func(path string) *sync.Mutex {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if _, ok := locks[path]; !ok {
+			locks[path] = &sync.Mutex{}
+		}
+
+		return locks[path]
+	}
+
+	return func(c ChartInfo) UpdateResult {
+		lock := lockFor(chartPath(cfg, c))
+		lock.Lock()
+		defer lock.Unlock()
+
+		return process(c)
+	}
+}
+
+// serializedWriter wraps write so that only one file is written at a time,
+// keeping the diffs and suggestions dry-run writers print from interleaving.
+func serializedWriter(write YAMLWriter) YAMLWriter {
+	var mu sync.Mutex
+
+	return func(ctx context.Context, path string, docs []*yaml.Node) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return write(ctx, path, docs)
+	}
+}
@@ -0,0 +1,135 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/BooleanCat/go-functional/v2/it"
+)
+
+func TestProcessConcurrentlyKeepsInputOrder(t *testing.T) {
+	charts := []ChartInfo{{File: "a.yaml"}, {File: "b.yaml"}, {File: "c.yaml"}, {File: "d.yaml"}}
+
+	// Earlier charts take longer, so they finish last.
+	delays := map[string]time.Duration{"a.yaml": 30 * time.Millisecond, "b.yaml": 20 * time.Millisecond, "c.yaml": 10 * time.Millisecond}
+
+	var running, peak atomic.Int32
+
+	process := func(c ChartInfo) UpdateResult {
+		peak.Store(max(peak.Load(), running.Add(1)))
+		defer running.Add(-1)
+
+		time.Sleep(delays[c.File])
+
+		return UpdateResult{File: c.File, Status: StatusUpToDate}
+	}
+
+	results := slices.Collect(processConcurrently(context.Background(), charts, 3, process))
+
+	got := slices.Collect(it.Map(slices.Values(results), func(r UpdateResult) string { return r.File }))
+	if want := []string{"a.yaml", "b.yaml", "c.yaml", "d.yaml"}; !slices.Equal(got, want) {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+
+	if n := peak.Load(); n < 2 || n > 3 {
+		t.Errorf("peak concurrency = %d, want between 2 and 3", n)
+	}
+}
+
+func TestProcessConcurrentlyStopsStartingCharts(t *testing.T) {
+	charts := make([]ChartInfo, 20)
+	for i := range charts {
+		charts[i] = ChartInfo{File: string(rune('a'+i)) + ".yaml"}
+	}
+
+	var started atomic.Int32
+
+	process := func(c ChartInfo) UpdateResult {
+		started.Add(1)
+		time.Sleep(5 * time.Millisecond)
+
+		return UpdateResult{File: c.File, Status: StatusUpToDate}
+	}
+
+	for range processConcurrently(context.Background(), charts, 2, process) {
+		break
+	}
+
+	// The first result may arrive while the other worker and one more chart
+	// are in flight; nothing after that is started.
+	if n := started.Load(); n > 4 {
+		t.Errorf("started %d charts after stopping at the first, want at most 4", n)
+	}
+}
+
+func TestProcessConcurrentlyCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	charts := []ChartInfo{{File: "a.yaml", Repo: "org/a"}, {File: "b.yaml", Repo: "org/b"}}
+	process := func(c ChartInfo) UpdateResult {
+		t.Errorf("process(%s) called after cancellation", c.File)
+		return UpdateResult{File: c.File, Status: StatusUpToDate}
+	}
+
+	for r := range processConcurrently(ctx, charts, 2, process) {
+		if r.Status != StatusError || !errors.Is(r.Error, context.Canceled) {
+			t.Errorf("%s: status %v, error %v, want a cancellation error", r.File, r.Status, r.Error)
+		}
+	}
+}
+
+func TestSerializedByPath(t *testing.T) {
+	cfg := Config{Dir: "apps"}
+	charts := []ChartInfo{
+		{File: "values.yaml", Repo: "org/a", ValuesKey: []string{"a", "version"}},
+		{File: "values.yaml", Repo: "org/b", ValuesKey: []string{"b", "version"}},
+		{File: "values.yaml", Repo: "org/c", ValuesKey: []string{"c", "version"}},
+		{File: "other.yaml", Repo: "org/d"},
+	}
+
+	var (
+		mu      sync.Mutex
+		running = map[string]int{}
+	)
+
+	process := serializedByPath(cfg, func(c ChartInfo) UpdateResult {
+		mu.Lock()
+		running[c.File]++
+		if running[c.File] > 1 {
+			t.Errorf("%s processed by two charts at once", c.File)
+		}
+		mu.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		mu.Lock()
+		running[c.File]--
+		mu.Unlock()
+
+		return UpdateResult{File: c.File, Status: StatusUpToDate}
+	})
+
+	_ = slices.Collect(processConcurrently(context.Background(), charts, 4, process))
+}
@@ -0,0 +1,98 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"text/tabwriter"
+)
+
+// SettingSource names the configuration layer a setting was resolved from.
+type SettingSource string
+
+const (
+	SettingDefault SettingSource = "default" // Built-in default
+	SettingConfig  SettingSource = "config"  // --config file or one of its profiles
+	SettingEnv     SettingSource = "env"     // Environment variable
+	SettingFlag    SettingSource = "flag"    // Command-line flag or response file
+)
+
+// Provenance records, per Config field name, the last layer that changed the
+// field. Fields missing from it kept their default.
+type Provenance map[string]SettingSource
+
+// Source returns the layer that set field.
+func (p Provenance) Source(field string) SettingSource {
+	if source, ok := p[field]; ok {
+		return source
+	}
+
+	return SettingDefault
+}
+
+// record attributes every field that differs between before and after to
+// source and returns after. A layer that sets a field to the value it already
+// had is not credited, since nothing observable changed.
+func (p Provenance) record(before, after Config, source SettingSource) Config {
+	b, a := reflect.ValueOf(before), reflect.ValueOf(after)
+
+	for i := range a.NumField() {
+		if !reflect.DeepEqual(b.Field(i).Interface(), a.Field(i).Interface()) {
+			p[a.Type().Field(i).Name] = source
+		}
+	}
+
+	return after
+}
+
+// printConfig writes every setting of cfg and its value, plus the layer that
+// set it when withSource is true, for --config-print.
+func printConfig(w io.Writer, cfg Config, sources Provenance, withSource bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	v := reflect.ValueOf(cfg)
+
+	for i := range v.NumField() {
+		name := v.Type().Field(i).Name
+		line := name + "\t" + formatSetting(v.Field(i))
+
+		if withSource {
+			line += "\t" + string(sources.Source(name))
+		}
+
+		if _, err := fmt.Fprintln(tw, line); err != nil {
+			return fmt.Errorf("write config: %w", err)
+		}
+	}
+
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("write config: %w", err)
+	}
+
+	return nil
+}
+
+// formatSetting renders a setting value, quoting strings so that empty ones
+// stay visible.
+func formatSetting(value reflect.Value) string {
+	if value.Kind() == reflect.String {
+		return fmt.Sprintf("%q", value.String())
+	}
+
+	return fmt.Sprint(value.Interface())
+}
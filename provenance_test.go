@@ -0,0 +1,123 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseConfigWithSources(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("dir: apps\nhistory: file.csv\nmaxPerHost: 4\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	env := map[string]string{argoAppsDirEnvVar: "/env/apps"}
+
+	cfg, sources, err := ParseConfigWithSources(
+		[]string{"--config", path, "--max-per-host", "2", "--dry-run"},
+		func(key string) string { return env[key] },
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		field string
+		want  SettingSource
+	}{
+		{field: "Dir", want: SettingEnv},
+		{field: "History", want: SettingConfig},
+		{field: "MaxPerHost", want: SettingFlag},
+		{field: "DryRun", want: SettingFlag},
+		{field: "ConfigFile", want: SettingFlag},
+		{field: "CheckOnly", want: SettingDefault},
+		{field: "OptOutLabel", want: SettingDefault},
+	}
+
+	for _, tt := range tests {
+		if got := sources.Source(tt.field); got != tt.want {
+			t.Errorf("Source(%q) = %q, want %q", tt.field, got, tt.want)
+		}
+	}
+
+	if cfg.Dir != "/env/apps" || cfg.MaxPerHost != 2 {
+		t.Errorf("ParseConfigWithSources() Dir = %q, MaxPerHost = %d, want %q, 2", cfg.Dir, cfg.MaxPerHost, "/env/apps")
+	}
+}
+
+func TestParseConfigWithSourcesUnchangedValue(t *testing.T) {
+	_, sources, err := ParseConfigWithSources([]string{"--dir", defaultArgoAppsDir}, func(string) string { return "" })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := sources.Source("Dir"); got != SettingDefault {
+		t.Errorf("Source(%q) = %q, want %q", "Dir", got, SettingDefault)
+	}
+}
+
+func TestPrintConfig(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.DryRun = true
+	cfg.ArtifactHubKey = ArtifactHubKey{ID: "key-id", Secret: "hunter2"}
+
+	sources := Provenance{"DryRun": SettingFlag}
+
+	tests := []struct {
+		name       string
+		withSource bool
+		want       map[string][]string
+	}{
+		{
+			name:       "values only",
+			withSource: false,
+			want:       map[string][]string{"Dir": {`"argoapps"`}, "DryRun": {"true"}, "StableRule": {`""`}, "ArtifactHubKey": {"key-id:<redacted>"}},
+		},
+		{
+			name:       "with source",
+			withSource: true,
+			want:       map[string][]string{"Dir": {`"argoapps"`, "default"}, "DryRun": {"true", "flag"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := printConfig(&buf, cfg, sources, tt.withSource); err != nil {
+				t.Fatal(err)
+			}
+
+			got := map[string][]string{}
+			for line := range strings.Lines(buf.String()) {
+				fields := strings.Fields(line)
+				got[fields[0]] = fields[1:]
+			}
+
+			for field, want := range tt.want {
+				if !slices.Equal(got[field], want) {
+					t.Errorf("printConfig() %s = %q, want %q", field, got[field], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/BooleanCat/go-functional/v2/it"
+)
+
+// Output formats accepted by --output.
+const (
+	OutputText = "text" // "▶" log lines for people
+	OutputJSON = "json" // One JSON document on stdout for other tools
+)
+
+// outputFormats lists the formats --output accepts.
+func outputFormats() []string {
+	return []string{OutputText, OutputJSON}
+}
+
+// ReportMarkdown is the --report format: a Markdown table of the applied
+// updates, for a pull request body.
+const ReportMarkdown = "markdown"
+
+// Reporter presents what a run found, as log lines or as structured data.
+// Result is called for every result in processing order and returns the
+// result's error, if any, like logResult; Finish is called once after the
+// last result, even when the run stopped early.
+type Reporter struct {
+	Checked func(charts []ChartInfo) error
+	Result  func(r UpdateResult) error
+	Finish  func() error
+}
+
+// newReporter returns the Reporter for cfg.Output.
+func newReporter(cfg Config, out io.Writer, log *Logger) Reporter {
+	if cfg.Output == OutputJSON {
+		return MakeJSONReporter(cfg, out)
+	}
+
+	return MakeTextReporter(cfg, log)
+}
+
+// MakeTextReporter creates the default Reporter, which logs a line per chart to log.
+func MakeTextReporter(cfg Config, log *Logger) Reporter {
+	return Reporter{
+		Checked: func(charts []ChartInfo) error {
+			perRepo := chartsPerRepo(charts)
+
+			log.Info("discovered %d chart(s) with artifacthub comments, %s:",
+				len(charts), plural(len(perRepo), "distinct repo"))
+			ForEach(slices.Values(charts), func(c ChartInfo) {
+				if optedOut(c, cfg.OptOutLabel) {
+					log.Info("  %s → %s (opted out)", c.File, c.Repo)
+					return
+				}
+
+				if c.Pinned {
+					log.Info("  %s → %s (pinned)", c.File, c.Repo)
+					return
+				}
+
+				log.Info("  %s → %s", c.File, c.Repo)
+			})
+
+			for _, repo := range slices.Sorted(maps.Keys(perRepo)) {
+				if n := perRepo[repo]; n > 1 {
+					log.Info("  %s is used by %d charts", repo, n)
+				}
+			}
+
+			return nil
+		},
+		Result: func(r UpdateResult) error { return logResult(r, log) },
+		Finish: func() error { return nil },
+	}
+}
+
+// chartsPerRepo counts the charts following each repository, so that --check
+// shows how many fetches a run needs and which repositories are shared.
+func chartsPerRepo(charts []ChartInfo) map[string]int {
+	counts := make(map[string]int)
+	for _, c := range charts {
+		counts[c.Repo]++
+	}
+
+	return counts
+}
+
+// checkedEntry is the JSON form of a chart listed by --check.
+type checkedEntry struct {
+	File     string `json:"file"`
+	Repo     string `json:"repo"`
+	OptedOut bool   `json:"optedOut,omitempty"`
+	Pinned   bool   `json:"pinned,omitempty"`
+}
+
+// resultEntry is the JSON form of an UpdateResult.
+type resultEntry struct {
+	File    string       `json:"file"`
+	Repo    string       `json:"repo"`
+	Current string       `json:"current"`
+	Latest  string       `json:"latest"`
+	Status  UpdateStatus `json:"status"`
+	Reason  string       `json:"reason,omitempty"`
+	Error   string       `json:"error,omitempty"`
+
+	Warnings   []string `json:"warnings,omitempty"`
+	AppVersion string   `json:"appVersion,omitempty"`
+	Digest     string   `json:"digest,omitempty"`
+}
+
+// MakeJSONReporter creates a Reporter that writes a JSON array to out: the
+// discovered charts for --check, otherwise every result once the run is over.
+// Errors are still returned from Result, so they reach stderr and the exit
+// code as with text output.
+func MakeJSONReporter(cfg Config, out io.Writer) Reporter {
+	entries := []resultEntry{}
+
+	return Reporter{
+		Checked: func(charts []ChartInfo) error {
+			checked := slices.AppendSeq(make([]checkedEntry, 0, len(charts)),
+				it.Map(slices.Values(charts), func(c ChartInfo) checkedEntry {
+					return checkedEntry{File: c.File, Repo: c.Repo, OptedOut: optedOut(c, cfg.OptOutLabel), Pinned: c.Pinned}
+				}))
+
+			return writeJSON(out, "check results", checked)
+		},
+		Result: func(r UpdateResult) error {
+			entry := resultEntry{
+				File: r.File, Repo: r.Repo, Current: r.Current, Latest: r.Latest,
+				Status: r.Status, Reason: r.Reason, Error: "",
+				Warnings: r.Warnings, AppVersion: r.LatestAppVersion, Digest: r.LatestDigest,
+			}
+			if r.Error != nil {
+				entry.Error = r.Error.Error()
+			}
+
+			entries = append(entries, entry)
+
+			return r.Error
+		},
+		Finish: func() error { return writeJSON(out, "results", entries) },
+	}
+}
+
+// writeJSON writes v to w as indented JSON; what names it in errors.
+func writeJSON(w io.Writer, what string, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("encode %s: %w", what, err)
+	}
+
+	return nil
+}
+
+// writeReport writes the --report summary of results to the --report-file
+// path, or to stdout when none is given.
+func writeReport(cfg Config, results *Results, stdout io.Writer) error {
+	if cfg.ReportFile == "" {
+		return writeMarkdownReport(stdout, cfg.DryRun, results)
+	}
+
+	var b strings.Builder
+	if err := writeMarkdownReport(&b, cfg.DryRun, results); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(cfg.ReportFile, []byte(b.String()), 0o600); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+
+	return nil
+}
+
+// writeMarkdownReport writes a header line and a "| Chart | From | To |" table
+// row per updated chart, or just the header when nothing was updated.
+func writeMarkdownReport(w io.Writer, dryRun bool, results *Results) error {
+	updated := results.WithStatus(StatusUpdated)
+
+	var b strings.Builder
+
+	switch {
+	case len(updated) == 0:
+		b.WriteString("No chart updates.\n")
+	case dryRun:
+		fmt.Fprintf(&b, "%s would be updated.\n", plural(len(updated), "chart"))
+	default:
+		fmt.Fprintf(&b, "Updated %s.\n", plural(len(updated), "chart"))
+	}
+
+	if len(updated) > 0 {
+		b.WriteString("\n| Chart | From | To |\n|-------|------|----|\n")
+
+		for _, r := range updated {
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", r.Repo, r.Current, r.Latest)
+		}
+	}
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+
+	return nil
+}
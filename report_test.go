@@ -0,0 +1,238 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONReporterResults(t *testing.T) {
+	var out bytes.Buffer
+
+	report := MakeJSONReporter(Config{}, &out)
+	errBoom := errors.New("boom")
+
+	if err := report.Result(UpdateResult{File: "a.yaml", Repo: "org/a", Current: "1.0.0", Latest: "1.1.0", Status: StatusUpdated, LatestAppVersion: "v2.0.0"}); err != nil {
+		t.Fatalf("Result() error = %v", err)
+	}
+
+	if err := report.Result(UpdateResult{File: "b.yaml", Repo: "org/b", Current: "2.0.0", Status: StatusError, Error: errBoom}); !errors.Is(err, errBoom) {
+		t.Fatalf("Result() error = %v, want %v", err, errBoom)
+	}
+
+	if err := report.Result(UpdateResult{File: "c.yaml", Repo: "org/c", Current: "3.0.0", Status: StatusSkipped, Reason: "opted out"}); err != nil {
+		t.Fatalf("Result() error = %v", err)
+	}
+
+	if out.Len() != 0 {
+		t.Fatalf("output before Finish = %q, want none", out.String())
+	}
+
+	if err := report.Finish(); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+
+	var got []map[string]string
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out.String())
+	}
+
+	want := []map[string]string{
+		{"file": "a.yaml", "repo": "org/a", "current": "1.0.0", "latest": "1.1.0", "status": "updated", "appVersion": "v2.0.0"},
+		{"file": "b.yaml", "repo": "org/b", "current": "2.0.0", "latest": "", "status": "error", "error": "boom"},
+		{"file": "c.yaml", "repo": "org/c", "current": "3.0.0", "latest": "", "status": "skipped", "reason": "opted out"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+}
+
+func TestJSONReporterNoResults(t *testing.T) {
+	var out bytes.Buffer
+
+	report := MakeJSONReporter(Config{}, &out)
+	if err := report.Finish(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := out.String(); got != "[]\n" {
+		t.Errorf("output = %q, want an empty array", got)
+	}
+}
+
+func TestTextReporterQuiet(t *testing.T) {
+	errBoom := errors.New("boom")
+	held := UpdateResult{File: "e.yaml", Repo: "org/e", Current: "1.0.0", Latest: "1.0.0", Status: StatusUpToDate}
+	held.Warnings = []string{"1.1.0 is deprecated"}
+	results := []UpdateResult{
+		{File: "a.yaml", Repo: "org/a", Current: "1.0.0", Latest: "1.0.0", Status: StatusUpToDate},
+		{File: "b.yaml", Repo: "org/b", Current: "1.0.0", Latest: "1.1.0", Status: StatusUpdated},
+		newSkippedResult("c.yaml", "org/c", "1.0.0", "opted out"),
+		newErrorResult("d.yaml", "org/d", errBoom),
+		held,
+	}
+
+	for _, quiet := range []bool{false, true} {
+		var w bytes.Buffer
+
+		cfg := Config{Quiet: quiet}
+		report := MakeTextReporter(cfg, NewLogger(&w, logLevel(cfg)))
+		for _, r := range results {
+			if err := report.Result(r); !errors.Is(err, r.Error) {
+				t.Fatalf("Result(%s) error = %v, want %v", r.File, err, r.Error)
+			}
+		}
+
+		want := "▶ a.yaml: already up to date (1.0.0)\n▶ b.yaml: 1.0.0 → 1.1.0\n▶ c.yaml: skipped (opted out)\n" +
+			"▶ e.yaml: already up to date (1.0.0)\n▶ warning: e.yaml: 1.1.0 is deprecated\n"
+		if quiet {
+			want = "▶ b.yaml: 1.0.0 → 1.1.0\n▶ warning: e.yaml: 1.1.0 is deprecated\n"
+		}
+
+		if got := w.String(); got != want {
+			t.Errorf("quiet %t: output = %q, want %q", quiet, got, want)
+		}
+	}
+}
+
+func TestReporterChecked(t *testing.T) {
+	charts := []ChartInfo{
+		{File: "a.yaml", Repo: "org/a"},
+		{File: "b.yaml", Repo: "org/b", Labels: map[string]string{defaultOptOutLabel: optOutDisabledValue}},
+		{File: "c.yaml", Repo: "org/a"},
+	}
+	cfg := Config{OptOutLabel: defaultOptOutLabel}
+
+	t.Run("text", func(t *testing.T) {
+		var w bytes.Buffer
+
+		if err := MakeTextReporter(cfg, NewLogger(&w, LogNormal)).Checked(charts); err != nil {
+			t.Fatal(err)
+		}
+
+		want := "▶ discovered 3 chart(s) with artifacthub comments, 2 distinct repos:\n" +
+			"▶   a.yaml → org/a\n▶   b.yaml → org/b (opted out)\n▶   c.yaml → org/a\n" +
+			"▶   org/a is used by 2 charts\n"
+		if got := w.String(); got != want {
+			t.Errorf("output = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var out bytes.Buffer
+
+		if err := MakeJSONReporter(cfg, &out).Checked(charts); err != nil {
+			t.Fatal(err)
+		}
+
+		var got []checkedEntry
+		if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+			t.Fatalf("output is not JSON: %v\n%s", err, out.String())
+		}
+
+		want := []checkedEntry{
+			{File: "a.yaml", Repo: "org/a", OptedOut: false},
+			{File: "b.yaml", Repo: "org/b", OptedOut: true},
+			{File: "c.yaml", Repo: "org/a", OptedOut: false},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("checked = %+v, want %+v", got, want)
+		}
+	})
+}
+
+func TestWriteMarkdownReport(t *testing.T) {
+	results := NewResults(
+		UpdateResult{File: "a.yaml", Repo: "cilium/cilium", Current: "1.15.0", Latest: "1.16.0", Status: StatusUpdated},
+		UpdateResult{File: "b.yaml", Repo: "org/b", Current: "2.0.0", Latest: "2.0.0", Status: StatusUpToDate},
+		UpdateResult{File: "c.yaml", Repo: "bitnami/redis", Current: "18.1.0", Latest: "18.2.1", Status: StatusUpdated},
+	)
+
+	tests := []struct {
+		name    string
+		dryRun  bool
+		results *Results
+		want    string
+	}{
+		{
+			name:    "two updates",
+			dryRun:  false,
+			results: results,
+			want: "Updated 2 charts.\n\n" +
+				"| Chart | From | To |\n" +
+				"|-------|------|----|\n" +
+				"| cilium/cilium | 1.15.0 | 1.16.0 |\n" +
+				"| bitnami/redis | 18.1.0 | 18.2.1 |\n",
+		},
+		{
+			name:    "dry run",
+			dryRun:  true,
+			results: NewResults(results.All()[0]),
+			want:    "1 chart would be updated.\n\n| Chart | From | To |\n|-------|------|----|\n| cilium/cilium | 1.15.0 | 1.16.0 |\n",
+		},
+		{
+			name:    "nothing updated",
+			dryRun:  false,
+			results: NewResults(results.All()[1]),
+			want:    "No chart updates.\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+
+			if err := writeMarkdownReport(&out, tt.dryRun, tt.results); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := out.String(); got != tt.want {
+				t.Errorf("report =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteReportFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pr-body.md")
+	results := NewResults(UpdateResult{File: "a.yaml", Repo: "org/a", Current: "1.0.0", Latest: "1.1.0", Status: StatusUpdated})
+
+	var stdout bytes.Buffer
+	if err := writeReport(Config{Report: ReportMarkdown, ReportFile: path}, results, &stdout); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(content), "| org/a | 1.0.0 | 1.1.0 |") {
+		t.Errorf("report file =\n%s\nwant the update row", content)
+	}
+
+	if stdout.Len() != 0 {
+		t.Errorf("stdout = %q, want nothing with --report-file", stdout.String())
+	}
+}
@@ -0,0 +1,60 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"slices"
+
+	"github.com/BooleanCat/go-functional/v2/it"
+)
+
+// Results collects the outcome of every chart in a run, in the order they were
+// added. It is what the reports written after a run, such as the summary line,
+// the history log and --fail-on, read from.
+type Results struct {
+	all []UpdateResult
+}
+
+// NewResults creates a Results holding results.
+func NewResults(results ...UpdateResult) *Results {
+	return &Results{all: slices.Clone(results)}
+}
+
+// Add records the outcome of one more chart.
+func (r *Results) Add(result UpdateResult) {
+	r.all = append(r.all, result)
+}
+
+// All returns every result in the order it was added.
+func (r *Results) All() []UpdateResult {
+	return slices.Clone(r.all)
+}
+
+// Len returns the number of results.
+func (r *Results) Len() int {
+	return len(r.all)
+}
+
+// WithStatus returns the results with the given status, in the order they were added.
+func (r *Results) WithStatus(status UpdateStatus) []UpdateResult {
+	return slices.Collect(it.Filter(slices.Values(r.all), func(result UpdateResult) bool { return result.Status == status }))
+}
+
+// Count returns the number of results with the given status.
+func (r *Results) Count(status UpdateStatus) int {
+	return len(r.WithStatus(status))
+}
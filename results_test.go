@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestResults(t *testing.T) {
+	updatedA := UpdateResult{File: "a.yaml", Repo: "org/a", Current: "1.0.0", Latest: "1.1.0", Status: StatusUpdated}
+	upToDate := UpdateResult{File: "b.yaml", Repo: "org/b", Current: "2.0.0", Latest: "2.0.0", Status: StatusUpToDate}
+	failed := UpdateResult{File: "c.yaml", Repo: "org/c", Status: StatusError, Error: errors.New("boom")}
+	updatedD := UpdateResult{File: "d.yaml", Repo: "org/d", Current: "3.0.0", Latest: "4.0.0", Status: StatusUpdated}
+	skipped := UpdateResult{File: "e.yaml", Repo: "org/e", Current: "1.0.0", Status: StatusSkipped, Reason: "opted out"}
+
+	results := NewResults()
+	for _, r := range []UpdateResult{updatedA, upToDate, failed, updatedD, skipped} {
+		results.Add(r)
+	}
+
+	if got := results.Len(); got != 5 {
+		t.Errorf("Len() = %d, want 5", got)
+	}
+
+	counts := map[UpdateStatus]int{StatusUpdated: 2, StatusUpToDate: 1, StatusError: 1, StatusSkipped: 1, StatusBlocked: 0}
+	for status, want := range counts {
+		if got := results.Count(status); got != want {
+			t.Errorf("Count(%s) = %d, want %d", status, got, want)
+		}
+	}
+
+	if got := results.WithStatus(StatusUpdated); !slices.EqualFunc(got, []UpdateResult{updatedA, updatedD}, sameResult) {
+		t.Errorf("WithStatus(updated) = %+v, want a.yaml and d.yaml in order", got)
+	}
+
+	if got := results.WithStatus(StatusBlocked); len(got) != 0 {
+		t.Errorf("WithStatus(blocked) = %+v, want none", got)
+	}
+
+	if got := results.All(); !slices.EqualFunc(got, []UpdateResult{updatedA, upToDate, failed, updatedD, skipped}, sameResult) {
+		t.Errorf("All() = %+v, want every result in the order added", got)
+	}
+}
+
+func TestResultsAreIsolatedFromCallers(t *testing.T) {
+	initial := []UpdateResult{{File: "a.yaml", Status: StatusUpdated}}
+	results := NewResults(initial...)
+
+	initial[0].Status = StatusError
+	results.All()[0].Status = StatusError
+
+	if got := results.Count(StatusUpdated); got != 1 {
+		t.Errorf("Count(updated) = %d after callers modified their slices, want 1", got)
+	}
+}
+
+func sameResult(a, b UpdateResult) bool {
+	return a.File == b.File && a.Status == b.Status && a.Current == b.Current && a.Latest == b.Latest
+}
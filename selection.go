@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/BooleanCat/go-functional/v2/it"
+)
+
+// Rejection records why a candidate version was not selected.
+type Rejection struct {
+	Version string
+	Reason  string
+}
+
+// Selection records how the latest version was chosen from the published candidates.
+type Selection struct {
+	Candidates []string    // Every usable version returned by the source, newest first
+	Rejected   []Rejection // Candidates removed by a filter, in filter order
+}
+
+// versionFilter keeps candidate versions that pass and rejects the rest for reason.
+type versionFilter struct {
+	reason string
+	keep   func(v string) bool
+}
+
+// selectionFilters returns the filters applied to candidates for the query.
+func selectionFilters(q VersionQuery) []versionFilter {
+	stable := func(v string) bool { return q.AllowPrerelease || isStableUnder(q.Stability, v) }
+	filters := []versionFilter{{reason: "pre-release", keep: stable}}
+
+	if q.PrereleaseSameMajor {
+		filters[0] = versionFilter{
+			reason: "pre-release outside the current major",
+			keep:   func(v string) bool { return stable(v) || sameMajor(v, q.Current) },
+		}
+	}
+
+	if isPartialPin(q.Current) {
+		filters = append(filters, versionFilter{
+			reason: "outside the " + q.Current + ".x line",
+			keep:   func(v string) bool { return inLine(v, q.Current) },
+		})
+	}
+
+	if q.Constraint != "" {
+		filters = append(filters, versionFilter{
+			reason: constraintReason(q.Constraint),
+			keep:   func(v string) bool { return satisfies(v, q.Constraint) },
+		})
+	}
+
+	if len(q.Ignore) > 0 {
+		filters = append(filters, ignoreFilter(q.Ignore))
+	}
+
+	return filters
+}
+
+// constraintReason is the rejection reason for versions outside constraint.
+func constraintReason(constraint string) string {
+	return "outside constraint " + constraint
+}
+
+// constraintBlocked reports whether the query's constraint rejected every
+// candidate the other filters left, so nothing could be selected.
+func constraintBlocked(sel Selection, q VersionQuery) bool {
+	return q.Constraint != "" && slices.ContainsFunc(sel.Rejected, func(r Rejection) bool {
+		return r.Reason == constraintReason(q.Constraint)
+	})
+}
+
+// constraintError explains that the constraint left no version to select.
+func constraintError(constraint string, sel Selection) error {
+	blocked := it.Filter(slices.Values(sel.Rejected), func(r Rejection) bool {
+		return r.Reason == constraintReason(constraint)
+	})
+
+	return fmt.Errorf("no versions satisfy constraint %q, %s outside it",
+		constraint, plural(len(slices.Collect(blocked)), "candidate"))
+}
+
+// ignoreFilter rejects the versions a source comment lists as "!version".
+func ignoreFilter(ignore []string) versionFilter {
+	return versionFilter{
+		reason: "ignored by the source comment",
+		keep:   func(v string) bool { return !slices.Contains(ignore, v) },
+	}
+}
+
+// selectVersion applies the query's filters to versions and returns the highest
+// remaining version along with a record of what was rejected and why.
+func selectVersion(versions []string, q VersionQuery) (string, Selection, bool) {
+	sel := Selection{Candidates: versions, Rejected: nil}
+	remaining := versions
+
+	for _, f := range selectionFilters(q) {
+		rejected := it.Filter(slices.Values(remaining), func(v string) bool { return !f.keep(v) })
+		sel.Rejected = slices.AppendSeq(sel.Rejected, it.Map(rejected, func(v string) Rejection {
+			return Rejection{Version: v, Reason: f.reason}
+		}))
+		remaining = slices.Collect(it.Filter(slices.Values(remaining), f.keep))
+	}
+
+	if len(remaining) == 0 {
+		return "", sel, false
+	}
+
+	return slices.MaxFunc(remaining, compareVersions), sel, true
+}
@@ -0,0 +1,180 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelectVersionRecordsRejections(t *testing.T) {
+	versions := []string{"1.14.9", "1.15.2", "1.15.3-rc1", "1.16.0"}
+
+	got, sel, ok := selectVersion(versions, VersionQuery{Repo: "org/chart", Current: "1.15"})
+	if !ok || got != "1.15.2" {
+		t.Fatalf("selectVersion() = %q, %v, want %q", got, ok, "1.15.2")
+	}
+
+	if !reflect.DeepEqual(sel.Candidates, versions) {
+		t.Errorf("Candidates = %v, want %v", sel.Candidates, versions)
+	}
+
+	want := []Rejection{
+		{Version: "1.15.3-rc1", Reason: "pre-release"},
+		{Version: "1.14.9", Reason: "outside the 1.15.x line"},
+		{Version: "1.16.0", Reason: "outside the 1.15.x line"},
+	}
+	if !reflect.DeepEqual(sel.Rejected, want) {
+		t.Errorf("Rejected = %+v, want %+v", sel.Rejected, want)
+	}
+}
+
+func TestSelectVersionNothingLeft(t *testing.T) {
+	_, sel, ok := selectVersion([]string{"2.0.0-beta"}, VersionQuery{Repo: "org/chart", Current: ""})
+	if ok {
+		t.Error("selectVersion() ok = true, want false")
+	}
+
+	want := []Rejection{{Version: "2.0.0-beta", Reason: "pre-release"}}
+	if !reflect.DeepEqual(sel.Rejected, want) {
+		t.Errorf("Rejected = %+v, want %+v", sel.Rejected, want)
+	}
+}
+
+func TestSelectVersionAllowPrerelease(t *testing.T) {
+	versions := []string{"2.0.0-rc.1", "1.9.0", "1.9.0-rc.2"}
+
+	if got, _, _ := selectVersion(versions, VersionQuery{Repo: "org/chart", Current: "1.9.0"}); got != "1.9.0" {
+		t.Errorf("stable only: selectVersion() = %q, want %q", got, "1.9.0")
+	}
+
+	got, sel, ok := selectVersion(versions, VersionQuery{Repo: "org/chart", Current: "1.9.0", AllowPrerelease: true, Constraint: "", Ignore: nil})
+	if !ok || got != "2.0.0-rc.1" {
+		t.Errorf("allowed: selectVersion() = %q, %v, want %q", got, ok, "2.0.0-rc.1")
+	}
+
+	if len(sel.Rejected) != 0 {
+		t.Errorf("Rejected = %+v, want none", sel.Rejected)
+	}
+}
+
+func TestSelectVersionPrereleaseWithinCurrentMajor(t *testing.T) {
+	tests := []struct {
+		name     string
+		versions []string
+		policy   bool
+		want     string
+	}{
+		{
+			name:     "same-major pre-release accepted with policy",
+			versions: []string{"1.15.2", "1.16.0-rc.1"},
+			policy:   true,
+			want:     "1.16.0-rc.1",
+		},
+		{
+			name:     "new-major pre-release rejected with policy",
+			versions: []string{"1.15.2", "2.0.0-rc.1"},
+			policy:   true,
+			want:     "1.15.2",
+		},
+		{
+			name:     "new-major stable accepted with policy",
+			versions: []string{"1.15.2", "1.16.0-rc.1", "2.0.0"},
+			policy:   true,
+			want:     "2.0.0",
+		},
+		{
+			name:     "same-major stable beats its own pre-release",
+			versions: []string{"1.16.0-rc.2", "1.16.0"},
+			policy:   true,
+			want:     "1.16.0",
+		},
+		{
+			name:     "same-major pre-release rejected without policy",
+			versions: []string{"1.15.2", "1.16.0-rc.1"},
+			policy:   false,
+			want:     "1.15.2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := VersionQuery{Repo: "org/chart", Current: "1.15.2", Timeout: 0, Limit: 0, PrereleaseSameMajor: tt.policy, Stability: StabilityDash, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil}
+
+			got, _, ok := selectVersion(tt.versions, q)
+			if !ok || got != tt.want {
+				t.Errorf("selectVersion() = %q, %v, want %q", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectVersionPrereleasePolicyReason(t *testing.T) {
+	q := VersionQuery{Repo: "org/chart", Current: "1.15.2", Timeout: 0, Limit: 0, PrereleaseSameMajor: true, Stability: StabilityDash, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil}
+
+	_, sel, _ := selectVersion([]string{"1.15.2", "2.0.0-rc.1"}, q)
+
+	want := []Rejection{{Version: "2.0.0-rc.1", Reason: "pre-release outside the current major"}}
+	if !reflect.DeepEqual(sel.Rejected, want) {
+		t.Errorf("Rejected = %+v, want %+v", sel.Rejected, want)
+	}
+}
+
+func TestSelectVersionStabilityRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     StabilityRule
+		versions []string
+		want     string
+		wantOK   bool
+	}{
+		{name: "dash rejects dated tags", rule: StabilityDash, versions: []string{"2023-01-01", "2024-06-30"}, want: "", wantOK: false},
+		{name: "empty rule is dash", rule: "", versions: []string{"1.0.0", "2024-06-30"}, want: "1.0.0", wantOK: true},
+		{name: "semver accepts dated tags", rule: StabilitySemverPrerelease, versions: []string{"2023-01-01", "2024-06-30"}, want: "2024-06-30", wantOK: true},
+		{name: "semver still rejects pre-releases", rule: StabilitySemverPrerelease, versions: []string{"1.15.2", "1.16.0-rc.1"}, want: "1.15.2", wantOK: true},
+		{name: "none accepts pre-releases", rule: StabilityNone, versions: []string{"1.15.2", "1.16.0-rc.1"}, want: "1.16.0-rc.1", wantOK: true},
+		{name: "none orders pre-release-only repos", rule: StabilityNone, versions: []string{"1.2.0-rc.2", "1.2.0-rc.11", "1.2.0-rc.1"}, want: "1.2.0-rc.11", wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := VersionQuery{Repo: "org/chart", Current: "", Timeout: 0, Limit: 0, PrereleaseSameMajor: false, Stability: tt.rule, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil}
+
+			got, _, ok := selectVersion(tt.versions, q)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("selectVersion() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestSelectVersionIgnore(t *testing.T) {
+	versions := []string{"2.3.1", "2.3.0", "2.2.1", "2.2.0"}
+
+	got, sel, ok := selectVersion(versions, VersionQuery{Repo: "org/chart", Current: "2.2.0", Ignore: []string{"2.3.0", "2.3.1"}})
+	if !ok || got != "2.2.1" {
+		t.Fatalf("selectVersion() = %q, %v, want %q", got, ok, "2.2.1")
+	}
+
+	want := []Rejection{
+		{Version: "2.3.1", Reason: "ignored by the source comment"},
+		{Version: "2.3.0", Reason: "ignored by the source comment"},
+	}
+	if !reflect.DeepEqual(sel.Rejected, want) {
+		t.Errorf("Rejected = %+v, want %+v", sel.Rejected, want)
+	}
+}
@@ -0,0 +1,66 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/BooleanCat/go-functional/v2/it"
+	"gopkg.in/yaml.v3"
+)
+
+// MakeSuggestionWriter creates a YAMLWriter that leaves files untouched and
+// instead prints a GitHub suggestion block for each updated targetRevision line,
+// keyed by file and line number so a review bot can post it as-is.
+func MakeSuggestionWriter(out io.Writer) YAMLWriter {
+	return func(_ context.Context, path string, docs []*yaml.Node) error {
+		//nolint:gosec // path is validated to be within base directory in config.go
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read original file: %w", err)
+		}
+
+		lines := strings.Split(string(data), "\n")
+
+		apps := it.Filter(slices.Values(docs), func(n *yaml.Node) bool {
+			return isSupportedKind(n)
+		})
+
+		return ForEachWithError(apps, func(d *yaml.Node) error {
+			return writeSuggestion(out, path, lines, targetRevisionNode(d))
+		})
+	}
+}
+
+func writeSuggestion(out io.Writer, path string, lines []string, node *yaml.Node) error {
+	if node == nil || node.Line < 1 || node.Line > len(lines) {
+		return fmt.Errorf("%s: targetRevision position unknown", path)
+	}
+
+	replacement := replaceScalarAt(lines[node.Line-1], node.Column, node.Style, node.Value)
+
+	if _, err := fmt.Fprintf(out, "%s:%d\n```suggestion\n%s\n```\n", path, node.Line, replacement); err != nil {
+		return fmt.Errorf("write suggestion: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestSuggestionWriter(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name: "plain scalar",
+			content: `# artifacthub: org/chart
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+spec:
+  source:
+    chart: chart
+    targetRevision: 1.0.0 # pinned by bot
+`,
+			want: "%s:7\n```suggestion\n    targetRevision: 1.1.0 # pinned by bot\n```\n",
+		},
+		{
+			name: "quoted scalar in second document",
+			content: `kind: Secret
+metadata:
+  name: creds
+---
+kind: Application
+spec:
+  source:
+    targetRevision: "1.0.0"
+`,
+			want: "%s:8\n```suggestion\n    targetRevision: \"1.1.0\"\n```\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), testAppFile)
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			docs, err := readYAMLDocuments(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			updateDocuments(docs, "1.1.0")
+
+			var buf bytes.Buffer
+			if err := MakeSuggestionWriter(&buf)(context.Background(), path, docs); err != nil {
+				t.Fatalf("suggestion writer error = %v", err)
+			}
+
+			if want := fmt.Sprintf(tt.want, path); buf.String() != want {
+				t.Errorf("suggestion =\n%s\nwant\n%s", buf.String(), want)
+			}
+
+			after, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(after) != tt.content {
+				t.Error("suggestion writer modified the file")
+			}
+		})
+	}
+}
+
+func TestReplaceScalarAt(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		col   int
+		style yaml.Style
+		want  string
+	}{
+		{"plain", "    targetRevision: 1.0.0", 21, 0, "    targetRevision: 2.0.0"},
+		{"trailing comment", "  targetRevision: 1.0.0  # keep", 19, 0, "  targetRevision: 2.0.0  # keep"},
+		{"double quoted", `  targetRevision: "1.0.0"`, 19, yaml.DoubleQuotedStyle, `  targetRevision: "2.0.0"`},
+		{"single quoted", `  targetRevision: '1.0.0'`, 19, yaml.SingleQuotedStyle, `  targetRevision: '2.0.0'`},
+		{"column out of range", "targetRevision: 1.0.0", 99, 0, "targetRevision: 1.0.0"},
+		{"multibyte before", "{café: x, targetRevision: 1.0.0}", 27, 0, "{café: x, targetRevision: 2.0.0}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := replaceScalarAt(tt.line, tt.col, tt.style, "2.0.0"); got != tt.want {
+				t.Errorf("replaceScalarAt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"text/template"
+)
+
+// defaultSummaryFormat is the summary line printed when --summary-format is not set.
+const defaultSummaryFormat = "{{.Updated}} updated, {{.UpToDate}} up to date, {{.Errors}} errors, {{.Skipped}} skipped" +
+	"{{if .Blocked}}, {{.Blocked}} blocked{{end}}{{if .Held}}, {{.Held}} held{{end}}" +
+	"{{if .Pinned}}, {{.Pinned}} pinned{{end}}"
+
+// Summary counts the outcomes of a run. Its fields are what a --summary-format
+// template can refer to.
+type Summary struct {
+	Updated  int
+	UpToDate int
+	Errors   int
+	Skipped  int
+	Blocked  int
+	Held     int
+	Pinned   int
+}
+
+// summarize counts results by status.
+func summarize(results *Results) Summary {
+	return Summary{
+		Updated:  results.Count(StatusUpdated),
+		UpToDate: results.Count(StatusUpToDate),
+		Errors:   results.Count(StatusError),
+		Skipped:  results.Count(StatusSkipped),
+		Blocked:  results.Count(StatusBlocked),
+		Held:     results.Count(StatusHeld),
+		Pinned:   results.Count(StatusPinned),
+	}
+}
+
+// parseSummaryFormat parses a summary template, the built-in one when format
+// is empty. The template is also executed once against an empty Summary so
+// that references to unknown fields fail here rather than after the run.
+func parseSummaryFormat(format string) (*template.Template, error) {
+	if format == "" {
+		format = defaultSummaryFormat
+	}
+
+	tmpl, err := template.New("summary").Parse(format)
+	if err != nil {
+		return nil, fmt.Errorf("invalid summary format: %w", err)
+	}
+
+	if err := tmpl.Execute(io.Discard, Summary{}); err != nil {
+		return nil, fmt.Errorf("invalid summary format: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+// printSummary logs the summary line rendered from format.
+func printSummary(log *Logger, format string, s Summary) error {
+	tmpl, err := parseSummaryFormat(format)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, s); err != nil {
+		return fmt.Errorf("render summary: %w", err)
+	}
+
+	log.Notice("%s", buf.String())
+
+	return nil
+}
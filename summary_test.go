@@ -0,0 +1,115 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	got := summarize(NewResults([]UpdateResult{
+		{File: "a.yaml", Status: StatusUpdated},
+		{File: "b.yaml", Status: StatusUpdated},
+		{File: "c.yaml", Status: StatusUpToDate},
+		{File: "d.yaml", Status: StatusError, Error: errors.New("boom")},
+		{File: "e.yaml", Status: StatusSkipped},
+		{File: "f.yaml", Status: StatusBlocked},
+		{File: "g.yaml", Status: StatusHeld},
+	}...))
+
+	want := Summary{Updated: 2, UpToDate: 1, Errors: 1, Skipped: 1, Blocked: 1, Held: 1}
+	if got != want {
+		t.Errorf("summarize() = %+v, want %+v", got, want)
+	}
+}
+
+func TestPrintSummary(t *testing.T) {
+	counts := Summary{Updated: 2, UpToDate: 5, Errors: 1, Skipped: 0, Blocked: 0, Held: 0}
+
+	tests := []struct {
+		name    string
+		format  string
+		summary Summary
+		want    string
+	}{
+		{
+			name:    "built-in",
+			format:  "",
+			summary: counts,
+			want:    "▶ 2 updated, 5 up to date, 1 errors, 0 skipped\n",
+		},
+		{
+			name:    "built-in with blocked charts",
+			format:  "",
+			summary: Summary{Updated: 0, UpToDate: 1, Errors: 0, Skipped: 0, Blocked: 3, Held: 0},
+			want:    "▶ 0 updated, 1 up to date, 0 errors, 0 skipped, 3 blocked\n",
+		},
+		{
+			name:    "built-in with held charts",
+			format:  "",
+			summary: Summary{Updated: 1, UpToDate: 0, Errors: 0, Skipped: 0, Blocked: 0, Held: 2},
+			want:    "▶ 1 updated, 0 up to date, 0 errors, 0 skipped, 2 held\n",
+		},
+		{
+			name:    "custom key-value",
+			format:  "updated={{.Updated}} up_to_date={{.UpToDate}} errors={{.Errors}} skipped={{.Skipped}}",
+			summary: counts,
+			want:    "▶ updated=2 up_to_date=5 errors=1 skipped=0\n",
+		},
+		{
+			name:    "custom with logic",
+			format:  `{{if .Errors}}FAILED{{else}}OK{{end}} ({{.Updated}} changed)`,
+			summary: counts,
+			want:    "▶ FAILED (2 changed)\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			if err := printSummary(NewLogger(&buf, LogNormal), tt.format, tt.summary); err != nil {
+				t.Fatalf("printSummary() error = %v", err)
+			}
+
+			if got := buf.String(); got != tt.want {
+				t.Errorf("printSummary() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSummaryFormatErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+	}{
+		{name: "unclosed action", format: "{{.Updated"},
+		{name: "unknown field", format: "{{.Failed}} failed"},
+		{name: "unknown function", format: "{{upper .Updated}}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseSummaryFormat(tt.format); err == nil {
+				t.Errorf("parseSummaryFormat(%q) error = nil, want error", tt.format)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// TransformOp names a VersionTransform operation.
+type TransformOp string
+
+const (
+	TransformPrefix  TransformOp = "prefix"  // Prepend Value
+	TransformSuffix  TransformOp = "suffix"  // Append Value
+	TransformReplace TransformOp = "replace" // Replace every Old with Value
+)
+
+// VersionTransform deterministically rewrites a fetched version into the form
+// the manifest stores, such as "1.2.3" into "1.2.3-ce".
+type VersionTransform struct {
+	Op    TransformOp
+	Old   string // Text replaced by TransformReplace, empty otherwise
+	Value string // Text added, or the replacement for TransformReplace
+}
+
+// parseTransforms parses the whitespace-separated "op=arg" list of an
+// artifacthub-transform annotation, as in "prefix=v suffix=-ce". A replace
+// argument has the form "old:new".
+func parseTransforms(value string) ([]VersionTransform, error) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return nil, errors.New("no transform given")
+	}
+
+	transforms := make([]VersionTransform, 0, len(fields))
+
+	for _, field := range fields {
+		op, arg, found := strings.Cut(field, "=")
+		if !found || arg == "" {
+			return nil, fmt.Errorf("%q: want op=value", field)
+		}
+
+		t := VersionTransform{Op: TransformOp(op), Old: "", Value: arg}
+
+		switch t.Op {
+		case TransformPrefix, TransformSuffix:
+		case TransformReplace:
+			old, replacement, ok := strings.Cut(arg, ":")
+			if !ok || old == "" {
+				return nil, fmt.Errorf("%q: want replace=old:new", field)
+			}
+
+			t.Old, t.Value = old, replacement
+		default:
+			return nil, fmt.Errorf("%q: unknown transform %q (want prefix, suffix or replace)", field, op)
+		}
+
+		transforms = append(transforms, t)
+	}
+
+	return transforms, nil
+}
+
+// applyTransforms applies transforms to version in order.
+func applyTransforms(version string, transforms []VersionTransform) string {
+	for _, t := range transforms {
+		switch t.Op {
+		case TransformPrefix:
+			version = t.Value + version
+		case TransformSuffix:
+			version += t.Value
+		case TransformReplace:
+			version = strings.ReplaceAll(version, t.Old, t.Value)
+		}
+	}
+
+	return version
+}
@@ -0,0 +1,84 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTransforms(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []VersionTransform
+		wantErr string
+	}{
+		{
+			name:    "suffix",
+			value:   " suffix=-ce ",
+			want:    []VersionTransform{{Op: TransformSuffix, Old: "", Value: "-ce"}},
+			wantErr: "",
+		},
+		{
+			name:  "prefix and replace in order",
+			value: "prefix=v\treplace=+:_",
+			want: []VersionTransform{
+				{Op: TransformPrefix, Old: "", Value: "v"},
+				{Op: TransformReplace, Old: "+", Value: "_"},
+			},
+			wantErr: "",
+		},
+		{
+			name:    "replace with empty replacement",
+			value:   "replace=-ce:",
+			want:    []VersionTransform{{Op: TransformReplace, Old: "-ce", Value: ""}},
+			wantErr: "",
+		},
+		{name: "empty", value: " ", want: nil, wantErr: "no transform given"},
+		{name: "missing value", value: "suffix=", want: nil, wantErr: `"suffix=": want op=value`},
+		{name: "replace without separator", value: "replace=+", want: nil, wantErr: `"replace=+": want replace=old:new`},
+		{name: "unknown op", value: "upper=yes", want: nil, wantErr: `"upper=yes": unknown transform "upper" (want prefix, suffix or replace)`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTransforms(tt.value)
+			assertError(t, tt.wantErr, err)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseTransforms() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyTransforms(t *testing.T) {
+	transforms := []VersionTransform{
+		{Op: TransformReplace, Old: "+", Value: "-"},
+		{Op: TransformPrefix, Old: "", Value: "v"},
+		{Op: TransformSuffix, Old: "", Value: "-ce"},
+	}
+
+	if got := applyTransforms("1.2.3+1", transforms); got != "v1.2.3-1-ce" {
+		t.Errorf("applyTransforms() = %q, want %q", got, "v1.2.3-1-ce")
+	}
+
+	if got := applyTransforms("1.2.3", nil); got != "1.2.3" {
+		t.Errorf("applyTransforms() without transforms = %q, want %q", got, "1.2.3")
+	}
+}
@@ -0,0 +1,223 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change, as
+// in git's default unified diff.
+const diffContext = 3
+
+// diffLine is one line of an edit script.
+type diffLine struct {
+	op   byte   // ' ' for an unchanged line, '-' for a removed one, '+' for an added one
+	text string // Including its trailing newline, if it has one
+}
+
+// diffHunk is the span [start, end) of an edit script printed as one hunk,
+// starting at line aLine of the old file and bLine of the new one.
+type diffHunk struct {
+	start, end   int
+	aLine, bLine int
+}
+
+// unifiedDiff returns a unified diff turning before into after, with --- and
+// +++ headers naming fromName and toName, or "" when the two are equal.
+func unifiedDiff(fromName, toName string, before, after []byte) string {
+	lines := editScript(splitLines(string(before)), splitLines(string(after)))
+	if !slices.ContainsFunc(lines, func(l diffLine) bool { return l.op != ' ' }) {
+		return ""
+	}
+
+	var b strings.Builder
+
+	_, _ = fmt.Fprintf(&b, "--- %s\n+++ %s\n", fromName, toName)
+	for _, h := range diffHunks(lines) {
+		writeHunk(&b, h, lines[h.start:h.end])
+	}
+
+	return b.String()
+}
+
+// splitLines splits s after every newline. A final line without a newline is
+// kept as is so that adding or removing it shows up in the diff.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+// editScript returns the shortest edit script turning a into b, from a longest
+// common subsequence of the lines between their common prefix and suffix.
+// Within a change, removed lines come before added ones, as git prints them.
+func editScript(a, b []string) []diffLine {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	// lcs[i][j] is the length of the longest common subsequence of midA[i:] and midB[j:].
+	lcs := make([][]int, len(midA)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(midB)+1)
+	}
+
+	for i := len(midA) - 1; i >= 0; i-- {
+		for j := len(midB) - 1; j >= 0; j-- {
+			if midA[i] == midB[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	lines := make([]diffLine, 0, len(a)+len(midB))
+	for _, l := range a[:prefix] {
+		lines = append(lines, diffLine{op: ' ', text: l})
+	}
+
+	for i, j := 0, 0; i < len(midA) || j < len(midB); {
+		switch {
+		case i < len(midA) && j < len(midB) && midA[i] == midB[j]:
+			lines = append(lines, diffLine{op: ' ', text: midA[i]})
+			i++
+			j++
+		case j == len(midB) || (i < len(midA) && lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, diffLine{op: '-', text: midA[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{op: '+', text: midB[j]})
+			j++
+		}
+	}
+
+	for _, l := range a[len(a)-suffix:] {
+		lines = append(lines, diffLine{op: ' ', text: l})
+	}
+
+	return lines
+}
+
+// diffHunks groups the changes in lines into hunks with diffContext unchanged
+// lines around them, merging changes that are close enough to share context.
+func diffHunks(lines []diffLine) []diffHunk {
+	// aAt[i] and bAt[i] are the old and new line numbers of lines[i].
+	aAt, bAt := make([]int, len(lines)), make([]int, len(lines))
+	aLine, bLine := 1, 1
+
+	for i, l := range lines {
+		aAt[i], bAt[i] = aLine, bLine
+
+		if l.op != '+' {
+			aLine++
+		}
+
+		if l.op != '-' {
+			bLine++
+		}
+	}
+
+	var hunks []diffHunk
+
+	for i := 0; i < len(lines); {
+		if lines[i].op == ' ' {
+			i++
+			continue
+		}
+
+		start, end := max(i-diffContext, 0), i
+		for end < len(lines) {
+			if lines[end].op != ' ' {
+				end++
+				continue
+			}
+
+			run := end
+			for run < len(lines) && lines[run].op == ' ' {
+				run++
+			}
+
+			if run == len(lines) || run-end > 2*diffContext {
+				end = min(end+diffContext, len(lines))
+				break
+			}
+
+			end = run
+		}
+
+		hunks = append(hunks, diffHunk{start: start, end: end, aLine: aAt[start], bLine: bAt[start]})
+		i = end
+	}
+
+	return hunks
+}
+
+// writeHunk writes the @@ header and the lines of hunk h to b.
+func writeHunk(b *strings.Builder, h diffHunk, lines []diffLine) {
+	aCount, bCount := 0, 0
+
+	for _, l := range lines {
+		if l.op != '+' {
+			aCount++
+		}
+
+		if l.op != '-' {
+			bCount++
+		}
+	}
+
+	_, _ = fmt.Fprintf(b, "@@ -%s +%s @@\n", hunkRange(h.aLine, aCount), hunkRange(h.bLine, bCount))
+
+	for _, l := range lines {
+		b.WriteByte(l.op)
+		b.WriteString(l.text)
+
+		if !strings.HasSuffix(l.text, "\n") {
+			b.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// hunkRange formats the start and length of one side of a hunk header. Like
+// diff(1), an empty side is numbered after the line it follows and a length of
+// one is left out.
+func hunkRange(start, n int) string {
+	switch n {
+	case 0:
+		return strconv.Itoa(start-1) + ",0"
+	case 1:
+		return strconv.Itoa(start)
+	default:
+		return strconv.Itoa(start) + "," + strconv.Itoa(n)
+	}
+}
@@ -0,0 +1,120 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiffSingleLine(t *testing.T) {
+	before := strings.Replace(diffTestManifest, "%s", "1.0.0", 1)
+	after := strings.Replace(diffTestManifest, "%s", "1.1.0", 1)
+
+	want := `--- a/app.yaml
++++ b/app.yaml
+@@ -3,4 +3,4 @@
+ kind: Application
+ spec:
+   source:
+-    targetRevision: 1.0.0
++    targetRevision: 1.1.0
+`
+
+	if got := unifiedDiff("a/app.yaml", "b/app.yaml", []byte(before), []byte(after)); got != want {
+		t.Errorf("unifiedDiff() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   string
+	}{
+		{
+			name:   "equal",
+			before: "a\nb\n",
+			after:  "a\nb\n",
+			want:   "",
+		},
+		{
+			name:   "separate hunks",
+			before: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			after:  "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n",
+			want: "--- a\n+++ b\n" +
+				"@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n" +
+				"@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+twelve\n",
+		},
+		{
+			name:   "merged hunk",
+			before: "1\n2\n3\n4\n5\n",
+			after:  "one\n2\n3\n4\nfive\n",
+			want:   "--- a\n+++ b\n@@ -1,5 +1,5 @@\n-1\n+one\n 2\n 3\n 4\n-5\n+five\n",
+		},
+		{
+			name:   "insertion into empty file",
+			before: "",
+			after:  "a\n",
+			want:   "--- a\n+++ b\n@@ -0,0 +1 @@\n+a\n",
+		},
+		{
+			name:   "missing final newline",
+			before: "a\nb",
+			after:  "a\nb\n",
+			want:   "--- a\n+++ b\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unifiedDiff("a", "b", []byte(tt.before), []byte(tt.after)); got != tt.want {
+				t.Errorf("unifiedDiff() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuiltinDiffWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.yaml")
+	writeManifest(t, path, "1.0.0")
+
+	docs, err := readYAMLDocuments(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updateDocuments(docs, "1.1.0")
+
+	var out bytes.Buffer
+	if err := MakeBuiltinDiffWriter(&out, false)(context.Background(), path, docs); err != nil {
+		t.Fatalf("MakeBuiltinDiffWriter() error = %v", err)
+	}
+
+	if !strings.Contains(out.String(), "-    targetRevision: 1.0.0\n+    targetRevision: 1.1.0\n") {
+		t.Errorf("diff = %q, want the targetRevision change", out.String())
+	}
+
+	if content, _ := os.ReadFile(path); !strings.Contains(string(content), "1.0.0") {
+		t.Errorf("MakeBuiltinDiffWriter() modified %s", path)
+	}
+}
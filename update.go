@@ -18,9 +18,11 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"path/filepath"
 	"slices"
+	"time"
 
 	"github.com/BooleanCat/go-functional/v2/it"
 	"gopkg.in/yaml.v3"
@@ -32,6 +34,10 @@ const (
 	StatusUpToDate UpdateStatus = "up-to-date"
 	StatusUpdated  UpdateStatus = "updated"
 	StatusError    UpdateStatus = "error"
+	StatusSkipped  UpdateStatus = "skipped"
+	StatusBlocked  UpdateStatus = "blocked"
+	StatusHeld     UpdateStatus = "held"   // A newer version exists but is beyond --max-bump
+	StatusPinned   UpdateStatus = "pinned" // The source comment pins the chart, so it was not checked
 )
 
 type UpdateResult struct {
@@ -41,6 +47,13 @@ type UpdateResult struct {
 	Latest  string
 	Status  UpdateStatus
 	Error   error
+	Reason  string // Why the chart was skipped, blocked, held or pinned, set only for those statuses
+
+	Selection        Selection // How Latest was chosen, for --explain-version
+	LatestReleasedAt time.Time // When Latest was released, zero if unknown
+	Warnings         []string  // Problems worth reporting that do not fail the chart, such as deprecation
+	LatestAppVersion string    // App version packaged by Latest, empty if unknown
+	LatestDigest     string    // Digest of Latest's chart package, empty if unknown
 }
 
 type (
@@ -53,49 +66,216 @@ func MakeChartUpdater(
 	read YAMLReader,
 	fetch VersionFetcher,
 	write YAMLWriter,
-) func(ctx context.Context, file, repo string) UpdateResult {
-	return func(ctx context.Context, file, repo string) UpdateResult {
-		path := filepath.Join(cfg.Dir, file)
+	now Clock,
+	debug LogFunc,
+) func(ctx context.Context, chart ChartInfo) UpdateResult {
+	return func(ctx context.Context, chart ChartInfo) UpdateResult {
+		file, repo := chart.File, chart.Repo
+		path := chartPath(cfg, chart)
 
 		docs, err := read(path)
 		if err != nil {
 			return newErrorResult(file, repo, err)
 		}
 
-		current, found := findCurrentVersion(docs)
+		current, found := findChartVersion(docs, chart)
 		if !found {
 			return newErrorResult(file, repo, fmt.Errorf("failed to read current version in %s", file))
 		}
 
-		latest, err := fetch(ctx, repo)
+		if optedOut(chart, cfg.OptOutLabel) {
+			return newSkippedResult(file, repo, current, fmt.Sprintf("opted out via %s: %s", cfg.OptOutLabel, optOutDisabledValue))
+		}
+
+		if chart.Pinned {
+			return newPinnedResult(file, repo, current)
+		}
+
+		info, err := fetch(ctx, VersionQuery{
+			Repo:    repo,
+			Current: current,
+			Timeout: chart.Timeout,
+			Limit:   cfg.FetchLimit,
+
+			PrereleaseSameMajor: cfg.PrereleaseSameMajor,
+			Stability:           cfg.StableRule,
+			RequireSigned:       cfg.RequireSigned,
+
+			Source:          chart.Source,
+			AllowPrerelease: chart.AllowPrerelease,
+			Constraint:      chart.Constraint,
+			Ignore:          chart.Ignore,
+		})
 		if err != nil {
+			if cfg.SkipUnreachable || errors.Is(err, errRequestQuota) {
+				return newSkippedResult(file, repo, current, err.Error())
+			}
+
 			return newErrorResultWithCurrent(file, repo, current, err)
 		}
 
-		if !versionLess(current, latest) {
-			return UpdateResult{
-				File:    file,
-				Repo:    repo,
-				Current: current,
-				Latest:  latest,
-				Status:  StatusUpToDate,
-				Error:   nil,
+		latest := applyTransforms(info.Version, chart.Transforms)
+
+		// Refuse to touch a manifest pinned ahead of what the source offers.
+		if cfg.NeverDowngrade && !isPartialPin(current) && versionLess(latest, current) {
+			return newFetchedResult(file, repo, current, latest, info, StatusBlocked,
+				fmt.Sprintf("latest %s is lower than current %s", latest, current))
+		}
+
+		// Fall back to the highest version within --max-bump, or hold the chart
+		// at current when there is none.
+		if !isPartialPin(current) && exceedsBump(current, latest, cfg.MaxBump) {
+			bounded, ok := boundedVersion(info.Selection, chart.Transforms, current, cfg.MaxBump)
+			if !ok {
+				return newFetchedResult(file, repo, current, latest, info, StatusHeld,
+					fmt.Sprintf("latest %s is a %s bump from %s, beyond --max-bump %s",
+						latest, bumpLevel(current, latest), current, cfg.MaxBump))
 			}
+
+			latest = bounded
 		}
 
-		updateDocuments(docs, latest)
+		// A partial pin such as "1.15" keeps its style; the resolved patch is only reported.
+		switch {
+		case isPartialPin(current):
+			debug("%s: %s is a partial pin resolving to %s, left as is", file, current, latest)
+		case versionLess(current, latest):
+			debug("%s: current %s is lower than latest %s, updating", file, current, latest)
+		default:
+			debug("%s: current %s is not lower than latest %s, nothing to update", file, current, latest)
+		}
 
-		if writeErr := write(ctx, path, docs); writeErr != nil {
+		if isPartialPin(current) || !versionLess(current, latest) {
+			if cfg.StampChecked {
+				stampChecked(docs, now())
+
+				if writeErr := write(ctx, path, arrangeDocuments(cfg, docs)); writeErr != nil {
+					return newErrorResultWithVersions(file, repo, current, latest, writeErr)
+				}
+			}
+
+			return newFetchedResult(file, repo, current, latest, info, StatusUpToDate, "")
+		}
+
+		setChartVersion(docs, chart, latest)
+
+		if cfg.StampChecked {
+			stampChecked(docs, now())
+		}
+
+		if moved, ok := mapRepo(cfg.RepoMap, repo); ok && cfg.RewriteMoved {
+			rewriteRepoComments(docs, repo, moved)
+		}
+
+		if writeErr := write(ctx, path, arrangeDocuments(cfg, docs)); writeErr != nil {
 			return newErrorResultWithVersions(file, repo, current, latest, writeErr)
 		}
 
-		return UpdateResult{File: file, Repo: repo, Current: current, Latest: latest, Status: StatusUpdated, Error: nil}
+		return newFetchedResult(file, repo, current, latest, info, StatusUpdated, "")
+	}
+}
+
+// newFetchedResult reports a chart whose latest version was fetched, carrying
+// what the source reported about it alongside status.
+func newFetchedResult(file, repo, current, latest string, info VersionInfo, status UpdateStatus, reason string) UpdateResult {
+	return UpdateResult{
+		File:    file,
+		Repo:    repo,
+		Current: current,
+		Latest:  latest,
+		Status:  status,
+		Error:   nil,
+		Reason:  reason,
+
+		Selection:        info.Selection,
+		LatestReleasedAt: info.ReleasedAt,
+		Warnings:         versionWarnings(info),
+		LatestAppVersion: info.AppVersion,
+		LatestDigest:     info.Digest,
+	}
+}
+
+// deprecatedWarning is the warning a result carries for a chart its
+// publisher has deprecated.
+const deprecatedWarning = "chart is deprecated by its publisher"
+
+// versionWarnings returns the warnings a chart's result carries for what its
+// source reported about it.
+func versionWarnings(info VersionInfo) []string {
+	if info.Deprecated {
+		return []string{deprecatedWarning}
+	}
+
+	return nil
+}
+
+// boundedVersion returns the highest version of sel, in its manifest form after
+// transforms, that is above current and within limit of it. Rejected
+// candidates are never returned.
+func boundedVersion(sel Selection, transforms []VersionTransform, current string, limit BumpLevel) (string, bool) {
+	selectable := it.Filter(slices.Values(sel.Candidates), func(v string) bool {
+		return !slices.ContainsFunc(sel.Rejected, func(r Rejection) bool { return r.Version == v })
+	})
+	transformed := it.Map(selectable, func(v string) string { return applyTransforms(v, transforms) })
+	allowed := slices.Collect(it.Filter(transformed, func(v string) bool {
+		return versionLess(current, v) && !exceedsBump(current, v, limit)
+	}))
+
+	if len(allowed) == 0 {
+		return "", false
+	}
+
+	return slices.MaxFunc(allowed, compareVersions), true
+}
+
+// arrangeDocuments returns docs in the order they are written: as read, or
+// sorted by kind with --sort-docs.
+func arrangeDocuments(cfg Config, docs []*yaml.Node) []*yaml.Node {
+	if cfg.SortDocs {
+		return sortDocumentsByKind(docs)
+	}
+
+	return docs
+}
+
+// stampChecked records t as the "# last-checked:" comment of every Application.
+func stampChecked(docs []*yaml.Node, t time.Time) {
+	appDocs := it.Filter(slices.Values(docs), func(n *yaml.Node) bool {
+		return isSupportedKind(n)
+	})
+
+	ForEach(appDocs, func(d *yaml.Node) {
+		setHeadComment(d, lastCheckedPrefix, t.UTC().Format(time.RFC3339))
+	})
+}
+
+// rewriteRepoComments points every artifacthub comment naming from at to instead.
+func rewriteRepoComments(docs []*yaml.Node, from, to string) {
+	ForEach(slices.Values(docs), func(d *yaml.Node) {
+		if comment := getArtifactHubRepo(d); comment.Repo == from {
+			comment.Repo = to
+			setArtifactHubRepo(d, comment)
+		}
+	})
+}
+
+// chartPath returns the manifest path for chart, resolving its File against the
+// chart's own directory or, if it has none, against the configured directory.
+func chartPath(cfg Config, chart ChartInfo) string {
+	if filepath.IsAbs(chart.File) {
+		return chart.File
 	}
+
+	if chart.Dir != "" {
+		return filepath.Join(chart.Dir, chart.File)
+	}
+
+	return filepath.Join(cfg.Dir, chart.File)
 }
 
 func findCurrentVersion(docs []*yaml.Node) (string, bool) {
 	n, found := it.Find(slices.Values(docs), func(n *yaml.Node) bool {
-		return kind(n) == KindApplication
+		return isSupportedKind(n)
 	})
 
 	if found {
@@ -107,7 +287,7 @@ func findCurrentVersion(docs []*yaml.Node) (string, bool) {
 
 func updateDocuments(docs []*yaml.Node, version string) {
 	appDocs := it.Filter(slices.Values(docs), func(n *yaml.Node) bool {
-		return kind(n) == KindApplication
+		return isSupportedKind(n)
 	})
 
 	ForEach(appDocs, func(d *yaml.Node) {
@@ -116,13 +296,64 @@ func updateDocuments(docs []*yaml.Node, version string) {
 }
 
 func newErrorResult(file, repo string, err error) UpdateResult {
-	return UpdateResult{File: file, Repo: repo, Current: "", Latest: "", Status: StatusError, Error: err}
+	return newErrorResultWithVersions(file, repo, "", "", err)
 }
 
 func newErrorResultWithCurrent(file, repo, current string, err error) UpdateResult {
-	return UpdateResult{File: file, Repo: repo, Current: current, Latest: "", Status: StatusError, Error: err}
+	return newErrorResultWithVersions(file, repo, current, "", err)
 }
 
 func newErrorResultWithVersions(file, repo, current, latest string, err error) UpdateResult {
-	return UpdateResult{File: file, Repo: repo, Current: current, Latest: latest, Status: StatusError, Error: err}
+	return UpdateResult{
+		File:    file,
+		Repo:    repo,
+		Current: current,
+		Latest:  latest,
+		Status:  StatusError,
+		Error:   err,
+		Reason:  "",
+
+		Selection:        Selection{},
+		LatestReleasedAt: time.Time{},
+		Warnings:         nil,
+		LatestAppVersion: "",
+		LatestDigest:     "",
+	}
+}
+
+func newSkippedResult(file, repo, current, reason string) UpdateResult {
+	return UpdateResult{
+		File:    file,
+		Repo:    repo,
+		Current: current,
+		Latest:  "",
+		Status:  StatusSkipped,
+		Error:   nil,
+		Reason:  reason,
+
+		Selection:        Selection{},
+		LatestReleasedAt: time.Time{},
+		Warnings:         nil,
+		LatestAppVersion: "",
+		LatestDigest:     "",
+	}
+}
+
+// newPinnedResult reports a chart whose source comment carries pinnedMarker.
+func newPinnedResult(file, repo, current string) UpdateResult {
+	return UpdateResult{
+		File:    file,
+		Repo:    repo,
+		Current: current,
+		Latest:  "",
+		Status:  StatusPinned,
+		Error:   nil,
+		Reason:  "pinned in the source comment",
+
+		Selection:        Selection{},
+		LatestReleasedAt: time.Time{},
+		Warnings:         nil,
+		LatestAppVersion: "",
+		LatestDigest:     "",
+	}
 }
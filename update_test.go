@@ -1,10 +1,18 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
 	"slices"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/BooleanCat/go-functional/v2/it"
 	"gopkg.in/yaml.v3"
@@ -49,6 +57,18 @@ func TestUpdateChart(t *testing.T) {
 			wantLatest:  "",
 			wantErr:     "",
 		},
+		{
+			name: "minor-line pin keeps its style",
+			read: func() ([]*yaml.Node, error) {
+				return []*yaml.Node{createMockAppNode("1.15")}, nil
+			},
+			fetch:       func() (string, error) { return "1.15.3", nil },
+			write:       func() error { return errors.New("write should not be called") },
+			wantStatus:  StatusUpToDate,
+			wantCurrent: "1.15",
+			wantLatest:  "1.15.3",
+			wantErr:     "",
+		},
 		{
 			name: "read error",
 			read: func() ([]*yaml.Node, error) {
@@ -112,11 +132,14 @@ func runUpdateChartTest(cfg Config, tc testCase) func(t *testing.T) {
 		t.Helper()
 
 		mockRead := func(_ string) ([]*yaml.Node, error) { return tc.read() }
-		mockFetch := func(_ context.Context, _ string) (string, error) { return tc.fetch() }
+		mockFetch := func(_ context.Context, _ VersionQuery) (VersionInfo, error) {
+			v, err := tc.fetch()
+			return versionInfo(v), err
+		}
 		mockWrite := func(_ context.Context, _ string, _ []*yaml.Node) error { return tc.write() }
 
-		updater := MakeChartUpdater(cfg, mockRead, mockFetch, mockWrite)
-		result := updater(context.Background(), "app.yaml", "org/repo")
+		updater := MakeChartUpdater(cfg, mockRead, mockFetch, mockWrite, time.Now, discardLog)
+		result := updater(context.Background(), ChartInfo{File: "app.yaml", Repo: "org/repo", Timeout: 0})
 
 		assertStatus(t, tc.wantStatus, result.Status)
 		assertString(t, "current", tc.wantCurrent, result.Current)
@@ -153,6 +176,11 @@ func assertError(t *testing.T, want string, got error) {
 	}
 }
 
+// versionInfo wraps a bare version as a fetcher result.
+func versionInfo(v string) VersionInfo {
+	return VersionInfo{Version: v, Selection: Selection{Candidates: []string{v}, Rejected: nil}}
+}
+
 // Helper to create a minimal node structure that satisfies the lookup.
 func createMockAppNode(version string) *yaml.Node {
 	// Construction of a minimal YAML AST for:
@@ -187,3 +215,715 @@ func createMockAppNode(version string) *yaml.Node {
 		},
 	}
 }
+
+func TestUpdateChartSkipUnreachable(t *testing.T) {
+	cfg := Config{Dir: ".", DryRun: false, CheckOnly: false, SkipUnreachable: true}
+
+	read := func(_ string) ([]*yaml.Node, error) {
+		return []*yaml.Node{createMockAppNode("1.0.0")}, nil
+	}
+	fetch := func(_ context.Context, q VersionQuery) (VersionInfo, error) {
+		if q.Repo == "org/down" {
+			return VersionInfo{}, errors.New("connection refused")
+		}
+
+		return versionInfo("1.1.0"), nil
+	}
+	write := func(_ context.Context, _ string, _ []*yaml.Node) error { return nil }
+
+	updater := MakeChartUpdater(cfg, read, fetch, write, time.Now, discardLog)
+
+	down := updater(context.Background(), ChartInfo{File: "down.yaml", Repo: "org/down", Timeout: 0})
+	assertStatus(t, StatusSkipped, down.Status)
+	assertString(t, "current", "1.0.0", down.Current)
+	assertError(t, "", down.Error)
+
+	if down.Reason != "connection refused" {
+		t.Errorf("expected reason %q, got %q", "connection refused", down.Reason)
+	}
+
+	up := updater(context.Background(), ChartInfo{File: "up.yaml", Repo: "org/up", Timeout: 0})
+	assertStatus(t, StatusUpdated, up.Status)
+	assertString(t, "latest", "1.1.0", up.Latest)
+}
+
+func TestUpdateChartRequestQuota(t *testing.T) {
+	cfg := Config{Dir: ".", DryRun: false, CheckOnly: false, MaxRequests: 2}
+
+	read := func(_ string) ([]*yaml.Node, error) {
+		return []*yaml.Node{createMockAppNode("1.0.0")}, nil
+	}
+
+	var fetched atomic.Int32
+
+	fetch := MakeBudgetedFetcher(func(_ context.Context, _ VersionQuery) (VersionInfo, error) {
+		fetched.Add(1)
+		return versionInfo("1.1.0"), nil
+	}, NewRequestBudget(cfg.MaxRequests))
+
+	var written []string
+
+	write := func(_ context.Context, path string, _ []*yaml.Node) error {
+		written = append(written, path)
+		return nil
+	}
+
+	updater := MakeChartUpdater(cfg, read, fetch, write, time.Now, discardLog)
+
+	results := NewResults()
+	for _, file := range []string{"a.yaml", "b.yaml", "c.yaml", "d.yaml", "e.yaml"} {
+		results.Add(updater(context.Background(), ChartInfo{File: file, Repo: "org/" + file, Timeout: 0}))
+	}
+
+	if got := fetched.Load(); got != 2 {
+		t.Errorf("fetcher called %d times, want 2", got)
+	}
+
+	if results.Count(StatusUpdated) != 2 || results.Count(StatusSkipped) != 3 || len(written) != 2 {
+		t.Errorf("updated %d, skipped %d, written %v; want 2 updated, 3 skipped, 2 written",
+			results.Count(StatusUpdated), results.Count(StatusSkipped), written)
+	}
+
+	for _, r := range results.WithStatus(StatusSkipped) {
+		assertError(t, "", r.Error)
+
+		if r.Reason != "request quota reached (--max-requests 2)" {
+			t.Errorf("%s: reason = %q, want the request quota", r.File, r.Reason)
+		}
+	}
+}
+
+func TestUpdateChartOptedOut(t *testing.T) {
+	cfg := Config{Dir: ".", DryRun: false, CheckOnly: false, OptOutLabel: defaultOptOutLabel}
+
+	read := func(_ string) ([]*yaml.Node, error) {
+		return []*yaml.Node{createMockAppNode("1.0.0")}, nil
+	}
+	fetch := func(_ context.Context, _ VersionQuery) (VersionInfo, error) {
+		t.Fatal("fetcher must not be called for an opted-out chart")
+		return VersionInfo{}, nil
+	}
+	write := func(_ context.Context, _ string, _ []*yaml.Node) error {
+		t.Fatal("writer must not be called for an opted-out chart")
+		return nil
+	}
+
+	updater := MakeChartUpdater(cfg, read, fetch, write, time.Now, discardLog)
+
+	chart := ChartInfo{File: "app.yaml", Repo: "org/chart", Timeout: 0, Labels: map[string]string{"chart-updater": "disabled"}}
+	result := updater(context.Background(), chart)
+
+	assertStatus(t, StatusSkipped, result.Status)
+	assertString(t, "current", "1.0.0", result.Current)
+	assertError(t, "", result.Error)
+	assertString(t, "reason", "opted out via chart-updater: disabled", result.Reason)
+}
+
+func TestUpdateChartPinned(t *testing.T) {
+	dir := t.TempDir()
+	content := "# artifacthub: org/chart pinned\nkind: Application\nspec:\n  source:\n    targetRevision: 1.0.0\n"
+	createTestFiles(t, dir, map[string]string{testAppFile: content})
+
+	charts, err := MakeChartDiscoverer(os.Stat, os.ReadDir, readYAMLDocuments, anyFile, isValidPath, discardLog)(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(charts) != 1 || !charts[0].Pinned {
+		t.Fatalf("discovered %+v, want one pinned chart", charts)
+	}
+
+	fetch := func(_ context.Context, _ VersionQuery) (VersionInfo, error) {
+		t.Fatal("fetcher must not be called for a pinned chart")
+		return VersionInfo{}, nil
+	}
+
+	result := MakeChartUpdater(Config{Dir: dir}, readYAMLDocuments, fetch, writeYAMLDocuments, time.Now, discardLog)(context.Background(), charts[0])
+
+	assertStatus(t, StatusPinned, result.Status)
+	assertString(t, "current", "1.0.0", result.Current)
+
+	got, err := os.ReadFile(filepath.Join(dir, testAppFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertString(t, "file", content, string(got))
+}
+
+func TestUpdateChartIgnoredVersions(t *testing.T) {
+	tests := []struct {
+		name      string
+		ignore    string
+		published []string
+		want      string
+		status    UpdateStatus
+	}{
+		{name: "next version taken", ignore: "!2.3.0", published: []string{"2.3.0", "2.2.1", "2.2.0"}, want: "2.2.1", status: StatusUpdated},
+		{name: "only newer version ignored", ignore: "!2.3.0", published: []string{"2.3.0", "2.2.0"}, want: "2.2.0", status: StatusUpToDate},
+		{name: "later release not ignored", ignore: "!2.3.0", published: []string{"2.3.1", "2.3.0", "2.2.0"}, want: "2.3.1", status: StatusUpdated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			createTestFiles(t, dir, map[string]string{
+				testAppFile: "# artifacthub: org/chart " + tt.ignore + "\nkind: Application\nspec:\n  source:\n    targetRevision: 2.2.0\n",
+			})
+
+			chart, warnings := chartInfoAt(readYAMLDocuments, filepath.Join(dir, testAppFile))
+			if warnings != "" {
+				t.Fatal(warnings)
+			}
+
+			chart.File = testAppFile
+
+			fetch := func(_ context.Context, q VersionQuery) (VersionInfo, error) {
+				latest, sel, _ := selectVersion(tt.published, q)
+				return VersionInfo{Version: latest, Selection: sel}, nil
+			}
+
+			result := MakeChartUpdater(Config{Dir: dir}, readYAMLDocuments, fetch, writeYAMLDocuments, time.Now, discardLog)(context.Background(), chart)
+			assertStatus(t, tt.status, result.Status)
+			assertString(t, "latest", tt.want, result.Latest)
+		})
+	}
+}
+
+func TestUpdateChartUsesChartDir(t *testing.T) {
+	cfg := Config{Dir: "clusters/*/apps", DryRun: false, CheckOnly: false}
+
+	var readPath string
+
+	read := func(path string) ([]*yaml.Node, error) {
+		readPath = path
+		return []*yaml.Node{createMockAppNode("1.0.0")}, nil
+	}
+	fetch := func(_ context.Context, _ VersionQuery) (VersionInfo, error) { return versionInfo("1.0.0"), nil }
+	write := func(_ context.Context, _ string, _ []*yaml.Node) error { return nil }
+
+	chart := ChartInfo{File: "clusters/prod/apps/app.yaml", Repo: "org/chart", Timeout: 0, Labels: nil, Dir: "."}
+	MakeChartUpdater(cfg, read, fetch, write, time.Now, discardLog)(context.Background(), chart)
+
+	if want := filepath.Join("clusters", "prod", "apps", "app.yaml"); readPath != want {
+		t.Errorf("read path = %q, want %q", readPath, want)
+	}
+}
+
+func TestUpdateChartRewriteMoved(t *testing.T) {
+	const body = "# owner: platform\nkind: Application\nspec:\n  source:\n    targetRevision: 1.0.0\n"
+
+	tests := []struct {
+		name        string
+		comment     string
+		rewrite     bool
+		wantComment string
+	}{
+		{
+			name: "comment kept by default", comment: "# artifacthub: oldorg/chart\n", rewrite: false,
+			wantComment: "# artifacthub: oldorg/chart\n# owner: platform\n",
+		},
+		{
+			name: "comment rewritten", comment: "# artifacthub: oldorg/chart\n", rewrite: true,
+			wantComment: "# artifacthub: neworg/chart\n# owner: platform\n",
+		},
+		{
+			name: "prerelease marker kept", comment: "# artifacthub: oldorg/chart prerelease\n", rewrite: true,
+			wantComment: "# artifacthub: neworg/chart prerelease\n# owner: platform\n",
+		},
+		{
+			name: "constraint kept", comment: "# artifacthub: oldorg/chart prerelease >=1.0.0 <2.0.0\n", rewrite: true,
+			wantComment: "# artifacthub: neworg/chart prerelease >=1.0.0 <2.0.0\n# owner: platform\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			createTestFiles(t, dir, map[string]string{testAppFile: tt.comment + body})
+
+			cfg := Config{Dir: dir, RepoMap: map[string]string{"oldorg": "neworg"}, RewriteMoved: tt.rewrite}
+
+			var queried string
+
+			fetch := MakeRepoMappingFetcher(func(_ context.Context, q VersionQuery) (VersionInfo, error) {
+				queried = q.Repo
+				return versionInfo("1.1.0"), nil
+			}, cfg.RepoMap)
+
+			result := MakeChartUpdater(cfg, readYAMLDocuments, fetch, writeYAMLDocuments, time.Now, discardLog)(
+				context.Background(), ChartInfo{File: testAppFile, Repo: "oldorg/chart", Timeout: 0, Labels: nil, Dir: ""})
+
+			assertStatus(t, StatusUpdated, result.Status)
+			assertString(t, "queried repo", "neworg/chart", queried)
+			assertString(t, "result repo", "oldorg/chart", result.Repo)
+
+			content, err := os.ReadFile(filepath.Join(dir, testAppFile))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !strings.HasPrefix(string(content), tt.wantComment) {
+				t.Errorf("written file =\n%s\nwant it to start with\n%s", content, tt.wantComment)
+			}
+		})
+	}
+}
+
+func TestUpdateChartDeprecated(t *testing.T) {
+	for _, current := range []string{"1.0.0", "1.1.0"} {
+		dir := t.TempDir()
+		createTestFiles(t, dir, map[string]string{
+			testAppFile: "# artifacthub: org/chart\nkind: Application\nspec:\n  source:\n    targetRevision: " + current + "\n",
+		})
+
+		fetch := func(_ context.Context, _ VersionQuery) (VersionInfo, error) {
+			info := versionInfo("1.1.0")
+			info.Deprecated = true
+
+			return info, nil
+		}
+
+		result := MakeChartUpdater(Config{Dir: dir}, readYAMLDocuments, fetch, writeYAMLDocuments, time.Now, discardLog)(
+			context.Background(), ChartInfo{File: testAppFile, Repo: "org/chart", Timeout: 0, Labels: nil, Dir: ""})
+
+		if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "deprecated") {
+			t.Errorf("%s: Warnings = %q, want a deprecation warning", result.Status, result.Warnings)
+		}
+	}
+}
+
+func TestUpdateChartVerboseLog(t *testing.T) {
+	tests := []struct {
+		current string
+		want    string
+	}{
+		{current: "1.0.0", want: "current 1.0.0 is lower than latest 1.1.0, updating"},
+		{current: "1.1.0", want: "nothing to update"},
+	}
+
+	for _, tt := range tests {
+		dir := t.TempDir()
+		createTestFiles(t, dir, map[string]string{
+			testAppFile: "# artifacthub: org/chart\nkind: Application\nspec:\n  source:\n    targetRevision: " + tt.current + "\n",
+		})
+
+		fetch := func(_ context.Context, _ VersionQuery) (VersionInfo, error) {
+			return versionInfo("1.1.0"), nil
+		}
+
+		var logs bytes.Buffer
+
+		MakeChartUpdater(Config{Dir: dir}, readYAMLDocuments, fetch, writeYAMLDocuments, time.Now, NewLogger(&logs, LogVerbose).Debug)(
+			context.Background(), ChartInfo{File: testAppFile, Repo: "org/chart", Timeout: 0, Labels: nil, Dir: ""})
+
+		if !strings.Contains(logs.String(), tt.want) {
+			t.Errorf("%s: log = %q, want it to contain %q", tt.current, logs.String(), tt.want)
+		}
+	}
+}
+
+func TestUpdateChartPackageMetadata(t *testing.T) {
+	dir := t.TempDir()
+	createTestFiles(t, dir, map[string]string{
+		testAppFile: "# artifacthub: org/chart\nkind: Application\nspec:\n  source:\n    targetRevision: 1.0.0\n",
+	})
+
+	fetch := func(_ context.Context, _ VersionQuery) (VersionInfo, error) {
+		info := versionInfo("1.1.0")
+		info.AppVersion, info.Digest = "v2.0.0", "sha256:abc"
+
+		return info, nil
+	}
+
+	result := MakeChartUpdater(Config{Dir: dir}, readYAMLDocuments, fetch, writeYAMLDocuments, time.Now, discardLog)(
+		context.Background(), ChartInfo{File: testAppFile, Repo: "org/chart", Timeout: 0, Labels: nil, Dir: ""})
+
+	assertStatus(t, StatusUpdated, result.Status)
+	assertString(t, "app version", "v2.0.0", result.LatestAppVersion)
+	assertString(t, "digest", "sha256:abc", result.LatestDigest)
+
+	content, err := os.ReadFile(filepath.Join(dir, testAppFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(content), "v2.0.0") {
+		t.Errorf("written file =\n%s\nwant only the chart version written", content)
+	}
+}
+
+func TestUpdateChartTransforms(t *testing.T) {
+	tests := []struct {
+		name      string
+		transform string
+		current   string
+		fetched   string
+		want      string
+		status    UpdateStatus
+	}{
+		{name: "suffix", transform: "suffix=-ce", current: "1.1.0-ce", fetched: "1.2.0", want: "1.2.0-ce", status: StatusUpdated},
+		{name: "prefix", transform: "prefix=v", current: "v1.1.0", fetched: "1.2.0", want: "v1.2.0", status: StatusUpdated},
+		{name: "replace", transform: "replace=+:_", current: "1.1.0_build.1", fetched: "1.2.0+build.1", want: "1.2.0_build.1", status: StatusUpdated},
+		{name: "chained", transform: "prefix=v suffix=-ce", current: "v1.1.0-ce", fetched: "1.2.0", want: "v1.2.0-ce", status: StatusUpdated},
+		{name: "compared after transform", transform: "suffix=-ce", current: "1.2.0-ce", fetched: "1.2.0", want: "1.2.0-ce", status: StatusUpToDate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			createTestFiles(t, dir, map[string]string{
+				testAppFile: "# artifacthub: org/chart\n# artifacthub-transform: " + tt.transform +
+					"\nkind: Application\nspec:\n  source:\n    targetRevision: " + tt.current + "\n",
+			})
+
+			chart, warnings := chartInfoAt(readYAMLDocuments, filepath.Join(dir, testAppFile))
+			if warnings != "" {
+				t.Fatal(warnings)
+			}
+
+			chart.File = testAppFile
+
+			fetch := func(_ context.Context, _ VersionQuery) (VersionInfo, error) { return versionInfo(tt.fetched), nil }
+
+			result := MakeChartUpdater(Config{Dir: dir}, readYAMLDocuments, fetch, writeYAMLDocuments, time.Now, discardLog)(context.Background(), chart)
+			assertStatus(t, tt.status, result.Status)
+			assertString(t, "latest", tt.want, result.Latest)
+
+			content, err := os.ReadFile(filepath.Join(dir, testAppFile))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !strings.Contains(string(content), "targetRevision: "+tt.want+"\n") {
+				t.Errorf("written file =\n%s\nwant targetRevision %s", content, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateChartStampChecked(t *testing.T) {
+	dir := t.TempDir()
+	createTestFiles(t, dir, map[string]string{
+		testAppFile: "# artifacthub: org/chart\nkind: Application\nspec:\n  source:\n    targetRevision: 1.0.0\n",
+	})
+
+	cfg := Config{Dir: dir, StampChecked: true}
+	chart := ChartInfo{File: testAppFile, Repo: "org/chart", Timeout: 0, Labels: nil, Dir: ""}
+
+	latest := "1.0.0"
+	fetch := func(_ context.Context, _ VersionQuery) (VersionInfo, error) { return versionInfo(latest), nil }
+
+	run := func(now time.Time) (UpdateResult, string) {
+		t.Helper()
+
+		result := MakeChartUpdater(cfg, readYAMLDocuments, fetch, writeYAMLDocuments, func() time.Time { return now }, discardLog)(
+			context.Background(), chart)
+
+		content, err := os.ReadFile(filepath.Join(dir, testAppFile))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return result, string(content)
+	}
+
+	first := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	result, content := run(first)
+	assertStatus(t, StatusUpToDate, result.Status)
+
+	if want := "# artifacthub: org/chart\n# last-checked: 2026-03-01T12:00:00Z\n"; !strings.HasPrefix(content, want) {
+		t.Errorf("stamp not added to up-to-date file:\n%s", content)
+	}
+
+	latest = "1.1.0"
+
+	result, content = run(first.Add(24 * time.Hour))
+	assertStatus(t, StatusUpdated, result.Status)
+
+	if strings.Count(content, lastCheckedPrefix) != 1 || !strings.Contains(content, "# last-checked: 2026-03-02T12:00:00Z") {
+		t.Errorf("stamp not updated in place:\n%s", content)
+	}
+
+	if !strings.Contains(content, "targetRevision: 1.1.0") {
+		t.Errorf("version not updated alongside stamp:\n%s", content)
+	}
+}
+
+func TestUpdateChartPassesCurrentToFetcher(t *testing.T) {
+	cfg := Config{Dir: ".", DryRun: false, CheckOnly: false}
+
+	var got VersionQuery
+
+	read := func(_ string) ([]*yaml.Node, error) {
+		return []*yaml.Node{createMockAppNode("1.15")}, nil
+	}
+	fetch := func(_ context.Context, q VersionQuery) (VersionInfo, error) {
+		got = q
+		return versionInfo("1.15.3"), nil
+	}
+	write := func(_ context.Context, _ string, _ []*yaml.Node) error { return nil }
+
+	chart := ChartInfo{File: "app.yaml", Repo: "org/repo", Timeout: 30 * time.Second}
+	MakeChartUpdater(cfg, read, fetch, write, time.Now, discardLog)(context.Background(), chart)
+
+	want := VersionQuery{Repo: "org/repo", Current: "1.15", Timeout: 30 * time.Second, Limit: 0, PrereleaseSameMajor: false, Stability: "", RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("fetch called with %+v, want %+v", got, want)
+	}
+}
+
+func TestUpdateChartPassesAllowPrerelease(t *testing.T) {
+	for _, allow := range []bool{false, true} {
+		var got VersionQuery
+
+		read := func(_ string) ([]*yaml.Node, error) {
+			return []*yaml.Node{createMockAppNode("1.0.0")}, nil
+		}
+		fetch := func(_ context.Context, q VersionQuery) (VersionInfo, error) {
+			got = q
+			return versionInfo("1.0.0"), nil
+		}
+		write := func(_ context.Context, _ string, _ []*yaml.Node) error { return nil }
+
+		chart := ChartInfo{File: "app.yaml", Repo: "org/repo", AllowPrerelease: allow}
+		MakeChartUpdater(Config{Dir: "."}, read, fetch, write, time.Now, discardLog)(context.Background(), chart)
+
+		if got.AllowPrerelease != allow {
+			t.Errorf("AllowPrerelease = %v, want %v", got.AllowPrerelease, allow)
+		}
+	}
+}
+
+func TestUpdateChartSecondRunIsNoOp(t *testing.T) {
+	const manifest = "# artifacthub: org/chart\n# owner: platform\napiVersion: argoproj.io/v1alpha1\n" +
+		"kind: Application\nmetadata:\n  name: app # inline\nspec:\n  source:\n    chart: chart\n" +
+		"    targetRevision: 1.0.0\n---\napiVersion: v1\nkind: ConfigMap\n# between keys\ndata:\n  a: \"b\"\n"
+
+	for _, stamp := range []bool{false, true} {
+		t.Run(fmt.Sprintf("stamp checked %v", stamp), func(t *testing.T) {
+			dir := t.TempDir()
+			createTestFiles(t, dir, map[string]string{testAppFile: manifest})
+			path := filepath.Join(dir, testAppFile)
+
+			cfg := Config{Dir: dir, StampChecked: stamp}
+			chart := ChartInfo{File: testAppFile, Repo: "org/chart", Timeout: 0, Labels: nil, Dir: ""}
+			fetch := func(_ context.Context, _ VersionQuery) (VersionInfo, error) { return versionInfo("1.1.0"), nil }
+			now := func() time.Time { return time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC) }
+			updater := MakeChartUpdater(cfg, readYAMLDocuments, fetch, writeYAMLDocuments, now, discardLog)
+
+			assertStatus(t, StatusUpdated, updater(context.Background(), chart).Status)
+
+			first, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Backdate the file so a rewrite would show up even when the bytes match.
+			old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+			if err := os.Chtimes(path, old, old); err != nil {
+				t.Fatal(err)
+			}
+
+			second := updater(context.Background(), chart)
+			assertStatus(t, StatusUpToDate, second.Status)
+			assertError(t, "", second.Error)
+
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(content) != string(first) {
+				t.Errorf("second run changed the file:\nfirst:\n%s\nsecond:\n%s", first, content)
+			}
+
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !info.ModTime().Equal(old) {
+				t.Errorf("second run rewrote the file: mtime %v, want %v", info.ModTime(), old)
+			}
+		})
+	}
+}
+
+func TestUpdateChartApplicationSet(t *testing.T) {
+	dir := t.TempDir()
+	createTestFiles(t, dir, map[string]string{testAppFile: testAppSetContent})
+
+	charts, err := MakeChartDiscoverer(os.Stat, os.ReadDir, readYAMLDocuments, anyFile, isValidPath, discardLog)(dir)
+	if err != nil || len(charts) != 1 {
+		t.Fatalf("discoverCharts() = %v, %v, want one chart", charts, err)
+	}
+
+	cfg := Config{Dir: dir, DryRun: false, CheckOnly: false}
+	fetch := func(_ context.Context, _ VersionQuery) (VersionInfo, error) { return versionInfo("1.1.0"), nil }
+	result := MakeChartUpdater(cfg, readYAMLDocuments, fetch, writeYAMLDocuments, time.Now, discardLog)(context.Background(), charts[0])
+
+	assertStatus(t, StatusUpdated, result.Status)
+
+	if result.Current != "1.0.0" {
+		t.Errorf("Current = %q, want %q", result.Current, "1.0.0")
+	}
+
+	docs, err := readYAMLDocuments(filepath.Join(dir, testAppFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := findCurrentVersion(docs); got != "1.1.0" {
+		t.Errorf("written targetRevision = %q, want %q", got, "1.1.0")
+	}
+}
+
+func TestUpdateChartMaxBump(t *testing.T) {
+	candidates := []string{"2.0.0", "1.9.2", "1.5.0-rc.1", "1.4.3", "1.4.0"}
+
+	tests := []struct {
+		name       string
+		limit      BumpLevel
+		current    string
+		wantLatest string
+		wantStatus UpdateStatus
+	}{
+		{name: "unbounded", limit: "", current: "1.4.0", wantLatest: "2.0.0", wantStatus: StatusUpdated},
+		{name: "major allowed", limit: BumpMajor, current: "1.4.0", wantLatest: "2.0.0", wantStatus: StatusUpdated},
+		{name: "minor falls back", limit: BumpMinor, current: "1.4.0", wantLatest: "1.9.2", wantStatus: StatusUpdated},
+		{name: "patch falls back", limit: BumpPatch, current: "1.4.0", wantLatest: "1.4.3", wantStatus: StatusUpdated},
+		{name: "patch held", limit: BumpPatch, current: "1.4.3", wantLatest: "2.0.0", wantStatus: StatusHeld},
+		{name: "minor held at latest of line", limit: BumpMinor, current: "1.9.2", wantLatest: "2.0.0", wantStatus: StatusHeld},
+		{name: "partial pin unaffected", limit: BumpPatch, current: "1.4", wantLatest: "2.0.0", wantStatus: StatusUpToDate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Dir: ".", MaxBump: tt.limit}
+
+			read := func(_ string) ([]*yaml.Node, error) {
+				return []*yaml.Node{createMockAppNode(tt.current)}, nil
+			}
+			fetch := func(_ context.Context, _ VersionQuery) (VersionInfo, error) {
+				return VersionInfo{
+					Version: "2.0.0",
+					Selection: Selection{
+						Candidates: candidates,
+						Rejected:   []Rejection{{Version: "1.5.0-rc.1", Reason: "pre-release"}},
+					},
+				}, nil
+			}
+
+			wrote := false
+			write := func(_ context.Context, _ string, _ []*yaml.Node) error {
+				wrote = true
+				return nil
+			}
+
+			result := MakeChartUpdater(cfg, read, fetch, write, time.Now, discardLog)(
+				context.Background(), ChartInfo{File: "app.yaml", Repo: "org/chart", Timeout: 0})
+
+			assertStatus(t, tt.wantStatus, result.Status)
+			assertString(t, "latest", tt.wantLatest, result.Latest)
+
+			if wrote != (tt.wantStatus == StatusUpdated) {
+				t.Errorf("wrote = %v, want %v", wrote, tt.wantStatus == StatusUpdated)
+			}
+
+			if tt.name == "patch held" {
+				assertString(t, "reason", "latest 2.0.0 is a major bump from 1.4.3, beyond --max-bump patch", result.Reason)
+			}
+		})
+	}
+}
+
+func TestUpdateChartNeverDowngrade(t *testing.T) {
+	tests := []struct {
+		name       string
+		current    string
+		latest     string
+		wantStatus UpdateStatus
+		wantWrite  bool
+	}{
+		{name: "latest lower than current", current: "2.0.0", latest: "1.9.0", wantStatus: StatusBlocked, wantWrite: false},
+		{name: "latest higher than current", current: "1.0.0", latest: "1.1.0", wantStatus: StatusUpdated, wantWrite: true},
+		{name: "latest equal to current", current: "1.1.0", latest: "1.1.0", wantStatus: StatusUpToDate, wantWrite: true},
+		{name: "partial pin", current: "1.15", latest: "1.15.3", wantStatus: StatusUpToDate, wantWrite: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Dir: ".", NeverDowngrade: true, StampChecked: true}
+
+			read := func(_ string) ([]*yaml.Node, error) {
+				return []*yaml.Node{createMockAppNode(tt.current)}, nil
+			}
+			fetch := func(_ context.Context, _ VersionQuery) (VersionInfo, error) { return versionInfo(tt.latest), nil }
+
+			wrote := false
+			write := func(_ context.Context, _ string, _ []*yaml.Node) error {
+				wrote = true
+				return nil
+			}
+
+			result := MakeChartUpdater(cfg, read, fetch, write, time.Now, discardLog)(
+				context.Background(), ChartInfo{File: "app.yaml", Repo: "org/chart", Timeout: 0})
+
+			assertStatus(t, tt.wantStatus, result.Status)
+			assertError(t, "", result.Error)
+
+			if wrote != tt.wantWrite {
+				t.Errorf("wrote = %v, want %v", wrote, tt.wantWrite)
+			}
+
+			if tt.wantStatus == StatusBlocked {
+				assertString(t, "reason", "latest 1.9.0 is lower than current 2.0.0", result.Reason)
+			}
+		})
+	}
+}
+
+func TestUpdateChartDocumentOrder(t *testing.T) {
+	const manifest = "kind: Secret\nmetadata:\n  name: creds\n---\n" +
+		"# artifacthub: org/chart\nkind: Application\nspec:\n  source:\n    targetRevision: 1.0.0\n---\n" +
+		"kind: ConfigMap\nmetadata:\n  name: settings\n"
+
+	tests := []struct {
+		name      string
+		sortDocs  bool
+		wantKinds []string
+	}{
+		{name: "original order preserved", sortDocs: false, wantKinds: []string{"Secret", KindApplication, "ConfigMap"}},
+		{name: "sorted by kind", sortDocs: true, wantKinds: []string{KindApplication, "ConfigMap", "Secret"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			createTestFiles(t, dir, map[string]string{testAppFile: manifest})
+
+			cfg := Config{Dir: dir, SortDocs: tt.sortDocs}
+			fetch := func(_ context.Context, _ VersionQuery) (VersionInfo, error) { return versionInfo("1.1.0"), nil }
+
+			result := MakeChartUpdater(cfg, readYAMLDocuments, fetch, writeYAMLDocuments, time.Now, discardLog)(
+				context.Background(), ChartInfo{File: testAppFile, Repo: "org/chart", Timeout: 0, Labels: nil, Dir: ""})
+			assertStatus(t, StatusUpdated, result.Status)
+
+			docs, err := readYAMLDocuments(filepath.Join(dir, testAppFile))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			kinds := slices.Collect(it.Map(slices.Values(docs), kind))
+			if !slices.Equal(kinds, tt.wantKinds) {
+				t.Errorf("document kinds = %v, want %v", kinds, tt.wantKinds)
+			}
+
+			if version, _ := findCurrentVersion(docs); version != "1.1.0" {
+				t.Errorf("targetRevision = %q, want %q", version, "1.1.0")
+			}
+		})
+	}
+}
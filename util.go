@@ -20,10 +20,120 @@ import (
 	"fmt"
 	"io"
 	"iter"
+	"sync"
+	"time"
 )
 
-func logwf(w io.Writer, format string, a ...any) {
-	_, _ = fmt.Fprintf(w, "▶ "+format+"\n", a...)
+// Clock returns the current time. It is injected wherever behavior depends on
+// the time of day so tests can pin it.
+type Clock func() time.Time
+
+// relativeTime describes t relative to now in the largest whole unit, such as
+// "3 days ago". Times in the future are reported as "just now".
+func relativeTime(now, t time.Time) string {
+	const day = 24 * time.Hour
+
+	d := now.Sub(t)
+
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return plural(int(d/time.Minute), "minute") + " ago"
+	case d < day:
+		return plural(int(d/time.Hour), "hour") + " ago"
+	default:
+		return plural(int(d/day), "day") + " ago"
+	}
+}
+
+// plural formats n with unit, adding an "s" unless n is 1.
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+
+	return fmt.Sprintf("%d %ss", n, unit)
+}
+
+// LogFunc writes one formatted log line. The fetchers and the updater take one
+// for the detail --verbose adds, such as request URLs and version decisions;
+// it is usually the Debug method of the run's Logger.
+type LogFunc func(format string, a ...any)
+
+// LogLevel selects the lines a Logger writes.
+type LogLevel int
+
+const (
+	// LogQuiet writes only notices, warnings and errors.
+	LogQuiet LogLevel = iota
+	// LogNormal also writes informational lines.
+	LogNormal
+	// LogVerbose also writes debug lines.
+	LogVerbose
+)
+
+// Logger writes log lines to an underlying writer. Writes are serialized so
+// that each line emitted by concurrent goroutines is written atomically, and
+// the logger's level decides which lines are written at all.
+type Logger struct {
+	mu    sync.Mutex
+	w     io.Writer
+	level LogLevel
+}
+
+// NewLogger creates a Logger writing the lines level allows to w.
+func NewLogger(w io.Writer, level LogLevel) *Logger {
+	return &Logger{mu: sync.Mutex{}, w: w, level: level}
+}
+
+// Notice logs a line, prefixed with "▶", that is written even when the logger
+// is quiet, such as an updated chart or the summary.
+func (l *Logger) Notice(format string, a ...any) {
+	l.printf("▶ "+format, a...)
+}
+
+// Info logs an informational line, prefixed with "▶", unless the logger is
+// quiet.
+func (l *Logger) Info(format string, a ...any) {
+	if l.level >= LogNormal {
+		l.Notice(format, a...)
+	}
+}
+
+// Warn logs a problem that does not stop the run.
+func (l *Logger) Warn(format string, a ...any) {
+	l.printf("▶ warning: "+format, a...)
+}
+
+// Error logs a failure that is reported without aborting the run.
+func (l *Logger) Error(format string, a ...any) {
+	l.printf("▶ error: "+format, a...)
+}
+
+// Debug logs an informational line only when the logger is verbose.
+func (l *Logger) Debug(format string, a ...any) {
+	if l.level >= LogVerbose {
+		l.Notice(format, a...)
+	}
+}
+
+// Write writes p unchanged, so output that is not a single log line, such as
+// a table, is serialized with the log lines around it.
+func (l *Logger) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	n, err := l.w.Write(p)
+	if err != nil {
+		return n, fmt.Errorf("write output: %w", err)
+	}
+
+	return n, nil
+}
+
+func (l *Logger) printf(format string, a ...any) {
+	_, _ = fmt.Fprintf(l, format+"\n", a...)
 }
 
 func ForEach[T any](seq iter.Seq[T], action func(T)) {
@@ -0,0 +1,108 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// byteWriter writes one byte at a time so unsynchronized callers interleave.
+type byteWriter struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *byteWriter) Write(p []byte) (int, error) {
+	for _, c := range p {
+		b.mu.Lock()
+		b.buf.WriteByte(c)
+		b.mu.Unlock()
+		runtime.Gosched()
+	}
+
+	return len(p), nil
+}
+
+// discardLog is the LogFunc of tests that do not look at debug output.
+func discardLog(string, ...any) {}
+
+func TestLoggerLevels(t *testing.T) {
+	tests := []struct {
+		level LogLevel
+		want  string
+	}{
+		{level: LogQuiet, want: "▶ notice 0\n▶ warning: warn 2\n▶ error: error 3\n"},
+		{level: LogNormal, want: "▶ notice 0\n▶ info 1\n▶ warning: warn 2\n▶ error: error 3\n"},
+		{level: LogVerbose, want: "▶ notice 0\n▶ info 1\n▶ warning: warn 2\n▶ error: error 3\n▶ debug 4\n"},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+
+		log := NewLogger(&buf, tt.level)
+		log.Notice("notice %d", 0)
+		log.Info("info %d", 1)
+		log.Warn("warn %d", 2)
+		log.Error("error %d", 3)
+		log.Debug("debug %d", 4)
+
+		if got := buf.String(); got != tt.want {
+			t.Errorf("level %d: logged %q, want %q", tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestLoggerConcurrentLines(t *testing.T) {
+	const (
+		goroutines = 20
+		lines      = 50
+	)
+
+	inner := &byteWriter{mu: sync.Mutex{}, buf: bytes.Buffer{}}
+	log := NewLogger(inner, LogNormal)
+
+	var wg sync.WaitGroup
+
+	for g := range goroutines {
+		wg.Go(func() {
+			for i := range lines {
+				log.Info("worker-%02d line-%03d %s", g, i, strings.Repeat("x", 32))
+			}
+		})
+	}
+
+	wg.Wait()
+
+	got := strings.Split(strings.TrimSuffix(inner.buf.String(), "\n"), "\n")
+	if len(got) != goroutines*lines {
+		t.Fatalf("got %d lines, want %d", len(got), goroutines*lines)
+	}
+
+	for _, line := range got {
+		var g, i int
+
+		var tail string
+		if n, err := fmt.Sscanf(line, "▶ worker-%d line-%d %s", &g, &i, &tail); err != nil || n != 3 || tail != strings.Repeat("x", 32) {
+			t.Fatalf("garbled line %q", line)
+		}
+	}
+}
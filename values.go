@@ -0,0 +1,169 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// valuesPin is a version in a Helm values file annotated with the ArtifactHub
+// repository it tracks.
+type valuesPin struct {
+	Key  []string // Path of mapping keys leading to the version
+	Repo string
+
+	AllowPrerelease bool
+	Constraint      string
+	Pinned          bool
+	Ignore          []string
+}
+
+// MakeValuesDiscoverer creates a function that finds the versions annotated in
+// a Helm values file, such as the sub-chart versions of an umbrella chart:
+//
+//	redis:
+//	  # artifacthub: bitnami/redis
+//	  version: 18.1.0
+//
+// Each annotated key becomes one ChartInfo. Only the first document is read.
+func MakeValuesDiscoverer(readYaml YAMLReader) func(path string) ([]ChartInfo, error) {
+	return func(path string) ([]ChartInfo, error) {
+		docs, err := readYaml(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(docs) == 0 {
+			return nil, nil
+		}
+
+		pins, err := annotatedValues(docRoot(docs[0]), nil)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		charts := make([]ChartInfo, 0, len(pins))
+		for _, p := range pins {
+			charts = append(charts, ChartInfo{File: path, Repo: p.Repo, Timeout: 0, Labels: nil, Dir: ".", ValuesKey: p.Key, Source: "", Transforms: nil, AllowPrerelease: p.AllowPrerelease, Constraint: p.Constraint, Pinned: p.Pinned, Ignore: p.Ignore})
+		}
+
+		return charts, nil
+	}
+}
+
+// discoverValuesFiles runs discover on every path and concatenates the results.
+func discoverValuesFiles(discover func(string) ([]ChartInfo, error), paths []string) ([]ChartInfo, error) {
+	var charts []ChartInfo
+
+	for _, path := range paths {
+		found, err := discover(path)
+		if err != nil {
+			return nil, err
+		}
+
+		charts = append(charts, found...)
+	}
+
+	return charts, nil
+}
+
+// annotatedValues walks the mappings under n and returns every scalar value
+// whose key carries an "# artifacthub:" comment, in document order.
+func annotatedValues(n *yaml.Node, prefix []string) ([]valuesPin, error) {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	var pins []valuesPin
+
+	for i := 0; i+1 < len(n.Content); i += mappingNodeStep {
+		key, val := n.Content[i], n.Content[i+1]
+		path := append(slices.Clone(prefix), key.Value)
+
+		comment, err := keyArtifactHubRepo(key)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", formatKey(path), err)
+		}
+
+		if comment.Repo != "" && val.Kind == yaml.ScalarNode {
+			pins = append(pins, valuesPin{
+				Key: path, Repo: comment.Repo, AllowPrerelease: comment.AllowPrerelease, Constraint: comment.Constraint,
+				Pinned: comment.Pinned, Ignore: comment.Ignore,
+			})
+			continue
+		}
+
+		nested, err := annotatedValues(val, path)
+		if err != nil {
+			return nil, err
+		}
+
+		pins = append(pins, nested...)
+	}
+
+	return pins, nil
+}
+
+// keyArtifactHubRepo parses the "# artifacthub:" comment above a mapping key.
+func keyArtifactHubRepo(key *yaml.Node) (RepoComment, error) {
+	value, ok := commentValue(key.HeadComment, artifactHubPrefix)
+	if !ok {
+		return RepoComment{Repo: "", AllowPrerelease: false, Pinned: false, Constraint: "", Ignore: nil}, nil
+	}
+
+	return parseRepoComment(value)
+}
+
+// formatKey renders a key path the way it reads in the values file, e.g. "redis.version".
+func formatKey(path []string) string {
+	return strings.Join(path, ".")
+}
+
+// findChartVersion returns the version chart pins in docs: its values key, or
+// the targetRevision of the first Application.
+func findChartVersion(docs []*yaml.Node, chart ChartInfo) (string, bool) {
+	if chart.ValuesKey == nil {
+		return findCurrentVersion(docs)
+	}
+
+	if len(docs) == 0 {
+		return "", false
+	}
+
+	n := lookupNode(docRoot(docs[0]), chart.ValuesKey...)
+	if n == nil || n.Kind != yaml.ScalarNode {
+		return "", false
+	}
+
+	return n.Value, true
+}
+
+// setChartVersion sets the version chart pins in docs to version.
+func setChartVersion(docs []*yaml.Node, chart ChartInfo, version string) {
+	if chart.ValuesKey == nil {
+		updateDocuments(docs, version)
+		return
+	}
+
+	if len(docs) > 0 {
+		set(docRoot(docs[0]), version, chart.ValuesKey...)
+	}
+}
@@ -0,0 +1,142 @@
+// SPDX-License-Identifier: GPL-3.0-only
+//
+// Copyright (C) 2026 f-hc <207619282+f-hc@users.noreply.github.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const umbrellaValues = `global:
+  domain: example.com
+redis:
+  enabled: true
+  # artifacthub: bitnami/redis
+  version: 18.1.0
+monitoring:
+  grafana:
+    # owner: observability
+    # artifacthub: grafana/grafana
+    version: "7.0.0"
+  # artifacthub: prometheus-community/prometheus
+  prometheus: {}
+`
+
+func TestValuesDiscoverer(t *testing.T) {
+	dir := t.TempDir()
+	createTestFiles(t, dir, map[string]string{"values.yaml": umbrellaValues})
+	path := filepath.Join(dir, "values.yaml")
+
+	charts, err := MakeValuesDiscoverer(readYAMLDocuments)(path)
+	if err != nil {
+		t.Fatalf("discover error = %v", err)
+	}
+
+	want := []ChartInfo{
+		{File: path, Repo: "bitnami/redis", Timeout: 0, Labels: nil, Dir: ".", ValuesKey: []string{"redis", "version"}, Source: "", Transforms: nil, AllowPrerelease: false, Constraint: ""},
+		{File: path, Repo: "grafana/grafana", Timeout: 0, Labels: nil, Dir: ".", ValuesKey: []string{"monitoring", "grafana", "version"}, Source: "", Transforms: nil, AllowPrerelease: false, Constraint: ""},
+	}
+	if !reflect.DeepEqual(charts, want) {
+		t.Errorf("discover = %+v, want %+v", charts, want)
+	}
+}
+
+func TestValuesDiscovererMalformedComment(t *testing.T) {
+	dir := t.TempDir()
+	createTestFiles(t, dir, map[string]string{"values.yaml": "redis:\n  # artifacthub: bitnami/ redis\n  version: 1.0.0\n"})
+
+	_, err := MakeValuesDiscoverer(readYAMLDocuments)(filepath.Join(dir, "values.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "redis.version") {
+		t.Errorf("discover error = %v, want it to name the key redis.version", err)
+	}
+}
+
+func TestUpdateChartValuesFile(t *testing.T) {
+	dir := t.TempDir()
+	createTestFiles(t, dir, map[string]string{"values.yaml": umbrellaValues})
+	path := filepath.Join(dir, "values.yaml")
+
+	charts, err := MakeValuesDiscoverer(readYAMLDocuments)(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	latest := map[string]string{"bitnami/redis": "18.2.0", "grafana/grafana": "7.0.0"}
+	fetch := func(_ context.Context, q VersionQuery) (VersionInfo, error) { return versionInfo(latest[q.Repo]), nil }
+
+	updater := MakeChartUpdater(Config{Dir: "argoapps"}, readYAMLDocuments, fetch, writeYAMLDocuments, time.Now, discardLog)
+
+	redis := updater(context.Background(), charts[0])
+	assertStatus(t, StatusUpdated, redis.Status)
+	assertString(t, "current", "18.1.0", redis.Current)
+	assertString(t, "latest", "18.2.0", redis.Latest)
+
+	grafana := updater(context.Background(), charts[1])
+	assertStatus(t, StatusUpToDate, grafana.Status)
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.Replace(umbrellaValues, "version: 18.1.0", "version: 18.2.0", 1)
+	if string(content) != want {
+		t.Errorf("values file =\n%s\nwant\n%s", content, want)
+	}
+}
+
+func TestUpdateChartValuesFileWithDocumentStart(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "annotated first key", content: "---\n# artifacthub: bitnami/redis\nredisVersion: 18.1.0\n"},
+		{name: "commented first key", content: "---\n# shared across environments\nredis:\n  # artifacthub: bitnami/redis\n  version: 18.1.0\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			createTestFiles(t, dir, map[string]string{"values.yaml": tt.content})
+			path := filepath.Join(dir, "values.yaml")
+
+			charts, err := MakeValuesDiscoverer(readYAMLDocuments)(path)
+			if err != nil || len(charts) != 1 {
+				t.Fatalf("discover = %+v, %v, want one chart", charts, err)
+			}
+
+			fetch := func(_ context.Context, _ VersionQuery) (VersionInfo, error) { return versionInfo("18.2.0"), nil }
+
+			result := MakeChartUpdater(Config{Dir: "argoapps"}, readYAMLDocuments, fetch, writeYAMLDocuments, time.Now, discardLog)(context.Background(), charts[0])
+			assertStatus(t, StatusUpdated, result.Status)
+
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if want := strings.Replace(tt.content, "18.1.0", "18.2.0", 1); string(content) != want {
+				t.Errorf("values file =\n%s\nwant\n%s", content, want)
+			}
+		})
+	}
+}
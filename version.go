@@ -25,7 +25,26 @@ import (
 )
 
 // versionLess returns true if a < b using semantic versioning comparison.
+// Any number of numeric segments is supported, so four-part versions such as
+// 1.2.3.4 order correctly; missing segments compare as zero. A pre-release
+// sorts before the release it precedes, so 1.5.0-rc.1 < 1.5.0. Build metadata
+// is ignored.
 func versionLess(a, b string) bool {
+	coreA, preA, _ := strings.Cut(withoutBuild(a), "-")
+	coreB, preB, _ := strings.Cut(withoutBuild(b), "-")
+
+	if coreLess(coreA, coreB) {
+		return true
+	}
+
+	if coreLess(coreB, coreA) {
+		return false
+	}
+
+	return prereleaseLess(preA, preB)
+}
+
+func coreLess(a, b string) bool {
 	as := strings.Split(a, ".")
 	bs := strings.Split(b, ".")
 	//nolint:gosec // lengths of slices are non-negative, overflow is not possible here
@@ -41,7 +60,164 @@ func versionLess(a, b string) bool {
 	return found && toInt(valA) < toInt(valB)
 }
 
+// prereleaseLess orders pre-release suffixes as semver does: no suffix (a
+// release) is highest, numeric identifiers compare numerically and below
+// alphanumeric ones, and a shorter list of equal identifiers comes first.
+func prereleaseLess(a, b string) bool {
+	switch {
+	case a == b || a == "":
+		return false
+	case b == "":
+		return true
+	}
+
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+
+	for i := range min(len(as), len(bs)) {
+		if as[i] == bs[i] {
+			continue
+		}
+
+		na, errA := strconv.Atoi(as[i])
+		nb, errB := strconv.Atoi(bs[i])
+
+		switch {
+		case errA == nil && errB == nil:
+			return na < nb
+		case errA == nil || errB == nil:
+			return errA == nil
+		default:
+			return as[i] < bs[i]
+		}
+	}
+
+	return len(as) < len(bs)
+}
+
+// compareVersions orders versions for use with the slices package.
+func compareVersions(a, b string) int {
+	if versionLess(a, b) {
+		return -1
+	}
+
+	if versionLess(b, a) {
+		return 1
+	}
+
+	return 0
+}
+
 func toInt(s string) int {
 	i, _ := strconv.Atoi(s)
 	return i
 }
+
+// BumpLevel names the most significant version segment an update changes.
+type BumpLevel string
+
+const (
+	BumpMajor BumpLevel = "major" // 1.4.0 → 2.0.0
+	BumpMinor BumpLevel = "minor" // 1.4.0 → 1.5.0
+	BumpPatch BumpLevel = "patch" // 1.4.0 → 1.4.1, or any change below the minor
+)
+
+// bumpLevels lists the levels --max-bump accepts, smallest first.
+func bumpLevels() []BumpLevel {
+	return []BumpLevel{BumpPatch, BumpMinor, BumpMajor}
+}
+
+// bumpLevel returns the most significant segment that differs between the
+// cores of current and latest.
+func bumpLevel(current, latest string) BumpLevel {
+	coreC, _, _ := strings.Cut(current, "-")
+	coreL, _, _ := strings.Cut(latest, "-")
+	partsC, partsL := strings.Split(coreC, "."), strings.Split(coreL, ".")
+
+	segment := func(parts []string, i int) int {
+		if i < len(parts) {
+			return toInt(parts[i])
+		}
+
+		return 0
+	}
+
+	switch {
+	case segment(partsC, 0) != segment(partsL, 0):
+		return BumpMajor
+	case segment(partsC, 1) != segment(partsL, 1):
+		return BumpMinor
+	default:
+		return BumpPatch
+	}
+}
+
+// exceedsBump reports whether moving from current up to latest changes a
+// segment more significant than limit allows. An empty limit allows anything.
+func exceedsBump(current, latest string, limit BumpLevel) bool {
+	if limit == "" || !versionLess(current, latest) {
+		return false
+	}
+
+	return slices.Index(bumpLevels(), bumpLevel(current, latest)) > slices.Index(bumpLevels(), limit)
+}
+
+// isPartialPin reports whether v pins only a major.minor release line, such as "1.15".
+func isPartialPin(v string) bool {
+	parts := strings.Split(v, ".")
+
+	return len(parts) == 2 && !slices.ContainsFunc(parts, func(p string) bool {
+		_, err := strconv.Atoi(p)
+		return err != nil
+	})
+}
+
+// sameMajor reports whether v shares the major version of current, e.g.
+// "1.16.0-rc.1" and "1.15.2". An empty current has no major.
+func sameMajor(v, current string) bool {
+	if current == "" {
+		return false
+	}
+
+	majorV, _, _ := strings.Cut(v, ".")
+	majorCurrent, _, _ := strings.Cut(current, ".")
+
+	return majorV == majorCurrent
+}
+
+// inLine reports whether v belongs to the release line, e.g. "1.15.3" is in "1.15".
+func inLine(v, line string) bool {
+	return strings.HasPrefix(v, line+".")
+}
+
+// isPlausibleVersion reports whether v looks like a release version: at least
+// two dot-separated numeric segments, optionally followed by a pre-release suffix.
+func isPlausibleVersion(v string) bool {
+	core, _, _ := strings.Cut(withoutBuild(v), "-")
+	return numericSegments(core, 2)
+}
+
+// isParseableVersion reports whether v can be ordered at all: a numeric core
+// of any length, such as the "2023" of the dated tag 2023-01-01, optionally
+// followed by a suffix.
+func isParseableVersion(v string) bool {
+	core, _, _ := strings.Cut(withoutBuild(v), "-")
+	return numericSegments(core, 1)
+}
+
+// withoutBuild strips the build metadata of a semver version, such as the
+// "+build.1" of 1.2.3+build.1, which takes no part in ordering or stability.
+func withoutBuild(v string) string {
+	v, _, _ = strings.Cut(v, "+")
+	return v
+}
+
+// numericSegments reports whether core is at least minimum dot-separated numbers.
+func numericSegments(core string, minimum int) bool {
+	parts := strings.Split(core, ".")
+
+	return len(parts) >= minimum && !slices.ContainsFunc(parts, func(p string) bool {
+		_, err := strconv.Atoi(p)
+		return err != nil
+	})
+}
@@ -37,6 +37,26 @@ func TestVersionLess(t *testing.T) {
 		{"two digit versions", "1.10.0", "1.9.0", false},
 		{"large versions", "10.20.30", "10.20.29", false},
 		{"v prefix stripped externally", "1.19.1", "1.19.2", true},
+		{"four segments fourth less", "1.2.3.4", "1.2.3.5", true},
+		{"build metadata below next patch", "2.0.2+build.1", "2.0.3", true},
+		{"build metadata above previous patch", "2.0.2+build.1", "2.0.1", false},
+		{"four segments fourth greater", "1.2.3.5", "1.2.3.4", false},
+		{"four segments vs higher patch", "1.2.3.4", "1.2.4", true},
+		{"higher patch vs four segments", "1.2.4", "1.2.3.4", false},
+		{"three segments vs four", "1.2.3", "1.2.3.1", true},
+		{"trailing zero segment is equal", "1.2.3.0", "1.2.3", false},
+		{"pre-release before its release", "1.5.0-rc.1", "1.5.0", true},
+		{"release after its pre-release", "1.5.0", "1.5.0-rc.1", false},
+		{"pre-release after previous release", "1.4.9", "1.5.0-rc.1", true},
+		{"numeric pre-release identifiers", "1.5.0-rc.9", "1.5.0-rc.10", true},
+		{"alpha before beta", "1.5.0-alpha", "1.5.0-beta", true},
+		{"numeric identifier before alphanumeric", "1.5.0-1", "1.5.0-alpha", true},
+		{"shorter pre-release first", "1.5.0-rc", "1.5.0-rc.1", true},
+		{"alpha before its release", "1.0.0-alpha", "1.0.0", true},
+		{"alpha.1 before alpha.2", "1.0.0-alpha.1", "1.0.0-alpha.2", true},
+		{"rc.11 after rc.2", "1.0.0-rc.11", "1.0.0-rc.2", false},
+		{"rc.2 before rc.11", "1.0.0-rc.2", "1.0.0-rc.11", true},
+		{"equal pre-releases", "1.0.0-rc.2", "1.0.0-rc.2", false},
 	}
 
 	for _, tt := range tests {
@@ -48,3 +68,131 @@ func TestVersionLess(t *testing.T) {
 		})
 	}
 }
+
+func TestFourSegmentVersions(t *testing.T) {
+	if !isStableUnder(StabilityDash, "1.2.3.4") {
+		t.Error("isStableUnder(StabilityDash, \"1.2.3.4\") = false, want true")
+	}
+
+	if !isPlausibleVersion("1.2.3.4") {
+		t.Error("isPlausibleVersion(\"1.2.3.4\") = false, want true")
+	}
+
+	got, _, ok := selectVersion([]string{"1.2.3.4", "1.2.3.10", "1.2.3.5", "1.2.4-rc.1"}, testQuery("", ""))
+	if !ok || got != "1.2.3.10" {
+		t.Errorf("selectVersion() = %q, %v, want %q", got, ok, "1.2.3.10")
+	}
+
+	got, _, ok = selectVersion([]string{"1.2.3.4", "1.3.0.1", "1.2.9.9"}, VersionQuery{Repo: "org/chart", Current: "1.2", Timeout: 0, Limit: 0, PrereleaseSameMajor: false, Stability: StabilityDash, RequireSigned: false, Source: "", AllowPrerelease: false, Constraint: "", Ignore: nil})
+	if !ok || got != "1.2.9.9" {
+		t.Errorf("selectVersion() in 1.2 line = %q, %v, want %q", got, ok, "1.2.9.9")
+	}
+}
+
+func TestSameMajor(t *testing.T) {
+	tests := []struct {
+		v, current string
+		want       bool
+	}{
+		{"1.16.0-rc.1", "1.15.2", true},
+		{"2.0.0-rc.1", "1.15.2", false},
+		{"1.0.0", "1.15", true},
+		{"1.0.0", "", false},
+	}
+
+	for _, tt := range tests {
+		if got := sameMajor(tt.v, tt.current); got != tt.want {
+			t.Errorf("sameMajor(%q, %q) = %v, want %v", tt.v, tt.current, got, tt.want)
+		}
+	}
+}
+
+func TestExceedsBump(t *testing.T) {
+	tests := []struct {
+		current, latest string
+		limit           BumpLevel
+		want            bool
+	}{
+		{"1.4.0", "2.0.0", BumpMinor, true},
+		{"1.4.0", "1.9.2", BumpMinor, false},
+		{"1.4.0", "1.9.2", BumpPatch, true},
+		{"1.4.0", "1.4.7", BumpPatch, false},
+		{"1.4.0", "2.0.0", BumpMajor, false},
+		{"1.4.0", "2.0.0", "", false},
+		{"1.4.0-rc.1", "1.4.0", BumpPatch, false},
+		{"1.4", "1.5.0", BumpPatch, true},
+		{"2.0.0", "1.0.0", BumpPatch, false},
+	}
+
+	for _, tt := range tests {
+		if got := exceedsBump(tt.current, tt.latest, tt.limit); got != tt.want {
+			t.Errorf("exceedsBump(%q, %q, %q) = %v, want %v", tt.current, tt.latest, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestIsPartialPin(t *testing.T) {
+	tests := []struct {
+		v    string
+		want bool
+	}{
+		{"1.15", true},
+		{"0.9", true},
+		{"1.15.0", false},
+		{"1", false},
+		{"1.x", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := isPartialPin(tt.v); got != tt.want {
+			t.Errorf("isPartialPin(%q) = %v, want %v", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestIsPlausibleVersion(t *testing.T) {
+	tests := []struct {
+		v    string
+		want bool
+	}{
+		{"1.16.0", true},
+		{"1.2", true},
+		{"2.0.0-rc.1", true},
+		{"2.0.2+build.1", true},
+		{"latest", false},
+		{"1", false},
+		{"", false},
+		{"v1.2.3", false},
+	}
+
+	for _, tt := range tests {
+		if got := isPlausibleVersion(tt.v); got != tt.want {
+			t.Errorf("isPlausibleVersion(%q) = %v, want %v", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestIsStableUnder(t *testing.T) {
+	tests := []struct {
+		rule StabilityRule
+		v    string
+		want bool
+	}{
+		{rule: StabilityDash, v: "1.2.3", want: true},
+		{rule: StabilityDash, v: "1.2.3-rc.1", want: false},
+		{rule: StabilityDash, v: "2023-01-01", want: false},
+		{rule: StabilityDash, v: "1.2.3+build-7", want: true},
+		{rule: StabilitySemverPrerelease, v: "1.2.3-rc.1", want: false},
+		{rule: StabilitySemverPrerelease, v: "1.2-beta", want: false},
+		{rule: StabilitySemverPrerelease, v: "2023-01-01", want: true},
+		{rule: StabilitySemverPrerelease, v: "1.2.3", want: true},
+		{rule: StabilityNone, v: "1.2.3-rc.1", want: true},
+	}
+
+	for _, tt := range tests {
+		if got := isStableUnder(tt.rule, tt.v); got != tt.want {
+			t.Errorf("isStableUnder(%q, %q) = %v, want %v", tt.rule, tt.v, got, tt.want)
+		}
+	}
+}
@@ -17,23 +17,62 @@
 package main
 
 import (
+	"bytes"
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"unicode"
 
 	"gopkg.in/yaml.v3"
 )
 
+// readYAMLDocuments reads every document of the manifest at path, which may
+// also be a JSON manifest; see decodeJSONDocument.
 func readYAMLDocuments(path string) ([]*yaml.Node, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("open yaml file: %w", err)
 	}
 
-	docs, err := decodeStream(yaml.NewDecoder(f))
+	var docs []*yaml.Node
+	if isJSONFile(path) {
+		docs, err = decodeJSONDocument(f)
+	} else {
+		docs, err = decodeStream(yaml.NewDecoder(f))
+	}
+
+	closeFile(f, &err)
+
+	return docs, err
+}
+
+// readFirstArtifactHubApplication is a YAMLReader for discovery: it stops decoding
+// after the first Application document carrying a source comment, so large
+// bundles are not decoded past the point discovery needs. Use readYAMLDocuments
+// when every document is required, as when updating.
+func readFirstArtifactHubApplication(path string) ([]*yaml.Node, error) {
+	if isJSONFile(path) {
+		return readYAMLDocuments(path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open yaml file: %w", err)
+	}
+
+	docs, err := decodeStreamUntil(yaml.NewDecoder(f), func(n *yaml.Node) bool {
+		return slices.ContainsFunc(sourcePrefixes(), func(prefix string) bool {
+			_, found := headComment(n, prefix)
+			return found
+		}) && isSupportedKind(n)
+	})
 	closeFile(f, &err)
 
 	return docs, err
@@ -46,6 +85,12 @@ func closeFile(c io.Closer, err *error) {
 }
 
 func decodeStream(dec *yaml.Decoder) ([]*yaml.Node, error) {
+	return decodeStreamUntil(dec, func(*yaml.Node) bool { return false })
+}
+
+// decodeStreamUntil decodes documents until one satisfies stop, which is
+// included in the result, or until the stream ends.
+func decodeStreamUntil(dec *yaml.Decoder, stop func(*yaml.Node) bool) ([]*yaml.Node, error) {
 	var n yaml.Node
 	if err := dec.Decode(&n); err != nil {
 		if errors.Is(err, io.EOF) {
@@ -55,7 +100,11 @@ func decodeStream(dec *yaml.Decoder) ([]*yaml.Node, error) {
 		return nil, fmt.Errorf("decode yaml: %w", err)
 	}
 
-	rest, err := decodeStream(dec)
+	if stop(&n) {
+		return []*yaml.Node{&n}, nil
+	}
+
+	rest, err := decodeStreamUntil(dec, stop)
 	if err != nil {
 		return nil, err
 	}
@@ -64,54 +113,213 @@ func decodeStream(dec *yaml.Decoder) ([]*yaml.Node, error) {
 }
 
 const (
-	yamlIndent        = 2
-	mappingNodeStep   = 2
-	artifactHubPrefix = "# artifacthub:"
-	KindApplication   = "Application"
+	yamlIndent         = 2
+	mappingNodeStep    = 2
+	artifactHubPrefix  = "# artifacthub:"
+	gitHubPrefix       = "# github:"
+	helmRepoPrefix     = "# helmrepo:"
+	ociPrefix          = "# oci:"
+	timeoutPrefix      = "# artifacthub-timeout:"
+	transformPrefix    = "# artifacthub-transform:"
+	lastCheckedPrefix  = "# last-checked:"
+	KindApplication    = "Application"
+	KindApplicationSet = "ApplicationSet"
 )
 
+// supportedKinds lists the resource kinds whose chart versions can be updated.
+func supportedKinds() []string {
+	return []string{KindApplication, KindApplicationSet}
+}
+
+// isSupportedKind reports whether n is a resource of one of supportedKinds.
+func isSupportedKind(n *yaml.Node) bool {
+	return slices.Contains(supportedKinds(), kind(n))
+}
+
+// writeYAMLDocuments is the YAMLWriter for real runs. The file is left
+// untouched when its encoded form already matches what is on disk, so a
+// repeated run is a no-op down to the modification time.
 func writeYAMLDocuments(_ context.Context, path string, docs []*yaml.Node) error {
-	f, err := os.Create(path)
+	return writeYAMLFile(path, docs, encoderFor(path), nil)
+}
+
+// encoderFor returns the encoding docs are stored in at path: JSON for a .json
+// manifest, YAML otherwise.
+func encoderFor(path string) func(io.Writer, []*yaml.Node) error {
+	if isJSONFile(path) {
+		return encodeJSONDocuments
+	}
+
+	return encodeYAMLDocuments
+}
+
+// MakeVerifyingYAMLWriter creates a YAMLWriter that, before replacing a file,
+// re-reads what was written and checks it still parses and, for Application
+// manifests, exposes the targetRevision docs carry. A file failing the check
+// is never put in place. With preserveFormat files are edited in place; see
+// preservingEncoder.
+func MakeVerifyingYAMLWriter(preserveFormat bool) YAMLWriter {
+	return func(_ context.Context, path string, docs []*yaml.Node) error {
+		encode := preservingEncoder(path, preserveFormat, encoderFor(path))
+
+		want, ok := findCurrentVersion(docs)
+		if !ok {
+			return writeYAMLFile(path, docs, encode, verifyParses)
+		}
+
+		return writeYAMLFile(path, docs, encode, verifyTargetRevision(want))
+	}
+}
+
+// writeYAMLFile encodes docs into a temporary file next to path and renames it
+// over path, so readers never see a partial write. A symlinked path has its
+// target replaced, as writing through the link would. When verify rejects the
+// temporary file it is removed and path is left as it was.
+func writeYAMLFile(
+	path string,
+	docs []*yaml.Node,
+	encode func(io.Writer, []*yaml.Node) error,
+	verify func(tmpPath string) error,
+) error {
+	var buf bytes.Buffer
+	if err := encode(&buf, docs); err != nil {
+		return err
+	}
+
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, buf.Bytes()) {
+		return nil
+	}
+
+	tmp, err := writeTempFile(path, buf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	if verify != nil {
+		if verifyErr := verify(tmp); verifyErr != nil {
+			_ = os.Remove(tmp)
+			return fmt.Errorf("verify %s: %w; file left unchanged", path, verifyErr)
+		}
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("replace yaml file: %w", err)
+	}
+
+	return nil
+}
+
+// writeTempFile writes data to a new file beside path, carrying over path's
+// permissions when it exists, and returns the new file's name.
+func writeTempFile(path string, data []byte) (string, error) {
+	const newFileMode = 0o644
+
+	mode := os.FileMode(newFileMode)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return "", fmt.Errorf("create yaml file: %w", err)
+	}
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Chmod(mode)
+	}
+
+	closeFile(f, &err)
+
 	if err != nil {
-		return fmt.Errorf("create yaml file: %w", err)
+		_ = os.Remove(f.Name())
+		return "", fmt.Errorf("write yaml file: %w", err)
+	}
+
+	return f.Name(), nil
+}
+
+// verifyParses checks that the file at path still parses as YAML, for files
+// such as Helm values that have no targetRevision.
+func verifyParses(path string) error {
+	if _, err := readYAMLDocuments(path); err != nil {
+		return fmt.Errorf("written file does not parse: %w", err)
 	}
 
-	var writeErr error
-	defer closeFile(f, &writeErr)
+	return nil
+}
+
+// verifyTargetRevision returns a check that the file at a path parses as YAML
+// and its Application's targetRevision is want.
+func verifyTargetRevision(want string) func(path string) error {
+	return func(path string) error {
+		docs, err := readYAMLDocuments(path)
+		if err != nil {
+			return fmt.Errorf("written file does not parse: %w", err)
+		}
+
+		if got, ok := findCurrentVersion(docs); !ok || got != want {
+			return fmt.Errorf("written file has targetRevision %q, want %q", got, want)
+		}
 
-	enc := yaml.NewEncoder(f)
+		return nil
+	}
+}
 
+// encodeYAMLDocuments writes docs the way they are stored on disk: the first
+// document's artifacthub comment block, a separator, then every document. A
+// comment that followed the separator, as in a values file opening with "---",
+// stays below it.
+func encodeYAMLDocuments(w io.Writer, docs []*yaml.Node) error {
+	enc := yaml.NewEncoder(w)
 	enc.SetIndent(yamlIndent)
-	defer closeFile(enc, &writeErr)
 
 	nodes := docs
 	if len(docs) > 0 {
 		first, comment := extractComment(docs[0])
-		if comment != "" {
-			if _, writeErr = fmt.Fprintf(f, "%s\n---\n", comment); writeErr != nil {
-				writeErr = fmt.Errorf("write yaml comment: %w", writeErr)
-				return writeErr
+
+		switch {
+		case comment != "":
+			if _, err := fmt.Fprintf(w, "%s\n---\n", comment); err != nil {
+				return fmt.Errorf("write yaml comment: %w", err)
 			}
 
 			nodes = append([]*yaml.Node{first}, docs[1:]...)
+		case commentAfterStart(docs[0]):
+			if _, err := io.WriteString(w, "---\n"); err != nil {
+				return fmt.Errorf("write yaml separator: %w", err)
+			}
 		}
 	}
 
-	if writeErr = encodeStream(enc, nodes); writeErr != nil {
-		return writeErr
+	if err := encodeStream(enc, nodes); err != nil {
+		return err
+	}
+
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("close yaml encoder: %w", err)
 	}
 
-	return writeErr
+	return nil
 }
 
+// extractComment returns n without the source comment block on its first key,
+// and that block, unless it follows an explicit document start.
 func extractComment(n *yaml.Node) (*yaml.Node, string) {
 	root := docRoot(n)
-	if root.Kind != yaml.MappingNode || len(root.Content) == 0 {
+	if root.Kind != yaml.MappingNode || len(root.Content) == 0 || commentAfterStart(n) {
 		return n, ""
 	}
 
 	firstKey := root.Content[0]
-	if !strings.HasPrefix(firstKey.HeadComment, artifactHubPrefix) {
+	if !slices.ContainsFunc(sourcePrefixes(), func(prefix string) bool {
+		return strings.HasPrefix(firstKey.HeadComment, prefix)
+	}) {
 		return n, ""
 	}
 
@@ -141,6 +349,31 @@ func extractComment(n *yaml.Node) (*yaml.Node, string) {
 	return &newRoot, comment
 }
 
+// commentAfterStart reports whether the head comment on the first key of the
+// document n follows the document's "---" rather than preceding it. yaml.v3
+// keeps the comment on the key either way, but places the document at the
+// "---" line, which only then comes before the comment.
+func commentAfterStart(n *yaml.Node) bool {
+	root := docRoot(n)
+	if n.Kind != yaml.DocumentNode || root.Kind != yaml.MappingNode || len(root.Content) == 0 {
+		return false
+	}
+
+	key := root.Content[0]
+	commentLine := key.Line - strings.Count(key.HeadComment, "\n") - 1
+
+	return key.HeadComment != "" && n.Line < commentLine
+}
+
+// sortDocumentsByKind returns docs ordered alphabetically by kind, keeping
+// the original order among documents of the same kind.
+func sortDocumentsByKind(docs []*yaml.Node) []*yaml.Node {
+	sorted := slices.Clone(docs)
+	slices.SortStableFunc(sorted, func(a, b *yaml.Node) int { return cmp.Compare(kind(a), kind(b)) })
+
+	return sorted
+}
+
 func encodeStream(enc *yaml.Encoder, docs []*yaml.Node) error {
 	if len(docs) == 0 {
 		return nil
@@ -165,43 +398,397 @@ func kind(n *yaml.Node) string {
 	return lookup(docRoot(n), "kind")
 }
 
+// sourcePath is the key path of key in n's Helm source: spec.source for an
+// Application, and that of the generated Applications' template for an
+// ApplicationSet. A multi-source resource's spec.sources entry is used
+// instead when it has one; see chartSourceIndex.
+func sourcePath(n *yaml.Node, key string) []string {
+	spec := []string{"spec"}
+	if kind(n) == KindApplicationSet {
+		spec = []string{"spec", "template", "spec"}
+	}
+
+	if i, ok := chartSourceIndex(n, lookupNode(docRoot(n), append(spec, "sources")...)); ok {
+		return append(spec, "sources", strconv.Itoa(i), key)
+	}
+
+	return append(spec, "source", key)
+}
+
+// chartSourceIndex picks the Helm chart entry of a spec.sources sequence: the
+// one whose chart is named in n's source comment, or else the first entry
+// with a chart at all. Entries without one, such as a values repository, are
+// never picked.
+func chartSourceIndex(n, sources *yaml.Node) (int, bool) {
+	if sources == nil || sources.Kind != yaml.SequenceNode {
+		return 0, false
+	}
+
+	comment, _, _ := parseChartSource(n)
+	first := -1
+
+	for i, source := range sources.Content {
+		chart := lookup(source, "chart")
+
+		switch {
+		case chart == "":
+			continue
+		case comment.Repo != "" && chart == chartName(comment.Repo):
+			return i, true
+		case first < 0:
+			first = i
+		}
+	}
+
+	return first, first >= 0
+}
+
 func getTargetRevision(n *yaml.Node) string {
-	return lookup(docRoot(n), "spec", "source", "targetRevision")
+	return lookup(docRoot(n), sourcePath(n, "targetRevision")...)
+}
+
+// metadataLabels returns the Application's metadata.labels merged with its
+// metadata.annotations (annotations win on conflicting keys).
+func metadataLabels(n *yaml.Node) map[string]string {
+	labels := make(map[string]string)
+
+	ForEach(slices.Values([]string{"labels", "annotations"}), func(field string) {
+		m := lookupNode(docRoot(n), "metadata", field)
+		if m == nil || m.Kind != yaml.MappingNode {
+			return
+		}
+
+		for i := 0; i+1 < len(m.Content); i += mappingNodeStep {
+			labels[m.Content[i].Value] = m.Content[i+1].Value
+		}
+	})
+
+	return labels
+}
+
+func targetRevisionNode(n *yaml.Node) *yaml.Node {
+	return lookupNode(docRoot(n), sourcePath(n, "targetRevision")...)
 }
 
 func setTargetRevision(n *yaml.Node, v string) {
-	set(docRoot(n), v, "spec", "source", "targetRevision")
+	set(docRoot(n), v, sourcePath(n, "targetRevision")...)
+}
+
+// prereleaseMarker, as the word after the repository in a source comment,
+// opts that chart in to pre-release versions.
+const prereleaseMarker = "prerelease"
+
+// ignorePrefix marks a word after the repository in a source comment as a
+// version the chart is never updated to, e.g. "!2.3.0".
+const ignorePrefix = "!"
+
+// pinnedMarker, as a word after the repository in a source comment, freezes
+// the chart: it is reported but neither fetched nor rewritten.
+const pinnedMarker = "pinned"
+
+// RepoComment is the parsed text of an "# artifacthub:", "# github:",
+// "# helmrepo:" or "# oci:" comment.
+type RepoComment struct {
+	Repo            string // Repository path, e.g. "org/chart"
+	AllowPrerelease bool   // The repository is followed by prereleaseMarker
+	Pinned          bool   // The repository is followed by pinnedMarker
+	Constraint      string // Version constraint following the repository, e.g. ">=1.2.0 <2.0.0"
+
+	Ignore []string // Versions never updated to, written as "!2.3.0" after the repository
+}
+
+// String renders the comment text the way it is written after the prefix.
+func (c RepoComment) String() string {
+	fields := []string{c.Repo}
+	if c.AllowPrerelease {
+		fields = append(fields, prereleaseMarker)
+	}
+
+	if c.Pinned {
+		fields = append(fields, pinnedMarker)
+	}
+
+	for _, v := range c.Ignore {
+		fields = append(fields, ignorePrefix+v)
+	}
+
+	if c.Constraint != "" {
+		fields = append(fields, c.Constraint)
+	}
+
+	return strings.Join(fields, " ")
 }
 
-// getArtifactHubRepo extracts the ArtifactHub repository path from a YAML comment.
-// It looks for a comment in the format "# artifacthub: org/repo" at the top of the file.
+// getArtifactHubRepo extracts the ArtifactHub repository from a YAML comment.
+// It looks for a comment in the format "# artifacthub: org/repo" at the top of the file,
+// optionally followed by the prerelease marker.
 // In yaml.v3, this comment is attached to the first key of the root mapping node.
-func getArtifactHubRepo(n *yaml.Node) string {
+// Malformed comments yield an empty Repo; use parseArtifactHubRepo to get the reason.
+func getArtifactHubRepo(n *yaml.Node) RepoComment {
+	comment, err := parseArtifactHubRepo(n)
+	if err != nil {
+		return RepoComment{Repo: "", AllowPrerelease: false, Pinned: false, Constraint: "", Ignore: nil}
+	}
+
+	return comment
+}
+
+// parseArtifactHubRepo is like getArtifactHubRepo but reports malformed comments.
+// Leading and trailing Unicode whitespace (including tabs) is trimmed; whitespace
+// inside the repository path is rejected rather than passed through to the API.
+func parseArtifactHubRepo(n *yaml.Node) (RepoComment, error) {
+	value, ok := artifactHubComment(n)
+	if !ok {
+		return RepoComment{Repo: "", AllowPrerelease: false, Pinned: false, Constraint: "", Ignore: nil}, nil
+	}
+
+	return parseRepoComment(value)
+}
+
+// sourcePrefixes lists the comment prefixes that name a chart's source,
+// artifactHubPrefix first.
+func sourcePrefixes() []string {
+	return []string{artifactHubPrefix, gitHubPrefix, helmRepoPrefix, ociPrefix}
+}
+
+// sourceGitHub is the ChartInfo and VersionQuery Source of charts published as
+// GitHub releases; ArtifactHub charts have an empty Source.
+const sourceGitHub = "github"
+
+// parseChartSource is like parseArtifactHubRepo but also accepts a
+// "# github: owner/repo", "# helmrepo: https://charts.example.com chart" or
+// "# oci: ghcr.io/org/chart" comment, reporting which source the repo belongs to. An artifacthub comment
+// wins when a document carries several, then a github one.
+func parseChartSource(n *yaml.Node) (RepoComment, string, error) {
+	if value, ok := artifactHubComment(n); ok {
+		comment, err := parseRepoComment(value)
+		return comment, "", err
+	}
+
+	if value, ok := headComment(n, gitHubPrefix); ok {
+		comment, err := parseRepoPathComment(sourceGitHub, value, orgRepoSegments)
+		return comment, sourceGitHub, err
+	}
+
+	if value, ok := headComment(n, helmRepoPrefix); ok {
+		comment, err := parseHelmRepoComment(value)
+		return comment, sourceHelmRepo, err
+	}
+
+	if value, ok := headComment(n, ociPrefix); ok {
+		comment, err := parseOCIComment(value)
+		return comment, sourceOCI, err
+	}
+
+	return RepoComment{Repo: "", AllowPrerelease: false, Pinned: false, Constraint: "", Ignore: nil}, "", nil
+}
+
+// errMalformedComment marks a manifest whose source comment, or one of the
+// artifacthub-* annotations accompanying it, cannot be parsed.
+var errMalformedComment = errors.New("malformed source comment")
+
+// Most segments of the repository paths source comments accept.
+const (
+	orgRepoSegments     = 2 // "org/repo"
+	artifactHubSegments = 3 // "org/repo" or, to disambiguate, "publisher/repo/package"
+)
+
+// parseRepoComment validates the text following an artifacthub prefix.
+func parseRepoComment(value string) (RepoComment, error) {
+	return parseRepoPathComment("artifacthub", value, artifactHubSegments)
+}
+
+// parseRepoPathComment is parseSourceComment for the sources whose repository
+// is a slash-separated path of two to maxSegments names, checking its shape up
+// front rather than leaving a typo such as "orgrepo" to fail as a 404 from the API.
+func parseRepoPathComment(source, value string, maxSegments int) (RepoComment, error) {
+	comment, err := parseSourceComment(source, value)
+	if err != nil {
+		return RepoComment{}, err
+	}
+
+	if err := validateRepoPath(source, comment.Repo, maxSegments); err != nil {
+		return RepoComment{}, err
+	}
+
+	return comment, nil
+}
+
+// validateRepoPath checks that repo is two to maxSegments non-empty names
+// separated by single slashes, each made of ASCII letters, digits, '.', '-'
+// and '_' and none of them "." or "..".
+func validateRepoPath(source, repo string, maxSegments int) error {
+	segments := strings.Split(repo, "/")
+	if len(segments) < orgRepoSegments || len(segments) > maxSegments ||
+		slices.ContainsFunc(segments, func(s string) bool { return s == "" || s == "." || s == ".." }) {
+		want := "org/repo"
+		if maxSegments > orgRepoSegments {
+			want += " or publisher/repo/package"
+		}
+
+		return fmt.Errorf("invalid %s repo %q: want %s", source, repo, want)
+	}
+
+	for _, r := range strings.Join(segments, "") {
+		if !isRepoNameRune(r) {
+			return fmt.Errorf("invalid %s repo %q: unexpected character %q", source, repo, r)
+		}
+	}
+
+	return nil
+}
+
+// isRepoNameRune reports whether r may appear in a segment of a repository path.
+func isRepoNameRune(r rune) bool {
+	return r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune(".-_", r))
+}
+
+// parseSourceComment validates the text following the prefix naming source:
+// a repository, optionally followed by prereleaseMarker and then by the terms
+// of a version constraint.
+func parseSourceComment(source, value string) (RepoComment, error) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return RepoComment{}, fmt.Errorf("empty %s repo", source)
+	}
+
+	comment := RepoComment{Repo: fields[0], AllowPrerelease: false, Pinned: false, Constraint: "", Ignore: nil}
+	rest := fields[1:]
+
+	for ; len(rest) > 0 && isMarkerWord(rest[0]); rest = rest[1:] {
+		switch word := rest[0]; word {
+		case prereleaseMarker:
+			comment.AllowPrerelease = true
+		case pinnedMarker:
+			comment.Pinned = true
+		default:
+			v := strings.TrimPrefix(word, ignorePrefix)
+			if !isParseableVersion(v) {
+				return RepoComment{}, fmt.Errorf("invalid %s ignored version %q for %s", source, word, comment.Repo)
+			}
+
+			comment.Ignore = append(comment.Ignore, v)
+		}
+	}
+
+	if len(rest) > 0 && isConstraintTerm(rest[0]) {
+		comment.Constraint = strings.Join(rest, " ")
+		if _, err := parseConstraint(comment.Constraint); err != nil {
+			return RepoComment{}, fmt.Errorf("invalid %s constraint for %s: %w", source, comment.Repo, err)
+		}
+
+		rest = nil
+	}
+
+	if len(rest) > 0 {
+		return RepoComment{}, fmt.Errorf("invalid %s repo %q: must not contain whitespace",
+			source, strings.Join(fields, " "))
+	}
+
+	return comment, nil
+}
+
+// isMarkerWord reports whether word, following the repository in a source
+// comment, is a marker or an ignored version rather than a constraint term.
+func isMarkerWord(word string) bool {
+	return word == prereleaseMarker || word == pinnedMarker || strings.HasPrefix(word, ignorePrefix)
+}
+
+// artifactHubComment returns the raw text following the artifacthub prefix.
+func artifactHubComment(n *yaml.Node) (string, bool) {
+	return headComment(n, artifactHubPrefix)
+}
+
+// headComment returns the raw text following prefix on the first matching line
+// of the comment block at the top of the document.
+func headComment(n *yaml.Node, prefix string) (string, bool) {
 	root := docRoot(n)
 
 	// The comment is attached to the first key in a mapping node
-	if root.Kind == yaml.MappingNode && len(root.Content) > 0 {
-		firstKey := root.Content[0]
-		if after, ok := strings.CutPrefix(firstKey.HeadComment, artifactHubPrefix); ok {
-			return strings.TrimSpace(after)
+	if root.Kind != yaml.MappingNode || len(root.Content) == 0 {
+		return "", false
+	}
+
+	return commentValue(root.Content[0].HeadComment, prefix)
+}
+
+// commentValue returns the raw text following prefix on the first matching
+// line of comment.
+func commentValue(comment, prefix string) (string, bool) {
+	for line := range strings.Lines(comment) {
+		if after, ok := strings.CutPrefix(strings.TrimRight(line, "\n"), prefix); ok {
+			return after, true
 		}
 	}
 
-	return ""
+	return "", false
+}
+
+// setArtifactHubRepo rewrites the "# artifacthub:" comment line to comment,
+// leaving any other comment lines untouched.
+func setArtifactHubRepo(n *yaml.Node, comment RepoComment) {
+	setHeadComment(n, artifactHubPrefix, comment.String())
+}
+
+// setHeadComment sets the comment line starting with prefix in the comment
+// block at the top of the document to "prefix value", replacing the first
+// existing line with that prefix or appending one to the end of the block.
+func setHeadComment(n *yaml.Node, prefix, value string) {
+	root := docRoot(n)
+	if root.Kind != yaml.MappingNode || len(root.Content) == 0 {
+		return
+	}
+
+	key := root.Content[0]
+	replacement := prefix + " " + value
+
+	var (
+		b        strings.Builder
+		replaced bool
+	)
+
+	for line := range strings.Lines(key.HeadComment) {
+		if replaced || !strings.HasPrefix(line, prefix) {
+			b.WriteString(line)
+			continue
+		}
+
+		b.WriteString(replacement)
+
+		if strings.HasSuffix(line, "\n") {
+			b.WriteString("\n")
+		}
+
+		replaced = true
+	}
+
+	if !replaced {
+		if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") {
+			b.WriteString("\n")
+		}
+
+		b.WriteString(replacement)
+	}
+
+	key.HeadComment = b.String()
 }
 
 func lookup(n *yaml.Node, path ...string) string {
-	if n == nil {
-		return ""
+	if v := lookupNode(n, path...); v != nil {
+		return v.Value
 	}
 
-	if len(path) == 0 {
-		return n.Value
+	return ""
+}
+
+func lookupNode(n *yaml.Node, path ...string) *yaml.Node {
+	if n == nil || len(path) == 0 {
+		return n
 	}
 
 	head, tail := path[0], path[1:]
 
-	return lookup(mapGet(n, head), tail...)
+	return lookupNode(child(n, head), tail...)
 }
 
 func set(n *yaml.Node, value string, path ...string) {
@@ -212,7 +799,7 @@ func set(n *yaml.Node, value string, path ...string) {
 
 	head, tail := path[0], path[1:]
 
-	next := mapGet(n, head)
+	next := child(n, head)
 	if next == nil {
 		next = &yaml.Node{Kind: yaml.MappingNode}
 		mapSet(n, head, next)
@@ -221,6 +808,21 @@ func set(n *yaml.Node, value string, path ...string) {
 	set(next, value, tail...)
 }
 
+// child returns the value of key in a mapping node or, when n is a sequence,
+// the item at the index key spells.
+func child(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.SequenceNode {
+		return mapGet(n, key)
+	}
+
+	i, err := strconv.Atoi(key)
+	if err != nil || i < 0 || i >= len(n.Content) {
+		return nil
+	}
+
+	return n.Content[i]
+}
+
 func mapGet(n *yaml.Node, key string) *yaml.Node {
 	if n == nil || n.Kind != yaml.MappingNode {
 		return nil
@@ -249,3 +851,68 @@ func mapSet(n *yaml.Node, key string, val *yaml.Node) {
 		val,
 	)
 }
+
+// replaceScalarAt rewrites the scalar token starting at the 1-based column col
+// of line with value, keeping the token's quoting style and the rest of the line.
+func replaceScalarAt(line string, col int, style yaml.Style, value string) string {
+	start := byteOffset(line, col)
+	if start < 0 {
+		return line
+	}
+
+	end := scalarEnd(line, start)
+
+	return line[:start] + quoteScalar(value, style) + line[end:]
+}
+
+// byteOffset converts the 1-based column col, which yaml.Node counts in runes,
+// into a byte index into line, or -1 when line is shorter.
+func byteOffset(line string, col int) int {
+	if col < 1 {
+		return -1
+	}
+
+	for i := range line {
+		if col == 1 {
+			return i
+		}
+
+		col--
+	}
+
+	if col == 1 {
+		return len(line)
+	}
+
+	return -1
+}
+
+// scalarEnd returns the index just past the scalar token beginning at start.
+// A plain scalar ends at whitespace or at a flow collection's ',', ']' or '}'.
+func scalarEnd(line string, start int) int {
+	if start < len(line) && (line[start] == '"' || line[start] == '\'') {
+		if i := strings.IndexByte(line[start+1:], line[start]); i >= 0 {
+			return start + 1 + i + 1
+		}
+
+		return len(line)
+	}
+
+	end := start
+	for end < len(line) && !strings.ContainsRune(" \t,]}", rune(line[end])) {
+		end++
+	}
+
+	return end
+}
+
+func quoteScalar(value string, style yaml.Style) string {
+	switch {
+	case style&yaml.DoubleQuotedStyle != 0:
+		return `"` + value + `"`
+	case style&yaml.SingleQuotedStyle != 0:
+		return "'" + value + "'"
+	default:
+		return value
+	}
+}
@@ -18,8 +18,10 @@ package main
 
 import (
 	"context"
+	"io"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -103,6 +105,84 @@ func TestReadYAMLDocumentsFileNotFound(t *testing.T) {
 	}
 }
 
+// writeLargeBundle writes an Application with an artifacthub comment followed by
+// n filler documents and, last, a document that fails to decode.
+func writeLargeBundle(tb testing.TB, n int) string {
+	tb.Helper()
+
+	var b strings.Builder
+
+	b.WriteString("# artifacthub: org/chart\nkind: Application\nspec:\n  source:\n    targetRevision: 1.0.0\n")
+
+	for range n {
+		b.WriteString("---\nkind: ConfigMap\ndata:\n  key: value\n")
+	}
+
+	b.WriteString("---\nkind: [unterminated\n")
+
+	path := filepath.Join(tb.TempDir(), "bundle.yaml")
+	if err := os.WriteFile(path, []byte(b.String()), 0o600); err != nil {
+		tb.Fatal(err)
+	}
+
+	return path
+}
+
+func TestReadFirstArtifactHubApplicationStopsEarly(t *testing.T) {
+	path := writeLargeBundle(t, 1000)
+
+	if _, err := readYAMLDocuments(path); err == nil {
+		t.Fatal("readYAMLDocuments() error = nil, want decode error from the trailing document")
+	}
+
+	docs, err := readFirstArtifactHubApplication(path)
+	if err != nil {
+		t.Fatalf("readFirstArtifactHubApplication() error = %v, want it to stop before the trailing document", err)
+	}
+
+	if len(docs) != 1 {
+		t.Errorf("readFirstArtifactHubApplication() got %d docs, want 1", len(docs))
+	}
+
+	if info, warnings := chartInfoAt(readFirstArtifactHubApplication, path); warnings != "" || info.Repo != "org/chart" {
+		t.Errorf("toChartInfo() = %q, warnings %q, want %q", info.Repo, warnings, "org/chart")
+	}
+}
+
+func TestReadFirstArtifactHubApplicationReadsUntilComment(t *testing.T) {
+	content := "kind: Secret\n---\nkind: Application\n---\n# artifacthub: org/chart\nkind: Application\n---\nkind: Secret\n"
+
+	path := filepath.Join(t.TempDir(), testAppFile)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	docs, err := readFirstArtifactHubApplication(path)
+	if err != nil {
+		t.Fatalf("readFirstArtifactHubApplication() error = %v", err)
+	}
+
+	if len(docs) != 3 {
+		t.Errorf("readFirstArtifactHubApplication() got %d docs, want 3", len(docs))
+	}
+}
+
+func BenchmarkDiscoveryReaders(b *testing.B) {
+	path := writeLargeBundle(b, 5000)
+
+	b.Run("full", func(b *testing.B) {
+		for b.Loop() {
+			_, _ = readYAMLDocuments(path)
+		}
+	})
+
+	b.Run("early exit", func(b *testing.B) {
+		for b.Loop() {
+			_, _ = readFirstArtifactHubApplication(path)
+		}
+	})
+}
+
 func TestWriteYAMLDocuments(t *testing.T) {
 	tmpDir := t.TempDir()
 	path := filepath.Join(tmpDir, "output.yaml")
@@ -204,6 +284,88 @@ spec:
 	}
 }
 
+func TestGetAndSetTargetRevisionApplicationSet(t *testing.T) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(testAppSetContent), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := getTargetRevision(&doc); got != "1.0.0" {
+		t.Errorf("getTargetRevision() = %q, want %q", got, "1.0.0")
+	}
+
+	setTargetRevision(&doc, "2.0.0")
+
+	if got := lookup(docRoot(&doc), "spec", "template", "spec", "source", "targetRevision"); got != "2.0.0" {
+		t.Errorf("template targetRevision = %q, want %q", got, "2.0.0")
+	}
+
+	if n := lookupNode(docRoot(&doc), "spec", "source"); n != nil {
+		t.Error("setTargetRevision() added spec.source to an ApplicationSet")
+	}
+}
+
+func TestGetAndSetTargetRevisionMultiSource(t *testing.T) {
+	const manifest = `# artifacthub: org/chart
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+spec:
+  sources:
+    - repoURL: https://github.com/org/config.git
+      targetRevision: main
+      ref: values
+    - repoURL: https://charts.example.com
+      chart: other
+      targetRevision: 3.0.0
+    - repoURL: https://charts.example.com
+      chart: chart
+      targetRevision: 1.0.0
+      helm:
+        valueFiles:
+          - $values/chart/values.yaml`
+
+	tests := []struct {
+		name     string
+		manifest string
+		want     string
+		index    int
+	}{
+		{name: "source matching the comment", manifest: manifest, want: "1.0.0", index: 2},
+		{name: "first chart source without a match", manifest: strings.Replace(manifest, "org/chart", "org/renamed", 1), want: "3.0.0", index: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc yaml.Node
+			if err := yaml.Unmarshal([]byte(tt.manifest), &doc); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := getTargetRevision(&doc); got != tt.want {
+				t.Errorf("getTargetRevision() = %q, want %q", got, tt.want)
+			}
+
+			setTargetRevision(&doc, "2.0.0")
+
+			sources := lookupNode(docRoot(&doc), "spec", "sources")
+			for i, source := range sources.Content {
+				want := []string{"main", "3.0.0", "1.0.0"}[i]
+				if i == tt.index {
+					want = "2.0.0"
+				}
+
+				if got := lookup(source, "targetRevision"); got != want {
+					t.Errorf("sources[%d].targetRevision = %q, want %q", i, got, want)
+				}
+			}
+
+			if n := lookupNode(docRoot(&doc), "spec", "source"); n != nil {
+				t.Error("setTargetRevision() added spec.source to a multi-source Application")
+			}
+		})
+	}
+}
+
 func TestKind(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -353,6 +515,21 @@ func TestGetArtifactHubRepo(t *testing.T) {
 			content: "# some other comment\nkind: Application",
 			want:    "",
 		},
+		{
+			name:    "tab separated with trailing tab",
+			content: "# artifacthub:\torg/chart\t\nkind: Application",
+			want:    "org/chart",
+		},
+		{
+			name:    "internal whitespace rejected",
+			content: "# artifacthub: org / chart\nkind: Application",
+			want:    "",
+		},
+		{
+			name:    "followed by another comment line",
+			content: "# artifacthub: org/chart\n# maintained by platform\nkind: Application",
+			want:    "org/chart",
+		},
 		{
 			name:    "nested org/repo",
 			content: "# artifacthub: cloudnative-pg/cloudnative-pg\nkind: Application",
@@ -367,10 +544,418 @@ func TestGetArtifactHubRepo(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			got := getArtifactHubRepo(&doc)
+			got := getArtifactHubRepo(&doc).Repo
 			if got != tt.want {
 				t.Errorf("getArtifactHubRepo() = %q, want %q", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestParseArtifactHubRepo(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    RepoComment
+		wantErr string
+	}{
+		{
+			name:    "no comment",
+			content: "kind: Application",
+			want:    RepoComment{Repo: "", AllowPrerelease: false},
+			wantErr: "",
+		},
+		{
+			name:    "tabs around repo",
+			content: "# artifacthub:\t\torg/chart\t \nkind: Application",
+			want:    RepoComment{Repo: "org/chart", AllowPrerelease: false},
+			wantErr: "",
+		},
+		{
+			name:    "unicode whitespace around repo",
+			content: "# artifacthub:\u00a0org/chart\u2003\nkind: Application",
+			want:    RepoComment{Repo: "org/chart", AllowPrerelease: false},
+			wantErr: "",
+		},
+		{
+			name:    "spaces around slash",
+			content: "# artifacthub: org / chart\nkind: Application",
+			want:    RepoComment{Repo: "", AllowPrerelease: false},
+			wantErr: `invalid artifacthub repo "org / chart": must not contain whitespace`,
+		},
+		{
+			name:    "mixed internal whitespace is collapsed in the error",
+			content: "# artifacthub: org \t/\tchart\nkind: Application",
+			want:    RepoComment{Repo: "", AllowPrerelease: false},
+			wantErr: `invalid artifacthub repo "org / chart": must not contain whitespace`,
+		},
+		{
+			name:    "prerelease marker",
+			content: "# artifacthub: org/chart prerelease\nkind: Application",
+			want:    RepoComment{Repo: "org/chart", AllowPrerelease: true},
+			wantErr: "",
+		},
+		{
+			name:    "prerelease marker after tabs",
+			content: "# artifacthub:\torg/chart\tprerelease\t\nkind: Application",
+			want:    RepoComment{Repo: "org/chart", AllowPrerelease: true},
+			wantErr: "",
+		},
+		{
+			name:    "constraint",
+			content: "# artifacthub: org/chart >=1.2.0  <2.0.0\nkind: Application",
+			want:    RepoComment{Repo: "org/chart", AllowPrerelease: false, Constraint: ">=1.2.0 <2.0.0"},
+			wantErr: "",
+		},
+		{
+			name:    "prerelease marker and constraint",
+			content: "# artifacthub: org/chart prerelease ^1.2.0\nkind: Application",
+			want:    RepoComment{Repo: "org/chart", AllowPrerelease: true, Constraint: "^1.2.0"},
+			wantErr: "",
+		},
+		{
+			name:    "pinned marker",
+			content: "# artifacthub: org/chart pinned\nkind: Application",
+			want:    RepoComment{Repo: "org/chart", AllowPrerelease: false, Pinned: true},
+			wantErr: "",
+		},
+		{
+			name:    "pinned and prerelease markers in either order with constraint",
+			content: "# artifacthub: org/chart pinned prerelease <2.0.0\nkind: Application",
+			want:    RepoComment{Repo: "org/chart", AllowPrerelease: true, Pinned: true, Constraint: "<2.0.0"},
+			wantErr: "",
+		},
+		{
+			name:    "ignored versions with constraint",
+			content: "# artifacthub: org/chart !2.3.0 prerelease !2.3.1 <3.0.0\nkind: Application",
+			want:    RepoComment{Repo: "org/chart", AllowPrerelease: true, Constraint: "<3.0.0", Ignore: []string{"2.3.0", "2.3.1"}},
+			wantErr: "",
+		},
+		{
+			name:    "ignored word not a version",
+			content: "# artifacthub: org/chart !latest\nkind: Application",
+			want:    RepoComment{},
+			wantErr: `invalid artifacthub ignored version "!latest" for org/chart`,
+		},
+		{
+			name:    "invalid constraint",
+			content: "# artifacthub: org/chart >=1.x\nkind: Application",
+			want:    RepoComment{Repo: "", AllowPrerelease: false, Constraint: ""},
+			wantErr: `invalid artifacthub constraint for org/chart: constraint term ">=1.x": invalid version "1.x"`,
+		},
+		{
+			name:    "unknown word after repo",
+			content: "# artifacthub: org/chart beta\nkind: Application",
+			want:    RepoComment{Repo: "", AllowPrerelease: false},
+			wantErr: `invalid artifacthub repo "org/chart beta": must not contain whitespace`,
+		},
+		{
+			name:    "dots, dashes and underscores",
+			content: "# artifacthub: my-org.io/chart_name-2\nkind: Application",
+			want:    RepoComment{Repo: "my-org.io/chart_name-2", AllowPrerelease: false},
+			wantErr: "",
+		},
+		{
+			name:    "missing slash",
+			content: "# artifacthub: orgchart\nkind: Application",
+			want:    RepoComment{},
+			wantErr: `invalid artifacthub repo "orgchart": want org/repo or publisher/repo/package`,
+		},
+		{
+			name:    "publisher, repo and package",
+			content: "# artifacthub: publisher/repo/chart\nkind: Application",
+			want:    RepoComment{Repo: "publisher/repo/chart", AllowPrerelease: false},
+			wantErr: "",
+		},
+		{
+			name:    "four segments",
+			content: "# artifacthub: a/b/c/d\nkind: Application",
+			want:    RepoComment{},
+			wantErr: `invalid artifacthub repo "a/b/c/d": want org/repo or publisher/repo/package`,
+		},
+		{
+			name:    "empty middle segment",
+			content: "# artifacthub: org//chart\nkind: Application",
+			want:    RepoComment{},
+			wantErr: `invalid artifacthub repo "org//chart": want org/repo or publisher/repo/package`,
+		},
+		{
+			name:    "dot segments",
+			content: "# artifacthub: ../../chart\nkind: Application",
+			want:    RepoComment{},
+			wantErr: `invalid artifacthub repo "../../chart": want org/repo or publisher/repo/package`,
+		},
+		{
+			name:    "empty org",
+			content: "# artifacthub: /chart\nkind: Application",
+			want:    RepoComment{},
+			wantErr: `invalid artifacthub repo "/chart": want org/repo or publisher/repo/package`,
+		},
+		{
+			name:    "empty chart",
+			content: "# artifacthub: org/\nkind: Application",
+			want:    RepoComment{},
+			wantErr: `invalid artifacthub repo "org/": want org/repo or publisher/repo/package`,
+		},
+		{
+			name:    "disallowed character",
+			content: "# artifacthub: org/chart?v=1\nkind: Application",
+			want:    RepoComment{},
+			wantErr: `invalid artifacthub repo "org/chart?v=1": unexpected character '?'`,
+		},
+		{
+			name:    "empty repo",
+			content: "# artifacthub:\t\nkind: Application",
+			want:    RepoComment{Repo: "", AllowPrerelease: false},
+			wantErr: "empty artifacthub repo",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc yaml.Node
+			if err := yaml.Unmarshal([]byte(tt.content), &doc); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := parseArtifactHubRepo(&doc)
+			assertError(t, tt.wantErr, err)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseArtifactHubRepo() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseChartSource(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		wantRepo   string
+		wantSource string
+		wantErr    string
+	}{
+		{
+			name:       "artifacthub",
+			content:    "# artifacthub: org/chart\nkind: Application",
+			wantRepo:   "org/chart",
+			wantSource: "",
+			wantErr:    "",
+		},
+		{
+			name:       "github",
+			content:    "# github: owner/repo\nkind: Application",
+			wantRepo:   "owner/repo",
+			wantSource: sourceGitHub,
+			wantErr:    "",
+		},
+		{
+			name:       "artifacthub wins over github",
+			content:    "# github: owner/repo\n# artifacthub: org/chart\nkind: Application",
+			wantRepo:   "org/chart",
+			wantSource: "",
+			wantErr:    "",
+		},
+		{
+			name:       "github repo with three segments",
+			content:    "# github: owner/repo/extra\nkind: Application",
+			wantRepo:   "",
+			wantSource: sourceGitHub,
+			wantErr:    `invalid github repo "owner/repo/extra": want org/repo`,
+		},
+		{
+			name:       "empty github repo",
+			content:    "# github:\nkind: Application",
+			wantRepo:   "",
+			wantSource: sourceGitHub,
+			wantErr:    "empty github repo",
+		},
+		{
+			name:       "no comment",
+			content:    "kind: Application",
+			wantRepo:   "",
+			wantSource: "",
+			wantErr:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc yaml.Node
+			if err := yaml.Unmarshal([]byte(tt.content), &doc); err != nil {
+				t.Fatal(err)
+			}
+
+			comment, source, err := parseChartSource(&doc)
+			assertError(t, tt.wantErr, err)
+
+			if repo := comment.Repo; repo != tt.wantRepo || source != tt.wantSource {
+				t.Errorf("parseChartSource() = %q, %q, want %q, %q", comment.Repo, source, tt.wantRepo, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestWriteYAMLFileRollsBackCorruptingEdit(t *testing.T) {
+	const original = "# artifacthub: org/chart\nkind: Application\nspec:\n  source:\n    targetRevision: 1.0.0\n"
+
+	tests := []struct {
+		name    string
+		corrupt func(string) string
+		wantErr string
+	}{
+		{
+			name:    "wrong version",
+			corrupt: func(s string) string { return strings.Replace(s, "1.1.0", "1.1.0-broken", 1) },
+			wantErr: `written file has targetRevision "1.1.0-broken", want "1.1.0"`,
+		},
+		{
+			name:    "invalid yaml",
+			corrupt: func(s string) string { return s + "spec: [\n" },
+			wantErr: "written file does not parse",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "app.yaml")
+
+			if err := os.WriteFile(path, []byte(original), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			docs, err := readYAMLDocuments(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			updateDocuments(docs, "1.1.0")
+
+			corrupting := func(w io.Writer, docs []*yaml.Node) error {
+				var buf strings.Builder
+				if err := encodeYAMLDocuments(&buf, docs); err != nil {
+					return err
+				}
+
+				_, err := io.WriteString(w, tt.corrupt(buf.String()))
+
+				return err
+			}
+
+			err = writeYAMLFile(path, docs, corrupting, verifyTargetRevision("1.1.0"))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("writeYAMLFile() error = %v, want %q", err, tt.wantErr)
+			}
+
+			content, readErr := os.ReadFile(path)
+			if readErr != nil {
+				t.Fatal(readErr)
+			}
+
+			if string(content) != original {
+				t.Errorf("file changed after rollback:\n%s", content)
+			}
+
+			assertOnlyFile(t, dir, "app.yaml")
+		})
+	}
+}
+
+func TestVerifyingYAMLWriter(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.yaml")
+
+	if err := os.WriteFile(path, []byte("kind: Application\nspec:\n  source:\n    targetRevision: 1.0.0\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	docs, err := readYAMLDocuments(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updateDocuments(docs, "1.1.0")
+
+	if err := MakeVerifyingYAMLWriter(false)(context.Background(), path, docs); err != nil {
+		t.Fatalf("writer error = %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(content), "targetRevision: 1.1.0") {
+		t.Errorf("file not updated:\n%s", content)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v, want %v", info.Mode().Perm(), os.FileMode(0o600))
+	}
+
+	assertOnlyFile(t, dir, "app.yaml")
+}
+
+func TestWriteYAMLDocumentsThroughSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.yaml")
+	link := filepath.Join(dir, "link.yaml")
+
+	if err := os.WriteFile(target, []byte("kind: Application\nspec:\n  source:\n    targetRevision: 1.0.0\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Symlink(target, link); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+
+	docs, err := readYAMLDocuments(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updateDocuments(docs, "1.1.0")
+
+	if err := writeYAMLDocuments(context.Background(), link, docs); err != nil {
+		t.Fatal(err)
+	}
+
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("link replaced by a regular file (err = %v)", err)
+	}
+
+	content, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(content), "targetRevision: 1.1.0") {
+		t.Errorf("target not updated:\n%s", content)
+	}
+}
+
+// assertOnlyFile fails unless name is the only entry in dir, catching leftover temporary files.
+func assertOnlyFile(t *testing.T, dir, name string) {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 || entries[0].Name() != name {
+		names := make([]string, 0, len(entries))
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+
+		t.Errorf("directory holds %v, want only %s", names, name)
+	}
+}